@@ -0,0 +1,37 @@
+// Команда serve-fake-backend запускает фейковый бэкенд Tribute для локальной разработки:
+//
+//	go run ./cmd/serve-fake-backend -addr :8090
+//
+// и API_BASE_URL=http://localhost:8090 для бота. Сбои задаются через POST /_fake/faults.
+package main
+
+import (
+	"flag"
+	"net/http"
+	"tribute-chatbot/internal/fakebackend"
+	"tribute-chatbot/internal/logger"
+)
+
+func main() {
+	addr := flag.String("addr", ":8090", "адрес HTTP сервера")
+	bearerToken := flag.String("bearer-token", "", "требовать Authorization: Bearer <token>")
+	hmacSecret := flag.String("hmac-secret", "", "требовать подпись запросов HMAC-SHA256")
+	flag.Parse()
+
+	logg := logger.New()
+
+	server := fakebackend.New(fakebackend.Options{
+		BearerToken: *bearerToken,
+		HMACSecret:  *hmacSecret,
+	})
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logg.Info(r.Method + " " + r.URL.Path)
+		server.ServeHTTP(w, r)
+	})
+
+	logg.Info("Fake Tribute backend listening on " + *addr)
+	if err := http.ListenAndServe(*addr, handler); err != nil {
+		logg.Fatal("Fake backend failed:", err)
+	}
+}
@@ -1,34 +1,64 @@
 package bot
 
 import (
+	"path/filepath"
+	"strings"
 	"time"
 	"tribute-chatbot/internal/config"
+	"tribute-chatbot/internal/handlers/admin"
 	"tribute-chatbot/internal/handlers/channel"
 	"tribute-chatbot/internal/handlers/common"
+	"tribute-chatbot/internal/handlers/events"
+	"tribute-chatbot/internal/handlers/expiry"
+	"tribute-chatbot/internal/handlers/invite"
+	"tribute-chatbot/internal/handlers/joinrequest"
 	"tribute-chatbot/internal/handlers/verification"
 	"tribute-chatbot/internal/logger"
 	"tribute-chatbot/internal/services"
+	"tribute-chatbot/internal/storage"
+	"tribute-chatbot/internal/webhook"
 
 	tele "gopkg.in/telebot.v4"
 )
 
+// allowedUpdates типы обновлений, которые получает бот. chat_member по умолчанию
+// не присылается, а нужен, чтобы знать, по какой ссылке вступил участник.
+var allowedUpdates = []string{
+	"message",
+	"edited_message",
+	"channel_post",
+	"edited_channel_post",
+	"inline_query",
+	"callback_query",
+	"my_chat_member",
+	"chat_member",
+	"chat_join_request",
+}
+
 // Bot основная структура бота
 type Bot struct {
 	bot                 *tele.Bot
 	config              *config.Config
 	logger              logger.Logger
 	verificationService *services.VerificationService
+	screeningService    *services.ScreeningService
 	apiService          *services.APIService
+	outboxService       *services.OutboxService
 	commonHandler       *common.Handler
 	verificationHandler *verification.Handler
 	channelHandler      *channel.Handler
+	adminHandler        *admin.Handler
+	inviteHandler       *invite.Handler
+	expiryHandler       *expiry.Handler
+	joinRequestHandler  *joinrequest.Handler
+	webhookServer       *webhook.Server
 }
 
 // NewBot создает новый экземпляр бота
 func NewBot(cfg *config.Config) (*Bot, error) {
 	pref := tele.Settings{
 		Token:  cfg.TelegramBotToken,
-		Poller: &tele.LongPoller{Timeout: 30 * time.Second},
+		Poller: &tele.LongPoller{Timeout: 30 * time.Second, AllowedUpdates: allowedUpdates},
 	}
 
 	bot, err := tele.NewBot(pref)
@@ -37,30 +67,98 @@ func NewBot(cfg *config.Config) (*Bot, error) {
 	}
 
 	// Инициализируем сервисы
-	verificationService := services.NewVerificationService()
-	apiService := services.NewAPIService(cfg)
+	verificationService := services.NewVerificationService(cfg.MRZMode != config.MRZModeOff)
+	decisionService, err := services.NewDecisionService(
+		storage.NewJSONFile(filepath.Join(cfg.DataDir, "pending_decisions.json")),
+	)
+	if err != nil {
+		return nil, err
+	}
+	policyService := services.NewPolicyService(cfg)
+	screeningService, err := services.NewScreeningService(cfg)
+	if err != nil {
+		return nil, err
+	}
+	apiService, err := services.NewAPIService(cfg)
+	if err != nil {
+		return nil, err
+	}
+	userService := services.NewUserService(apiService, cfg.UserCacheTTL, cfg.BackendLookupTimeout)
+	channelSettingsService := services.NewChannelSettingsService(apiService, cfg.ChannelSettingsCacheTTL, cfg.BackendLookupTimeout)
+	eventLog, err := services.NewEventLog(
+		storage.NewJSONFile(filepath.Join(cfg.DataDir, "processed_events.json")),
+	)
+	if err != nil {
+		return nil, err
+	}
+	channelService, err := services.NewChannelService(
+		storage.NewJSONFile(filepath.Join(cfg.DataDir, "channels.json")),
+	)
+	if err != nil {
+		return nil, err
+	}
+	linkService, err := services.NewChannelLinkService(
+		storage.NewJSONFile(filepath.Join(cfg.DataDir, "pending_channel_links.json")),
+	)
+	if err != nil {
+		return nil, err
+	}
+	inviteService, err := services.NewInviteService(
+		storage.NewJSONFile(filepath.Join(cfg.DataDir, "invite_links.json")),
+	)
+	if err != nil {
+		return nil, err
+	}
+	expiryService, err := services.NewExpiryService(
+		storage.NewJSONFile(filepath.Join(cfg.DataDir, "expired_subscriptions.json")),
+	)
+	if err != nil {
+		return nil, err
+	}
+	outboxService, err := services.NewOutboxService(
+		storage.NewJSONFile(filepath.Join(cfg.DataDir, "outbox.json")), apiService, cfg,
+	)
+	if err != nil {
+		return nil, err
+	}
 
 	// Инициализируем обработчики
 	commonHandler := common.NewHandler()
-	verificationHandler := verification.NewHandler(verificationService, apiService, cfg)
-	channelHandler := channel.NewHandler(apiService, cfg)
+	verificationHandler := verification.NewHandler(
+		verificationService, decisionService, policyService, screeningService, outboxService, userService, cfg,
+	)
+	channelHandler := channel.NewHandler(
+		outboxService, apiService, userService, channelService, linkService, channelSettingsService, cfg,
+	)
+	adminHandler := admin.NewHandler(outboxService, cfg)
+	inviteHandler := invite.NewHandler(inviteService, outboxService, cfg)
+	expiryHandler := expiry.NewHandler(expiryService, outboxService, apiService, cfg)
+	joinRequestHandler := joinrequest.NewHandler(apiService)
+	eventsHandler := events.NewHandler(bot, eventLog, userService, inviteHandler)
 
 	return &Bot{
 		bot:                 bot,
 		config:              cfg,
 		logger:              logger.New(),
 		verificationService: verificationService,
+		screeningService:    screeningService,
 		apiService:          apiService,
+		outboxService:       outboxService,
 		commonHandler:       commonHandler,
 		verificationHandler: verificationHandler,
 		channelHandler:      channelHandler,
+		adminHandler:        adminHandler,
+		inviteHandler:       inviteHandler,
+		expiryHandler:       expiryHandler,
+		joinRequestHandler:  joinRequestHandler,
+		webhookServer:       webhook.NewServer(cfg, eventsHandler),
 	}, nil
 }
 
 // SetupHandlers настраивает обработчики команд и событий
 func (b *Bot) SetupHandlers() {
 	// Общие команды
-	b.bot.Handle("/start", b.commonHandler.HandleStart)
+	b.bot.Handle("/start", b.handleStart)
 	b.bot.Handle("/help", b.commonHandler.HandleHelp)
 	b.bot.Handle("/echo", b.commonHandler.HandleEcho)
 	b.bot.Handle("/donate", b.commonHandler.HandleDonate)
@@ -68,29 +166,99 @@ func (b *Bot) SetupHandlers() {
 	// Верификация
 	b.bot.Handle("/verificate", b.verificationHandler.HandleStartVerification)
 	b.bot.Handle(tele.OnPhoto, b.verificationHandler.HandlePhoto)
-	b.bot.Handle(tele.OnCallback, b.verificationHandler.HandleCallback)
+	b.bot.Handle("/skip", b.verificationHandler.HandleSkip)
+	b.bot.Handle(tele.OnCallback, b.handleCallback)
 
 	// WebApp
 	b.bot.Handle(tele.OnWebApp, b.commonHandler.HandleWebApp)
 
 	// Текстовые сообщения
-	b.bot.Handle(tele.OnText, b.commonHandler.HandleText)
+	b.bot.Handle(tele.OnText, b.handleText)
 
 	// Каналы
 	b.bot.Handle(tele.OnMyChatMember, b.channelHandler.HandleMyChatMember)
+	b.bot.Handle(tele.OnChannelPost, b.handleChannelPost)
+	b.bot.Handle(tele.OnEditedChannelPost, b.channelHandler.HandleChannelUpdate)
+	b.bot.Handle(tele.OnNewGroupTitle, b.channelHandler.HandleChannelUpdate)
+	b.bot.Handle(tele.OnNewGroupPhoto, b.channelHandler.HandleChannelUpdate)
+	b.bot.Handle(tele.OnGroupPhotoDeleted, b.channelHandler.HandleChannelUpdate)
+
+	// Вступление участников по персональным ссылкам
+	b.bot.Handle(tele.OnChatMember, b.inviteHandler.HandleChatMember)
+
+	// Заявки на вступление в каналы
+	b.bot.Handle(tele.OnChatJoinRequest, b.joinRequestHandler.HandleChatJoinRequest)
 
 	// Inline-режим для доната
 	b.bot.Handle(tele.OnQuery, b.commonHandler.HandleInlineDonate)
+
+	// Админские команды
+	b.bot.Handle("/outbox", b.adminHandler.HandleOutbox)
+}
+
+// handleCallback направляет нажатия кнопок в обработчик соответствующего раздела
+func (b *Bot) handleCallback(c tele.Context) error {
+	callback := c.Callback()
+	if callback == nil {
+		return b.verificationHandler.HandleCallback(c)
+	}
+
+	data := strings.TrimSpace(callback.Data)
+	switch {
+	case strings.HasPrefix(data, admin.CallbackPrefix):
+		return b.adminHandler.HandleCallback(c)
+	case strings.HasPrefix(data, channel.CallbackPrefix):
+		return b.channelHandler.HandleCallback(c)
+	default:
+		return b.verificationHandler.HandleCallback(c)
+	}
+}
+
+// handleStart направляет переходы по кнопкам под постами каналов обработчику каналов
+func (b *Bot) handleStart(c tele.Context) error {
+	if strings.HasPrefix(c.Message().Payload, channel.StartPrefix) {
+		return b.channelHandler.HandleStartLink(c)
+	}
+	return b.commonHandler.HandleStart(c)
+}
+
+// handleChannelPost отслеживает изменения канала по посту и прикрепляет к посту кнопки
+func (b *Bot) handleChannelPost(c tele.Context) error {
+	return b.channelHandler.HandleChannelPost(c, b.bot.Me)
+}
+
+// handleText направляет текст в активный шаг верификации или в общий обработчик
+func (b *Bot) handleText(c tele.Context) error {
+	if b.verificationHandler.AwaitsText(c.Sender().ID) {
+		return b.verificationHandler.HandleText(c)
+	}
+	return b.commonHandler.HandleText(c)
 }
 
 // Start запускает бота
 func (b *Bot) Start() {
 	b.logger.Info("Starting Telegram bot (Telebot)...")
 	b.SetupHandlers()
+	b.verificationHandler.StartWorkers(b.bot)
+	b.channelHandler.StartWorkers(b.bot)
+	b.inviteHandler.StartWorkers(b.bot)
+	b.apiService.OnCircuitChange(func(from, to services.CircuitState) {
+		b.adminHandler.NotifyCircuitChange(b.bot, from, to)
+	})
+	b.outboxService.Start()
+	b.channelHandler.StartReconciliation(b.bot, b.bot.Me.ID)
+	b.expiryHandler.Start(b.bot)
+	b.screeningService.StartWatching()
+	b.webhookServer.Start()
 	b.bot.Start()
 }
 
 // Stop останавливает бота
 func (b *Bot) Stop() {
+	b.webhookServer.Stop()
+	b.screeningService.Stop()
+	b.channelHandler.StopReconciliation()
+	b.expiryHandler.Stop()
+	b.outboxService.Stop()
 	b.bot.Stop()
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"time"
 )
 
 // Config содержит все настройки приложения
@@ -13,8 +14,161 @@ type Config struct {
 	LogLevel            string
 	Port                int
 	APIBaseURL          string
+
+	// BackendTimeout таймаут одной попытки запроса к API
+	BackendTimeout time.Duration
+	// BackendMaxRetries число повторов при сетевых ошибках и ответах 5xx/429
+	BackendMaxRetries int
+	// BackendLookupTimeout общий срок запроса профиля или настроек канала из API
+	// вместе с повторами; обработчики не ждут ответа дольше
+	BackendLookupTimeout time.Duration
+	// BackendRetryBaseDelay начальная задержка перед повтором, удваивается с каждой попыткой
+	BackendRetryBaseDelay time.Duration
+	// BackendRetryMaxDelay максимальная задержка перед повтором
+	BackendRetryMaxDelay time.Duration
+
+	// BackendBreakerFailureThreshold число сбоев подряд, после которого запросы к API
+	// отклоняются без ожидания таймаута; 0 — выключатель не используется
+	BackendBreakerFailureThreshold int
+	// BackendBreakerOpenTimeout пауза перед пробным запросом к недоступному API
+	BackendBreakerOpenTimeout time.Duration
+	// BackendBreakerHalfOpenSuccesses число успешных пробных запросов для восстановления
+	BackendBreakerHalfOpenSuccesses int
+
+	// BackendAuthScheme схема аутентификации запросов к API: "none", "bearer" или "hmac"
+	BackendAuthScheme string
+	// BackendAuthToken статический токен для схемы "bearer"
+	BackendAuthToken string
+	// BackendHMACKeyID идентификатор ключа подписи для схемы "hmac" (опционально)
+	BackendHMACKeyID string
+	// BackendHMACSecret секрет подписи запросов для схемы "hmac"
+	BackendHMACSecret string
+	// BackendTLSCertFile и BackendTLSKeyFile клиентский сертификат для mutual TLS
+	BackendTLSCertFile string
+	BackendTLSKeyFile  string
+	// BackendTLSCAFile сертификат CA для проверки сервера API (опционально)
+	BackendTLSCAFile string
+
+	// UserCacheTTL время хранения профилей пользователей из API, 0 — без кэша
+	UserCacheTTL time.Duration
+
+	// WebhookHMACSecret секрет подписи событий, которые бэкенд отправляет на Port;
+	// пустой — прием событий выключен
+	WebhookHMACSecret string
+	// WebhookReplayWindow допустимое расхождение метки времени подписи события
+	WebhookReplayWindow time.Duration
+
+	// AdminMessageMode определяет, что делать с сообщениями заявки после решения:
+	// "edit" — отметить решение в подписи и скрыть фото, "delete" — удалить
+	AdminMessageMode string
+	// AdminPlaceholderPhoto путь к заглушке, заменяющей фото заявки в режиме "edit".
+	// Если не задан, фотографии скрываются под спойлером.
+	AdminPlaceholderPhoto string
+	// DecisionUndoWindow время, в течение которого решение по заявке можно отменить.
+	// Нулевое значение применяет решение сразу.
+	DecisionUndoWindow time.Duration
+
+	// MRZMode определяет шаг ввода MRZ после фото паспорта:
+	// "off" — не запрашивать, "optional" — можно пропустить, "required" — обязателен
+	MRZMode string
+
+	// PolicyMinAge минимальный возраст владельца документа, 0 — без ограничения
+	PolicyMinAge int
+	// PolicyMinDocumentValidityDays минимальный оставшийся срок действия документа в днях
+	PolicyMinDocumentValidityDays int
+	// PolicyViolationAction действие при нарушении правил:
+	// "block" — не отправлять заявку, "flag" — отметить заявку в админском чате
+	PolicyViolationAction string
+
+	// ScreeningListPath путь к CSV или JSON файлу списка для проверки, пустой — проверка выключена
+	ScreeningListPath string
+	// ScreeningThreshold минимальная оценка сходства (0..1), при которой фиксируется совпадение
+	ScreeningThreshold float64
+	// ScreeningAction действие при совпадении: "flag" — отметить заявку,
+	// "hold" — удерживать заявку до ручного снятия удержания
+	ScreeningAction string
+	// ScreeningReloadInterval период проверки файла списка на изменения
+	ScreeningReloadInterval time.Duration
+
+	// ChannelOwnerRole кто может подключить канал к своему аккаунту:
+	// "creator" — только владелец канала, "administrator" — также администраторы,
+	// которым разрешено назначать других администраторов
+	ChannelOwnerRole string
+	// ChannelLinkTimeout время, в течение которого владелец может подтвердить
+	// привязку канала к своему аккаунту
+	ChannelLinkTimeout time.Duration
+	// ChannelReconcileInterval период сверки подключенных каналов в API с фактическим
+	// статусом бота в них; 0 — сверка выключена
+	ChannelReconcileInterval time.Duration
+
+	// InviteLinkTTL срок действия персональной ссылки подписчика в канал;
+	// неиспользованная ссылка отзывается по его истечении
+	InviteLinkTTL time.Duration
+
+	// ExpiryCheckInterval период запроса истекших подписок из API; 0 — подписчики
+	// с истекшей подпиской не удаляются из каналов
+	ExpiryCheckInterval time.Duration
+	// ExpiryGracePeriod время между напоминанием о продлении и удалением из канала
+	ExpiryGracePeriod time.Duration
+
+	// ChannelSettingsCacheTTL время хранения настроек каналов из API; 0 — без кэша
+	ChannelSettingsCacheTTL time.Duration
+	// PostButtonsExcludeHashtag хэштег, с которым посты остаются без кнопок,
+	// если в настройках канала не задан свой
+	PostButtonsExcludeHashtag string
+
+	// OutboxPollInterval период проверки outbox на элементы, готовые к повтору
+	OutboxPollInterval time.Duration
+	// OutboxRetryBaseDelay начальная задержка перед повторной доставкой, удваивается с каждой попыткой
+	OutboxRetryBaseDelay time.Duration
+	// OutboxRetryMaxDelay максимальная задержка перед повторной доставкой
+	OutboxRetryMaxDelay time.Duration
+	// OutboxMaxAttempts число неудачных попыток, после которого элемент считается зависшим
+	// и ждет ручного повтора или удаления командой /outbox
+	OutboxMaxAttempts int
+
+	// DataDir каталог для файлов состояния, которое должно пережить перезапуск
+	DataDir string
 }
 
+// Схемы аутентификации запросов к API
+const (
+	BackendAuthNone   = "none"
+	BackendAuthBearer = "bearer"
+	BackendAuthHMAC   = "hmac"
+)
+
+// Режимы обработки сообщений заявки в админском чате
+const (
+	AdminMessageModeEdit   = "edit"
+	AdminMessageModeDelete = "delete"
+)
+
+// Действия при нарушении правил проверки данных личности
+const (
+	PolicyActionBlock = "block"
+	PolicyActionFlag  = "flag"
+)
+
+// Действия при совпадении со списком
+const (
+	ScreeningActionFlag = "flag"
+	ScreeningActionHold = "hold"
+)
+
+// Роли, которым разрешено подключать канал
+const (
+	ChannelOwnerRoleCreator       = "creator"
+	ChannelOwnerRoleAdministrator = "administrator"
+)
+
+// Режимы шага ввода MRZ
+const (
+	MRZModeOff      = "off"
+	MRZModeOptional = "optional"
+	MRZModeRequired = "required"
+)
+
 // Load загружает конфигурацию из переменных окружения
 func Load() (*Config, error) {
 	config := &Config{
@@ -23,6 +177,63 @@ func Load() (*Config, error) {
 		LogLevel:            getEnv("LOG_LEVEL", "info"),
 		Port:                getEnvAsInt("PORT", 8080),
 		APIBaseURL:          getEnv("API_BASE_URL", ""),
+
+		BackendTimeout:        getEnvAsDuration("BACKEND_TIMEOUT", 10*time.Second),
+		BackendMaxRetries:     getEnvAsInt("BACKEND_MAX_RETRIES", 3),
+		BackendLookupTimeout:  getEnvAsDuration("BACKEND_LOOKUP_TIMEOUT", 30*time.Second),
+		BackendRetryBaseDelay: getEnvAsDuration("BACKEND_RETRY_BASE_DELAY", 500*time.Millisecond),
+		BackendRetryMaxDelay:  getEnvAsDuration("BACKEND_RETRY_MAX_DELAY", 10*time.Second),
+
+		BackendBreakerFailureThreshold:  getEnvAsInt("BACKEND_BREAKER_FAILURE_THRESHOLD", 5),
+		BackendBreakerOpenTimeout:       getEnvAsDuration("BACKEND_BREAKER_OPEN_TIMEOUT", 30*time.Second),
+		BackendBreakerHalfOpenSuccesses: getEnvAsInt("BACKEND_BREAKER_HALF_OPEN_SUCCESSES", 1),
+
+		BackendAuthScheme:  getEnv("BACKEND_AUTH_SCHEME", BackendAuthNone),
+		BackendAuthToken:   getEnv("BACKEND_AUTH_TOKEN", ""),
+		BackendHMACKeyID:   getEnv("BACKEND_HMAC_KEY_ID", ""),
+		BackendHMACSecret:  getEnv("BACKEND_HMAC_SECRET", ""),
+		BackendTLSCertFile: getEnv("BACKEND_TLS_CERT_FILE", ""),
+		BackendTLSKeyFile:  getEnv("BACKEND_TLS_KEY_FILE", ""),
+		BackendTLSCAFile:   getEnv("BACKEND_TLS_CA_FILE", ""),
+
+		UserCacheTTL: getEnvAsDuration("USER_CACHE_TTL", 5*time.Minute),
+
+		WebhookHMACSecret:   getEnv("WEBHOOK_HMAC_SECRET", ""),
+		WebhookReplayWindow: getEnvAsDuration("WEBHOOK_REPLAY_WINDOW", 5*time.Minute),
+
+		AdminMessageMode:      getEnv("ADMIN_MESSAGE_MODE", AdminMessageModeEdit),
+		AdminPlaceholderPhoto: getEnv("ADMIN_PLACEHOLDER_PHOTO", ""),
+		DecisionUndoWindow:    getEnvAsDuration("DECISION_UNDO_WINDOW", 30*time.Second),
+
+		MRZMode: getEnv("MRZ_MODE", MRZModeOff),
+
+		PolicyMinAge:                  getEnvAsInt("POLICY_MIN_AGE", 0),
+		PolicyMinDocumentValidityDays: getEnvAsInt("POLICY_MIN_DOCUMENT_VALIDITY_DAYS", 0),
+		PolicyViolationAction:         getEnv("POLICY_VIOLATION_ACTION", PolicyActionBlock),
+
+		ScreeningListPath:       getEnv("SCREENING_LIST_PATH", ""),
+		ScreeningThreshold:      getEnvAsFloat("SCREENING_THRESHOLD", 0.88),
+		ScreeningAction:         getEnv("SCREENING_ACTION", ScreeningActionHold),
+		ScreeningReloadInterval: getEnvAsDuration("SCREENING_RELOAD_INTERVAL", time.Minute),
+
+		ChannelOwnerRole:         getEnv("CHANNEL_OWNER_ROLE", ChannelOwnerRoleCreator),
+		ChannelLinkTimeout:       getEnvAsDuration("CHANNEL_LINK_TIMEOUT", time.Hour),
+		ChannelReconcileInterval: getEnvAsDuration("CHANNEL_RECONCILE_INTERVAL", 6*time.Hour),
+
+		InviteLinkTTL: getEnvAsDuration("INVITE_LINK_TTL", 24*time.Hour),
+
+		ExpiryCheckInterval: getEnvAsDuration("EXPIRY_CHECK_INTERVAL", 10*time.Minute),
+		ExpiryGracePeriod:   getEnvAsDuration("EXPIRY_GRACE_PERIOD", 24*time.Hour),
+
+		ChannelSettingsCacheTTL:   getEnvAsDuration("CHANNEL_SETTINGS_CACHE_TTL", 5*time.Minute),
+		PostButtonsExcludeHashtag: getEnv("POST_BUTTONS_EXCLUDE_HASHTAG", "#nobuttons"),
+
+		OutboxPollInterval:   getEnvAsDuration("OUTBOX_POLL_INTERVAL", 5*time.Second),
+		OutboxRetryBaseDelay: getEnvAsDuration("OUTBOX_RETRY_BASE_DELAY", 10*time.Second),
+		OutboxRetryMaxDelay:  getEnvAsDuration("OUTBOX_RETRY_MAX_DELAY", 10*time.Minute),
+		OutboxMaxAttempts:    getEnvAsInt("OUTBOX_MAX_ATTEMPTS", 20),
+
+		DataDir: getEnv("DATA_DIR", "data"),
 	}
 
 	if config.TelegramBotToken == "" {
@@ -37,6 +248,89 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("TELEGRAM_ADMIN_CHAT_ID is required")
 	}
 
+	switch config.BackendAuthScheme {
+	case BackendAuthNone:
+	case BackendAuthBearer:
+		if config.BackendAuthToken == "" {
+			return nil, fmt.Errorf("BACKEND_AUTH_TOKEN is required for bearer authentication")
+		}
+	case BackendAuthHMAC:
+		if config.BackendHMACSecret == "" {
+			return nil, fmt.Errorf("BACKEND_HMAC_SECRET is required for hmac authentication")
+		}
+	default:
+		return nil, fmt.Errorf("BACKEND_AUTH_SCHEME must be %q, %q or %q", BackendAuthNone, BackendAuthBearer, BackendAuthHMAC)
+	}
+
+	if (config.BackendTLSCertFile == "") != (config.BackendTLSKeyFile == "") {
+		return nil, fmt.Errorf("BACKEND_TLS_CERT_FILE and BACKEND_TLS_KEY_FILE must be set together")
+	}
+
+	if config.AdminMessageMode != AdminMessageModeEdit && config.AdminMessageMode != AdminMessageModeDelete {
+		return nil, fmt.Errorf("ADMIN_MESSAGE_MODE must be %q or %q", AdminMessageModeEdit, AdminMessageModeDelete)
+	}
+
+	switch config.MRZMode {
+	case MRZModeOff, MRZModeOptional, MRZModeRequired:
+	default:
+		return nil, fmt.Errorf("MRZ_MODE must be %q, %q or %q", MRZModeOff, MRZModeOptional, MRZModeRequired)
+	}
+
+	if config.PolicyViolationAction != PolicyActionBlock && config.PolicyViolationAction != PolicyActionFlag {
+		return nil, fmt.Errorf("POLICY_VIOLATION_ACTION must be %q or %q", PolicyActionBlock, PolicyActionFlag)
+	}
+
+	if config.ScreeningAction != ScreeningActionFlag && config.ScreeningAction != ScreeningActionHold {
+		return nil, fmt.Errorf("SCREENING_ACTION must be %q or %q", ScreeningActionFlag, ScreeningActionHold)
+	}
+
+	// Без обязательного MRZ данные для проверок могут отсутствовать, и заявка
+	// прошла бы мимо списка или блокирующих правил
+	if config.ScreeningListPath != "" && config.MRZMode != MRZModeRequired {
+		return nil, fmt.Errorf("MRZ_MODE must be %q when SCREENING_LIST_PATH is set", MRZModeRequired)
+	}
+
+	policyConfigured := config.PolicyMinAge > 0 || config.PolicyMinDocumentValidityDays > 0
+	if config.PolicyViolationAction == PolicyActionBlock && policyConfigured && config.MRZMode != MRZModeRequired {
+		return nil, fmt.Errorf("MRZ_MODE must be %q when POLICY_VIOLATION_ACTION is %q", MRZModeRequired, PolicyActionBlock)
+	}
+
+	if config.ChannelOwnerRole != ChannelOwnerRoleCreator && config.ChannelOwnerRole != ChannelOwnerRoleAdministrator {
+		return nil, fmt.Errorf("CHANNEL_OWNER_ROLE must be %q or %q", ChannelOwnerRoleCreator, ChannelOwnerRoleAdministrator)
+	}
+
+	if config.ChannelLinkTimeout <= 0 {
+		return nil, fmt.Errorf("CHANNEL_LINK_TIMEOUT must be positive")
+	}
+
+	if config.ChannelReconcileInterval < 0 {
+		return nil, fmt.Errorf("CHANNEL_RECONCILE_INTERVAL must not be negative")
+	}
+
+	if config.InviteLinkTTL <= 0 {
+		return nil, fmt.Errorf("INVITE_LINK_TTL must be positive")
+	}
+
+	if config.ExpiryCheckInterval < 0 || config.ExpiryGracePeriod < 0 {
+		return nil, fmt.Errorf("EXPIRY_CHECK_INTERVAL and EXPIRY_GRACE_PERIOD must not be negative")
+	}
+
+	if config.BackendLookupTimeout <= 0 {
+		return nil, fmt.Errorf("BACKEND_LOOKUP_TIMEOUT must be positive")
+	}
+
+	if config.ChannelSettingsCacheTTL < 0 {
+		return nil, fmt.Errorf("CHANNEL_SETTINGS_CACHE_TTL must not be negative")
+	}
+
+	if config.OutboxPollInterval <= 0 {
+		return nil, fmt.Errorf("OUTBOX_POLL_INTERVAL must be positive")
+	}
+
+	if config.OutboxMaxAttempts < 1 {
+		return nil, fmt.Errorf("OUTBOX_MAX_ATTEMPTS must be at least 1")
+	}
+
 	return config, nil
 }
 
@@ -67,3 +361,24 @@ func getEnvAsInt64(key string, defaultValue int64) int64 {
 	}
 	return defaultValue
 }
+
+// getEnvAsFloat получает значение переменной окружения как float64 или возвращает значение по умолчанию
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsDuration получает значение переменной окружения как time.Duration (например, "30s")
+// или возвращает значение по умолчанию
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,137 @@
+package fakebackend
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// faultRequest описание сбоя для служебного эндпоинта; latency задается строкой, например "2s"
+type faultRequest struct {
+	Path    string        `json:"path"`
+	Times   int           `json:"times"`
+	Latency string        `json:"latency"`
+	Status  int           `json:"status"`
+	Code    string        `json:"code"`
+	Message string        `json:"message"`
+	Details []ErrorDetail `json:"details"`
+}
+
+// registerControlRoutes регистрирует служебные эндпоинты для сценариев и отладки:
+//
+//	POST /_fake/faults                — добавить сбой
+//	POST /_fake/subscriptions         — оформить активную подписку
+//	POST /_fake/expired-subscriptions — добавить истекшую подписку
+//	GET  /_fake/requests              — записанные запросы
+//	GET  /_fake/state                 — состояние
+//	POST /_fake/reset                 — сбросить все
+func (s *Server) registerControlRoutes() {
+	s.mux.HandleFunc(ControlPrefix+"faults", s.handleFaults)
+	s.mux.HandleFunc(ControlPrefix+"subscriptions", s.handleAddSubscription)
+	s.mux.HandleFunc(ControlPrefix+"expired-subscriptions", s.handleExpireSubscription)
+	s.mux.HandleFunc(ControlPrefix+"requests", s.handleRequests)
+	s.mux.HandleFunc(ControlPrefix+"state", s.handleState)
+	s.mux.HandleFunc(ControlPrefix+"reset", s.handleReset)
+}
+
+// handleFaults добавляет сбой
+func (s *Server) handleFaults(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var req faultRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	fault := Fault{
+		Path:    req.Path,
+		Times:   req.Times,
+		Status:  req.Status,
+		Code:    req.Code,
+		Message: req.Message,
+		Details: req.Details,
+	}
+	if req.Latency != "" {
+		latency, err := time.ParseDuration(req.Latency)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, "validation_error", "invalid latency", nil)
+			return
+		}
+		fault.Latency = latency
+	}
+
+	s.Inject(fault)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true})
+}
+
+// handleAddSubscription оформляет активную подписку
+func (s *Server) handleAddSubscription(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var payload struct {
+		ChannelID    int64 `json:"channel_id"`
+		SubscriberID int64 `json:"subscriber_id"`
+	}
+	if !decodeJSON(w, r, &payload) {
+		return
+	}
+
+	s.state.AddSubscription(payload.ChannelID, payload.SubscriberID)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true})
+}
+
+// handleExpireSubscription добавляет истекшую подписку
+func (s *Server) handleExpireSubscription(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var subscription Subscription
+	if !decodeJSON(w, r, &subscription) {
+		return
+	}
+	if subscription.ExpiredAt.IsZero() {
+		subscription.ExpiredAt = time.Now()
+	}
+
+	s.state.ExpireSubscription(subscription)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true})
+}
+
+// handleRequests отдает записанные запросы; тела JSON-запросов отдаются как есть
+func (s *Server) handleRequests(w http.ResponseWriter, r *http.Request) {
+	requests := s.Requests()
+	result := make([]map[string]interface{}, 0, len(requests))
+	for _, req := range requests {
+		entry := map[string]interface{}{
+			"method":      req.Method,
+			"path":        req.Path,
+			"headers":     req.Header,
+			"received_at": req.ReceivedAt,
+		}
+		if json.Valid(req.Body) {
+			entry["body"] = json.RawMessage(req.Body)
+		} else {
+			entry["body"] = string(req.Body)
+		}
+		result = append(result, entry)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleState отдает состояние
+func (s *Server) handleState(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.state)
+}
+
+// handleReset сбрасывает состояние, запросы и сбои
+func (s *Server) handleReset(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+	s.Reset()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true})
+}
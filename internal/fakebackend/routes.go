@@ -0,0 +1,481 @@
+package fakebackend
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// registerRoutes регистрирует эндпоинты API бэкенда
+func (s *Server) registerRoutes() {
+	s.mux.HandleFunc("/v1/check-verified-passport", s.handleCheckVerifiedPassport)
+	s.mux.HandleFunc("/v1/add-bot", s.handleAddBot)
+	s.mux.HandleFunc("/v1/update-channel", s.handleUpdateChannel)
+	s.mux.HandleFunc("/v1/deactivate-channel", s.handleDeactivateChannel)
+	s.mux.HandleFunc("/v1/channel-permissions", s.handleChannelPermissions)
+	s.mux.HandleFunc("/v1/channels", s.handleListChannels)
+	s.mux.HandleFunc("/v1/channel-reconciliation", s.handleChannelReconciliation)
+	s.mux.HandleFunc("/v1/invite-links", s.handleInviteLink)
+	s.mux.HandleFunc("/v1/channel-access", s.handleChannelAccess)
+	s.mux.HandleFunc("/v1/channel-settings", s.handleChannelSettings)
+	s.mux.HandleFunc("/v1/expired-subscriptions", s.handleExpiredSubscriptions)
+	s.mux.HandleFunc("/v1/subscription-removals", s.handleSubscriptionRemoval)
+	s.mux.HandleFunc("/v1/users/", s.handleGetUser)
+}
+
+// handleCheckVerifiedPassport сохраняет статус верификации пользователя
+func (s *Server) handleCheckVerifiedPassport(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var payload struct {
+		UserID        int64                  `json:"userId"`
+		IsVerificated *bool                  `json:"isVerificated"`
+		Identity      map[string]interface{} `json:"identity"`
+		Policy        map[string]interface{} `json:"policy"`
+		Screening     map[string]interface{} `json:"screening"`
+	}
+	if !decodeJSON(w, r, &payload) {
+		return
+	}
+
+	var details []ErrorDetail
+	if payload.UserID == 0 {
+		details = append(details, ErrorDetail{Field: "userId", Message: "required"})
+	}
+	if payload.IsVerificated == nil {
+		details = append(details, ErrorDetail{Field: "isVerificated", Message: "required"})
+	}
+	if len(details) > 0 {
+		writeError(w, http.StatusUnprocessableEntity, "validation_error", "invalid payload", details)
+		return
+	}
+
+	s.state.mutex.Lock()
+	s.state.verifications[payload.UserID] = Verification{
+		UserID:     payload.UserID,
+		IsVerified: *payload.IsVerificated,
+		Identity:   payload.Identity,
+		Policy:     payload.Policy,
+		Screening:  payload.Screening,
+		UpdatedAt:  time.Now(),
+	}
+	s.state.mutex.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true})
+}
+
+// handleAddBot регистрирует канал по его ID, отклоняя повторную регистрацию
+func (s *Server) handleAddBot(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var payload struct {
+		UserID          int64  `json:"user_id"`
+		ChannelID       int64  `json:"channel_id"`
+		ChatType        string `json:"chat_type"`
+		ChannelTitle    string `json:"channel_title"`
+		ChannelUsername string `json:"channel_username"`
+		Description     string `json:"description"`
+		PhotoFileID     string `json:"photo_file_id"`
+		PhotoUniqueID   string `json:"photo_unique_id"`
+	}
+	if !decodeJSON(w, r, &payload) {
+		return
+	}
+
+	var details []ErrorDetail
+	if payload.UserID == 0 {
+		details = append(details, ErrorDetail{Field: "user_id", Message: "required"})
+	}
+	if payload.ChannelID == 0 {
+		details = append(details, ErrorDetail{Field: "channel_id", Message: "required"})
+	}
+	if len(details) > 0 {
+		writeError(w, http.StatusUnprocessableEntity, "validation_error", "invalid payload", details)
+		return
+	}
+
+	now := time.Now()
+	s.state.mutex.Lock()
+	existing, exists := s.state.channels[payload.ChannelID]
+	if !exists || !existing.Active {
+		// Повторное добавление бота возобновляет отключенный канал
+		s.state.channels[payload.ChannelID] = Channel{
+			ChatID:             payload.ChannelID,
+			ChatType:           payload.ChatType,
+			UserID:             payload.UserID,
+			Title:              payload.ChannelTitle,
+			Username:           payload.ChannelUsername,
+			Description:        payload.Description,
+			PhotoFileID:        payload.PhotoFileID,
+			PhotoUniqueID:      payload.PhotoUniqueID,
+			Active:             true,
+			AddedAt:            now,
+			UpdatedAt:          now,
+			MissingPermissions: existing.MissingPermissions,
+		}
+	}
+	s.state.mutex.Unlock()
+
+	if exists && existing.Active {
+		writeError(w, http.StatusBadRequest, "channel_already_added", "channel is already added", nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true})
+}
+
+// handleUpdateChannel обновляет название, username и метаданные канала
+func (s *Server) handleUpdateChannel(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var payload struct {
+		ChannelID       int64  `json:"channel_id"`
+		ChannelTitle    string `json:"channel_title"`
+		ChannelUsername string `json:"channel_username"`
+		Description     string `json:"description"`
+		PhotoFileID     string `json:"photo_file_id"`
+		PhotoUniqueID   string `json:"photo_unique_id"`
+	}
+	if !decodeJSON(w, r, &payload) {
+		return
+	}
+
+	s.state.mutex.Lock()
+	channel, exists := s.state.channels[payload.ChannelID]
+	if exists {
+		channel.Title = payload.ChannelTitle
+		channel.Username = payload.ChannelUsername
+		channel.Description = payload.Description
+		channel.PhotoFileID = payload.PhotoFileID
+		channel.PhotoUniqueID = payload.PhotoUniqueID
+		channel.UpdatedAt = time.Now()
+		s.state.channels[payload.ChannelID] = channel
+	}
+	s.state.mutex.Unlock()
+
+	if !exists {
+		writeError(w, http.StatusNotFound, "channel_not_found", "channel not found", nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true})
+}
+
+// handleDeactivateChannel отключает зарегистрированный канал
+func (s *Server) handleDeactivateChannel(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var payload struct {
+		ChannelID int64 `json:"channel_id"`
+	}
+	if !decodeJSON(w, r, &payload) {
+		return
+	}
+
+	s.state.mutex.Lock()
+	channel, exists := s.state.channels[payload.ChannelID]
+	if exists {
+		channel.Active = false
+		s.state.channels[payload.ChannelID] = channel
+	}
+	s.state.mutex.Unlock()
+
+	if !exists {
+		writeError(w, http.StatusNotFound, "channel_not_found", "channel not found", nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true})
+}
+
+// handleChannelPermissions сохраняет последний результат проверки прав бота в канале
+func (s *Server) handleChannelPermissions(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var payload struct {
+		ChannelID int64    `json:"channel_id"`
+		Missing   []string `json:"missing"`
+	}
+	if !decodeJSON(w, r, &payload) {
+		return
+	}
+
+	s.state.mutex.Lock()
+	channel, exists := s.state.channels[payload.ChannelID]
+	if exists {
+		channel.MissingPermissions = payload.Missing
+		s.state.channels[payload.ChannelID] = channel
+	}
+	s.state.mutex.Unlock()
+
+	if !exists {
+		writeError(w, http.StatusNotFound, "channel_not_found", "channel not found", nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true})
+}
+
+// handleListChannels отдает все зарегистрированные каналы
+func (s *Server) handleListChannels(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	s.state.mutex.Lock()
+	channels := make([]map[string]interface{}, 0, len(s.state.channels))
+	for _, channel := range s.state.channels {
+		channels = append(channels, channelJSON(channel))
+	}
+	s.state.mutex.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"channels": channels})
+}
+
+// handleChannelReconciliation отключает каналы, в которых бот по данным сверки
+// удален или больше не администратор
+func (s *Server) handleChannelReconciliation(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var payload struct {
+		Discrepancies []struct {
+			ChannelID int64  `json:"channel_id"`
+			Kind      string `json:"kind"`
+		} `json:"discrepancies"`
+	}
+	if !decodeJSON(w, r, &payload) {
+		return
+	}
+
+	s.state.mutex.Lock()
+	for _, d := range payload.Discrepancies {
+		channel, exists := s.state.channels[d.ChannelID]
+		if !exists || (d.Kind != "bot_removed" && d.Kind != "bot_not_admin") {
+			continue
+		}
+		channel.Active = false
+		s.state.channels[d.ChannelID] = channel
+	}
+	s.state.mutex.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true})
+}
+
+// handleInviteLink сохраняет последний статус персональной ссылки подписчика
+func (s *Server) handleInviteLink(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var payload Invite
+	if !decodeJSON(w, r, &payload) {
+		return
+	}
+	if payload.Name == "" {
+		writeError(w, http.StatusUnprocessableEntity, "validation_error", "invalid payload",
+			[]ErrorDetail{{Field: "name", Message: "required"}})
+		return
+	}
+
+	payload.UpdatedAt = time.Now()
+	s.state.mutex.Lock()
+	s.state.invites[payload.Name] = payload
+	s.state.mutex.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true})
+}
+
+// handleChannelAccess отдает правило вступления в канал и статус пользователя
+func (s *Server) handleChannelAccess(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	query := r.URL.Query()
+	channelID, err := strconv.ParseInt(query.Get("channel_id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "validation_error", "invalid channel_id", nil)
+		return
+	}
+	userID, err := strconv.ParseInt(query.Get("user_id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "validation_error", "invalid user_id", nil)
+		return
+	}
+
+	s.state.mutex.Lock()
+	channel, exists := s.state.channels[channelID]
+	subscribed := s.state.subscriptions[subscriptionKey(channelID, userID)]
+	verification, verified := s.state.verifications[userID]
+	s.state.mutex.Unlock()
+
+	if !exists || !channel.Active {
+		writeError(w, http.StatusNotFound, "channel_not_found", "channel not found", nil)
+		return
+	}
+	policy := channel.JoinPolicy
+	if policy == "" {
+		policy = "subscription"
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"channel_id":       channelID,
+		"user_id":          userID,
+		"policy":           policy,
+		"has_subscription": subscribed,
+		"is_verified":      verified && verification.IsVerified,
+		"subscribe_url":    fmt.Sprintf("https://t.me/tribute_egorbot/app?startapp=channel%d", channelID),
+	})
+}
+
+// handleChannelSettings отдает настройки кнопок под постами канала
+func (s *Server) handleChannelSettings(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	channelID, err := strconv.ParseInt(r.URL.Query().Get("channel_id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "validation_error", "invalid channel_id", nil)
+		return
+	}
+
+	s.state.mutex.Lock()
+	channel, exists := s.state.channels[channelID]
+	s.state.mutex.Unlock()
+
+	if !exists || !channel.Active {
+		writeError(w, http.StatusNotFound, "channel_not_found", "channel not found", nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"channel_id": channelID,
+		"post_buttons": map[string]interface{}{
+			"tip":             channel.TipButton,
+			"subscribe":       channel.SubscribeButton,
+			"exclude_hashtag": channel.ExcludeHashtag,
+		},
+		"tip_url": fmt.Sprintf("https://t.me/tribute_egorbot/app?startapp=donate%d", channelID),
+	})
+}
+
+// handleExpiredSubscriptions отдает истекшие подписки, подписчики которых еще не удалены
+func (s *Server) handleExpiredSubscriptions(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	s.state.mutex.Lock()
+	subscriptions := make([]Subscription, 0, len(s.state.expired))
+	for _, subscription := range s.state.expired {
+		if !subscription.Removed {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	s.state.mutex.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"subscriptions": subscriptions})
+}
+
+// handleSubscriptionRemoval отмечает подписчика удаленным из канала
+func (s *Server) handleSubscriptionRemoval(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var payload struct {
+		ChannelID    int64 `json:"channel_id"`
+		SubscriberID int64 `json:"subscriber_id"`
+	}
+	if !decodeJSON(w, r, &payload) {
+		return
+	}
+
+	key := subscriptionKey(payload.ChannelID, payload.SubscriberID)
+	s.state.mutex.Lock()
+	subscription, exists := s.state.expired[key]
+	if exists {
+		subscription.Removed = true
+		s.state.expired[key] = subscription
+	}
+	s.state.mutex.Unlock()
+
+	if !exists {
+		writeError(w, http.StatusNotFound, "subscription_not_found", "subscription not found", nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true})
+}
+
+// handleGetUser отдает профиль пользователя, собранный из верификации и каналов
+func (s *Server) handleGetUser(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	userID, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/v1/users/"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "validation_error", "invalid user id", nil)
+		return
+	}
+
+	s.state.mutex.Lock()
+	verification, verified := s.state.verifications[userID]
+	channels := make([]map[string]interface{}, 0)
+	for _, channel := range s.state.channels {
+		if channel.UserID == userID {
+			channels = append(channels, channelJSON(channel))
+		}
+	}
+	s.state.mutex.Unlock()
+
+	if !verified && len(channels) == 0 {
+		writeError(w, http.StatusNotFound, "user_not_found", "user not found", nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"user_id":     userID,
+		"is_verified": verified && verification.IsVerified,
+		"is_creator":  len(channels) > 0,
+		"channels":    channels,
+	})
+}
+
+// channelJSON представляет канал в ответах API
+func channelJSON(channel Channel) map[string]interface{} {
+	return map[string]interface{}{
+		"chat_id":  channel.ChatID,
+		"user_id":  channel.UserID,
+		"title":    channel.Title,
+		"username": channel.Username,
+		"active":   channel.Active,
+	}
+}
+
+// requireMethod отклоняет запросы с другим методом
+func requireMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed", nil)
+		return false
+	}
+	return true
+}
+
+// decodeJSON разбирает тело запроса, отвечая ошибкой валидации при неудаче
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "validation_error", "invalid JSON: "+err.Error(), nil)
+		return false
+	}
+	return true
+}
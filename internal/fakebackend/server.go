@@ -0,0 +1,280 @@
+// Package fakebackend реализует API бэкенда Tribute в памяти для локальной
+// разработки и интеграционных тестов. Server реализует http.Handler
+// и подходит для httptest.NewServer.
+package fakebackend
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"tribute-chatbot/internal/signing"
+)
+
+// ControlPrefix префикс служебных эндпоинтов управления фейком
+const ControlPrefix = "/_fake/"
+
+// Options настройки фейкового бэкенда
+type Options struct {
+	// BearerToken, если задан, требуется в заголовке Authorization
+	BearerToken string
+	// HMACSecret, если задан, требует подпись запросов как в signing.Sign
+	HMACSecret string
+}
+
+// RecordedRequest запрос, полученный фейком
+type RecordedRequest struct {
+	Method     string
+	Path       string
+	Header     http.Header
+	Body       []byte
+	ReceivedAt time.Time
+}
+
+// JSON разбирает тело запроса в v
+func (r RecordedRequest) JSON(v interface{}) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// ErrorDetail уточнение ошибки в ответе
+type ErrorDetail struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Fault сбой, который фейк вернет вместо обработки запроса
+type Fault struct {
+	Path    string        `json:"path"`    // пустой — любой путь API
+	Times   int           `json:"times"`   // сколько запросов затронуть, 0 — все
+	Latency time.Duration `json:"latency"` // задержка перед ответом
+	Status  int           `json:"status"`  // 0 — только задержка, запрос обрабатывается
+	Code    string        `json:"code"`
+	Message string        `json:"message"`
+	Details []ErrorDetail `json:"details"`
+}
+
+// response сохраненный ответ для повтора по ключу идемпотентности
+type response struct {
+	status int
+	body   []byte
+}
+
+// Server фейковый бэкенд с состоянием в памяти
+type Server struct {
+	options   Options
+	verifier  *signing.Verifier
+	mux       *http.ServeMux
+	state     *State
+	requests  []RecordedRequest
+	faults    []*Fault
+	responses map[string]response
+	mutex     sync.Mutex
+}
+
+// New создает фейковый бэкенд
+func New(options Options) *Server {
+	s := &Server{
+		options:   options,
+		mux:       http.NewServeMux(),
+		state:     newState(),
+		responses: make(map[string]response),
+	}
+	if options.HMACSecret != "" {
+		s.verifier = signing.NewVerifier([]byte(options.HMACSecret), 5*time.Minute)
+	}
+	s.registerRoutes()
+	s.registerControlRoutes()
+	return s
+}
+
+// ServeHTTP обрабатывает запрос: служебные эндпоинты напрямую, запросы API —
+// с записью, проверкой аутентификации, сбоями и повтором по ключу идемпотентности
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, ControlPrefix) {
+		s.mux.ServeHTTP(w, r)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "failed to read body", nil)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	s.record(r, body)
+
+	if !s.authenticate(w, r, body) {
+		return
+	}
+
+	if fault := s.takeFault(r.URL.Path); fault != nil {
+		if fault.Latency > 0 {
+			select {
+			case <-time.After(fault.Latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if fault.Status != 0 {
+			writeError(w, fault.Status, fault.Code, fault.Message, fault.Details)
+			return
+		}
+	}
+
+	key := r.Header.Get("Idempotency-Key")
+	if key != "" {
+		if cached, ok := s.cachedResponse(r.URL.Path, key); ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(cached.status)
+			w.Write(cached.body)
+			return
+		}
+	}
+
+	recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+	s.mux.ServeHTTP(recorder, r)
+	if key != "" && recorder.status < 500 {
+		s.cacheResponse(r.URL.Path, key, response{status: recorder.status, body: recorder.body.Bytes()})
+	}
+}
+
+// State возвращает состояние фейка
+func (s *Server) State() *State {
+	return s.state
+}
+
+// Inject добавляет сбой. Сбои применяются в порядке добавления.
+func (s *Server) Inject(fault Fault) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.faults = append(s.faults, &fault)
+}
+
+// Requests возвращает копию всех записанных запросов API
+func (s *Server) Requests() []RecordedRequest {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]RecordedRequest(nil), s.requests...)
+}
+
+// RequestsTo возвращает записанные запросы к указанному пути
+func (s *Server) RequestsTo(path string) []RecordedRequest {
+	var result []RecordedRequest
+	for _, req := range s.Requests() {
+		if req.Path == path {
+			result = append(result, req)
+		}
+	}
+	return result
+}
+
+// Reset очищает состояние, записанные запросы, сбои и сохраненные ответы
+func (s *Server) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.requests = nil
+	s.faults = nil
+	s.responses = make(map[string]response)
+	s.state.reset()
+}
+
+// record сохраняет запрос
+func (s *Server) record(r *http.Request, body []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.requests = append(s.requests, RecordedRequest{
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Header:     r.Header.Clone(),
+		Body:       body,
+		ReceivedAt: time.Now(),
+	})
+}
+
+// authenticate проверяет учетные данные запроса согласно настройкам
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request, body []byte) bool {
+	if s.options.BearerToken != "" && r.Header.Get("Authorization") != "Bearer "+s.options.BearerToken {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "invalid bearer token", nil)
+		return false
+	}
+	if s.verifier != nil {
+		if err := s.verifier.Verify(r, body, time.Now()); err != nil {
+			writeError(w, http.StatusUnauthorized, "unauthorized", err.Error(), nil)
+			return false
+		}
+	}
+	return true
+}
+
+// takeFault возвращает сбой для пути и уменьшает его счетчик
+func (s *Server) takeFault(path string) *Fault {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, fault := range s.faults {
+		if fault.Path != "" && fault.Path != path {
+			continue
+		}
+		result := *fault
+		if fault.Times > 0 {
+			fault.Times--
+			if fault.Times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		return &result
+	}
+	return nil
+}
+
+// cachedResponse возвращает ответ на запрос с тем же ключом идемпотентности
+func (s *Server) cachedResponse(path, key string) (response, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	cached, ok := s.responses[path+" "+key]
+	return cached, ok
+}
+
+// cacheResponse сохраняет ответ для повтора по ключу идемпотентности
+func (s *Server) cacheResponse(path, key string, resp response) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.responses[path+" "+key] = resp
+}
+
+// responseRecorder запоминает статус и тело ответа
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+// writeJSON отправляет JSON-ответ
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError отправляет ошибку в формате бэкенда
+func writeError(w http.ResponseWriter, status int, code, message string, details []ErrorDetail) {
+	writeJSON(w, status, map[string]interface{}{
+		"code":    code,
+		"message": message,
+		"details": details,
+	})
+}
@@ -0,0 +1,194 @@
+package fakebackend
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Verification статус верификации пользователя, полученный фейком
+type Verification struct {
+	UserID     int64                  `json:"user_id"`
+	IsVerified bool                   `json:"is_verified"`
+	Identity   map[string]interface{} `json:"identity,omitempty"`
+	Policy     map[string]interface{} `json:"policy,omitempty"`
+	Screening  map[string]interface{} `json:"screening,omitempty"`
+	UpdatedAt  time.Time              `json:"updated_at"`
+}
+
+// Channel канал, зарегистрированный через фейк
+type Channel struct {
+	ChatID        int64  `json:"chat_id"`
+	ChatType      string `json:"chat_type"`
+	UserID        int64  `json:"user_id"`
+	Title         string `json:"title"`
+	Username      string `json:"username"`
+	Description   string `json:"description"`
+	PhotoFileID   string `json:"photo_file_id"`
+	PhotoUniqueID string `json:"photo_unique_id"`
+	Active        bool   `json:"active"`
+	JoinPolicy    string `json:"join_policy"`
+	// Кнопки под постами канала
+	TipButton       bool      `json:"tip_button"`
+	SubscribeButton bool      `json:"subscribe_button"`
+	ExcludeHashtag  string    `json:"exclude_hashtag"`
+	AddedAt         time.Time `json:"added_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	// MissingPermissions права, которых не хватало боту при последней проверке
+	MissingPermissions []string `json:"missing_permissions"`
+}
+
+// Invite персональная ссылка подписчика в канал по последнему отчету бота
+type Invite struct {
+	Name         string    `json:"name"`
+	Link         string    `json:"link"`
+	ChatID       int64     `json:"channel_id"`
+	SubscriberID int64     `json:"subscriber_id"`
+	Status       string    `json:"status"`
+	UsedBy       int64     `json:"used_by,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Subscription истекшая подписка, подписчика которой бот должен удалить из канала
+type Subscription struct {
+	ChannelID    int64     `json:"channel_id"`
+	ChannelTitle string    `json:"channel_title"`
+	SubscriberID int64     `json:"subscriber_id"`
+	ExpiredAt    time.Time `json:"expired_at"`
+	RenewURL     string    `json:"renew_url"`
+	Removed      bool      `json:"removed"`
+}
+
+// State состояние фейкового бэкенда в памяти
+type State struct {
+	verifications map[int64]Verification
+	channels      map[int64]Channel
+	invites       map[string]Invite
+	expired       map[string]Subscription
+	subscriptions map[string]bool
+	mutex         sync.Mutex
+}
+
+// newState создает пустое состояние
+func newState() *State {
+	return &State{
+		verifications: make(map[int64]Verification),
+		channels:      make(map[int64]Channel),
+		invites:       make(map[string]Invite),
+		expired:       make(map[string]Subscription),
+		subscriptions: make(map[string]bool),
+	}
+}
+
+// Verification возвращает статус верификации пользователя
+func (s *State) Verification(userID int64) (Verification, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	verification, ok := s.verifications[userID]
+	return verification, ok
+}
+
+// Channels возвращает зарегистрированные каналы
+func (s *State) Channels() []Channel {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	channels := make([]Channel, 0, len(s.channels))
+	for _, channel := range s.channels {
+		channels = append(channels, channel)
+	}
+	return channels
+}
+
+// Invites возвращает персональные ссылки подписчиков
+func (s *State) Invites() []Invite {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	invites := make([]Invite, 0, len(s.invites))
+	for _, invite := range s.invites {
+		invites = append(invites, invite)
+	}
+	return invites
+}
+
+// AddSubscription оформляет активную подписку подписчика на канал
+func (s *State) AddSubscription(channelID, subscriberID int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.subscriptions[subscriptionKey(channelID, subscriberID)] = true
+}
+
+// ExpireSubscription добавляет истекшую подписку, подписчика которой нужно удалить
+func (s *State) ExpireSubscription(subscription Subscription) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	key := subscriptionKey(subscription.ChannelID, subscription.SubscriberID)
+	s.expired[key] = subscription
+	delete(s.subscriptions, key)
+}
+
+// RenewSubscription убирает подписку из истекших и снова делает ее активной, как при продлении
+func (s *State) RenewSubscription(channelID, subscriberID int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	key := subscriptionKey(channelID, subscriberID)
+	delete(s.expired, key)
+	s.subscriptions[key] = true
+}
+
+// AddChannel регистрирует канал напрямую, например для подготовки теста
+func (s *State) AddChannel(channel Channel) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.channels[channel.ChatID] = channel
+}
+
+// MarshalJSON отдает состояние целиком для служебного эндпоинта
+func (s *State) MarshalJSON() ([]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	verifications := make([]Verification, 0, len(s.verifications))
+	for _, verification := range s.verifications {
+		verifications = append(verifications, verification)
+	}
+	channels := make([]Channel, 0, len(s.channels))
+	for _, channel := range s.channels {
+		channels = append(channels, channel)
+	}
+	invites := make([]Invite, 0, len(s.invites))
+	for _, invite := range s.invites {
+		invites = append(invites, invite)
+	}
+	expired := make([]Subscription, 0, len(s.expired))
+	for _, subscription := range s.expired {
+		expired = append(expired, subscription)
+	}
+	subscriptions := make([]string, 0, len(s.subscriptions))
+	for key := range s.subscriptions {
+		subscriptions = append(subscriptions, key)
+	}
+	return json.Marshal(map[string]interface{}{
+		"verifications":         verifications,
+		"channels":              channels,
+		"invites":               invites,
+		"subscriptions":         subscriptions,
+		"expired_subscriptions": expired,
+	})
+}
+
+// reset очищает состояние
+func (s *State) reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.verifications = make(map[int64]Verification)
+	s.channels = make(map[int64]Channel)
+	s.invites = make(map[string]Invite)
+	s.expired = make(map[string]Subscription)
+	s.subscriptions = make(map[string]bool)
+}
+
+// subscriptionKey идентифицирует подписку подписчика на канал
+func subscriptionKey(channelID, subscriberID int64) string {
+	return strconv.FormatInt(channelID, 10) + ":" + strconv.FormatInt(subscriberID, 10)
+}
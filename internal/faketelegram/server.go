@@ -0,0 +1,159 @@
+// Package faketelegram реализует Bot API Telegram в памяти для тестов обработчиков.
+// Server реализует http.Handler и подходит для httptest.NewServer, а бот,
+// созданный NewBot, отправляет в него все вызовы.
+package faketelegram
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// Token токен бота, которого создает NewBot
+const Token = "test-token"
+
+// Call вызов метода Bot API, полученный фейком
+type Call struct {
+	Method string
+	Params map[string]interface{}
+}
+
+// Param возвращает параметр вызова строкой или пустую строку, если его нет
+func (c Call) Param(name string) string {
+	switch value := c.Params[name].(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	default:
+		data, _ := json.Marshal(value)
+		return string(data)
+	}
+}
+
+// Server фейковый Bot API, записывающий вызовы
+type Server struct {
+	calls     []Call
+	results   map[string]json.RawMessage
+	messageID int
+	mutex     sync.Mutex
+}
+
+// New создает фейковый Bot API. Методы отправки и редактирования отвечают
+// новым сообщением, остальные методы — true, пока для них не задан ответ SetResult.
+func New() *Server {
+	return &Server{results: make(map[string]json.RawMessage)}
+}
+
+// NewBot создает бота, который не обращается к Telegram и вызывает методы по адресу url
+func NewBot(url string) (*tele.Bot, error) {
+	return tele.NewBot(tele.Settings{URL: url, Token: Token, Offline: true})
+}
+
+// ServeHTTP записывает вызов метода и отвечает на него
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+
+	params, err := readParams(r)
+	if err != nil {
+		writeResponse(w, map[string]interface{}{"ok": false, "error_code": 400, "description": "Bad Request: " + err.Error()})
+		return
+	}
+
+	s.mutex.Lock()
+	s.calls = append(s.calls, Call{Method: method, Params: params})
+	result, ok := s.results[method]
+	if !ok {
+		result = s.defaultResultLocked(method, params)
+	}
+	s.mutex.Unlock()
+
+	writeResponse(w, map[string]interface{}{"ok": true, "result": result})
+}
+
+// SetResult задает ответ на вызовы метода
+func (s *Server) SetResult(method string, result interface{}) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %w", err)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.results[method] = data
+	return nil
+}
+
+// Calls возвращает копию всех записанных вызовов
+func (s *Server) Calls() []Call {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// CallsTo возвращает записанные вызовы указанного метода
+func (s *Server) CallsTo(method string) []Call {
+	var result []Call
+	for _, call := range s.Calls() {
+		if call.Method == method {
+			result = append(result, call)
+		}
+	}
+	return result
+}
+
+// defaultResultLocked ответ на вызов, для которого не задан SetResult
+func (s *Server) defaultResultLocked(method string, params map[string]interface{}) json.RawMessage {
+	if !strings.HasPrefix(method, "send") && !strings.HasPrefix(method, "edit") {
+		return json.RawMessage("true")
+	}
+
+	chatID, _ := strconv.ParseInt(Call{Params: params}.Param("chat_id"), 10, 64)
+	messageID, _ := strconv.Atoi(Call{Params: params}.Param("message_id"))
+	if messageID == 0 {
+		s.messageID++
+		messageID = s.messageID
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"message_id": messageID,
+		"chat":       map[string]interface{}{"id": chatID},
+		"date":       time.Now().Unix(),
+	})
+	return data
+}
+
+// readParams разбирает параметры вызова: JSON или multipart/form-data,
+// в котором бот отправляет файлы с диска
+func readParams(r *http.Request) (map[string]interface{}, error) {
+	params := make(map[string]interface{})
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			return nil, err
+		}
+		for name, values := range r.MultipartForm.Value {
+			params[name] = values[0]
+		}
+		for name := range r.MultipartForm.File {
+			params[name] = "attach://" + name
+		}
+		return params, nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil || len(body) == 0 {
+		return params, err
+	}
+	return params, json.Unmarshal(body, &params)
+}
+
+// writeResponse отправляет ответ в формате Bot API
+func writeResponse(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
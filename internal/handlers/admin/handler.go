@@ -0,0 +1,178 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"tribute-chatbot/internal/config"
+	"tribute-chatbot/internal/logger"
+	"tribute-chatbot/internal/models"
+	"tribute-chatbot/internal/services"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// CallbackPrefix префикс callback-данных кнопок админских команд
+const CallbackPrefix = "outbox_"
+
+// Handler обработчик админских команд
+type Handler struct {
+	outboxService *services.OutboxService
+	config        *config.Config
+	logger        logger.Logger
+}
+
+// NewHandler создает новый обработчик админских команд
+func NewHandler(outboxService *services.OutboxService, config *config.Config) *Handler {
+	return &Handler{
+		outboxService: outboxService,
+		config:        config,
+		logger:        logger.New(),
+	}
+}
+
+// HandleOutbox обрабатывает команду /outbox: показывает недоставленные вызовы API
+// и кнопки повтора и удаления для зависших элементов
+func (h *Handler) HandleOutbox(c tele.Context) error {
+	if !h.isAdminChat(c) {
+		return nil
+	}
+
+	items := h.outboxService.Items()
+	if len(items) == 0 {
+		return c.Send("📭 Outbox пуст: все вызовы API доставлены")
+	}
+
+	pending := 0
+	for _, item := range items {
+		if !item.Stuck {
+			pending++
+		}
+	}
+	if err := c.Send(fmt.Sprintf("📤 Ожидают доставки: %d\n⚠️ Зависли: %d", pending, len(items)-pending)); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if !item.Stuck {
+			continue
+		}
+		if err := c.Send(itemText(item), itemMarkup(item.ID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HandleCallback обрабатывает кнопки повтора и удаления элементов outbox
+func (h *Handler) HandleCallback(c tele.Context) error {
+	if !h.isAdminChat(c) {
+		return c.Respond(&tele.CallbackResponse{Text: "⛔ Недостаточно прав"})
+	}
+
+	// Парсим данные: outbox_<retry|discard>_<id>
+	data := strings.TrimSpace(c.Callback().Data)
+	parts := strings.Split(data, "_")
+	if len(parts) != 3 {
+		h.logger.Error("Invalid outbox callback data format:", data)
+		return c.Respond(&tele.CallbackResponse{Text: "❌ Ошибка обработки запроса"})
+	}
+	action, id := parts[1], parts[2]
+
+	var err error
+	var result string
+	switch action {
+	case "retry":
+		err = h.outboxService.Retry(id)
+		result = "🔁 Повторная отправка запущена"
+	case "discard":
+		err = h.outboxService.Discard(id)
+		result = "🗑 Удалено из outbox"
+	default:
+		h.logger.Error("Unknown outbox action:", action)
+		return c.Respond(&tele.CallbackResponse{Text: "❌ Ошибка обработки запроса"})
+	}
+
+	if errors.Is(err, services.ErrOutboxItemInFlight) {
+		// Кнопки остаются: после попытки элемент можно будет удалить
+		return c.Respond(&tele.CallbackResponse{Text: "⏳ Элемент сейчас отправляется, попробуйте позже"})
+	}
+	if errors.Is(err, services.ErrOutboxItemNotFound) {
+		result = "⌛ Элемент уже доставлен или удален"
+	} else if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to %s outbox item %s:", action, id), err)
+		return c.Respond(&tele.CallbackResponse{Text: "❌ Ошибка обработки запроса"})
+	} else {
+		h.logger.Info(fmt.Sprintf("Outbox item %s: %s by user %d", id, action, c.Callback().Sender.ID))
+	}
+
+	if _, editErr := c.Bot().Edit(c.Callback().Message, c.Callback().Message.Text+"\n\n"+result); editErr != nil {
+		h.logger.Error("Failed to update outbox message:", editErr)
+	}
+	return c.Respond(&tele.CallbackResponse{Text: result})
+}
+
+// NotifyCircuitChange сообщает в админский чат о недоступности API и его восстановлении.
+// Переходы между разомкнутым и полуоткрытым состоянием во время сбоя не публикуются.
+func (h *Handler) NotifyCircuitChange(api tele.API, from, to services.CircuitState) {
+	var text string
+	switch {
+	case from == services.CircuitClosed && to == services.CircuitOpen:
+		text = "🔴 API Tribute недоступно: запросы временно не отправляются, " +
+			"решения и регистрации каналов копятся в outbox"
+	case to == services.CircuitClosed:
+		text = "🟢 API Tribute снова доступно: накопленные вызовы отправляются"
+	default:
+		return
+	}
+
+	if _, err := api.Send(&tele.Chat{ID: h.config.TelegramAdminChatID}, text); err != nil {
+		h.logger.Error("Failed to send backend availability alert:", err)
+	}
+}
+
+// isAdminChat сообщает, что команда пришла из админского чата
+func (h *Handler) isAdminChat(c tele.Context) bool {
+	return c.Chat() != nil && c.Chat().ID == h.config.TelegramAdminChatID
+}
+
+// itemText описывает элемент outbox для администратора
+func itemText(item models.OutboxItem) string {
+	return fmt.Sprintf("⚠️ %s (%s)\nСоздан: %s\nПопыток: %d\nОшибка: %s",
+		kindName(item.Kind), item.ID,
+		item.CreatedAt.Format("02.01.2006 15:04:05"),
+		item.Attempts, item.LastError)
+}
+
+// kindName переводит тип элемента outbox для администратора
+func kindName(kind string) string {
+	switch kind {
+	case models.OutboxKindVerification:
+		return "Решение по верификации"
+	case models.OutboxKindChannelRegistration:
+		return "Регистрация канала"
+	case models.OutboxKindChannelUpdate:
+		return "Обновление данных канала"
+	case models.OutboxKindChannelDeactivation:
+		return "Отключение канала"
+	case models.OutboxKindChannelPermissions:
+		return "Проверка прав бота в канале"
+	case models.OutboxKindReconciliation:
+		return "Сверка каналов"
+	case models.OutboxKindInviteLink:
+		return "Ссылка подписчика в канал"
+	case models.OutboxKindSubscriptionRemoval:
+		return "Удаление подписчика из канала"
+	default:
+		return kind
+	}
+}
+
+// itemMarkup создает кнопки повтора и удаления элемента
+func itemMarkup(id string) *tele.ReplyMarkup {
+	markup := &tele.ReplyMarkup{}
+	retryBtn := markup.Data("🔁 Повторить", CallbackPrefix+"retry_"+id)
+	discardBtn := markup.Data("🗑 Удалить", CallbackPrefix+"discard_"+id)
+	markup.Inline(markup.Row(retryBtn, discardBtn))
+	return markup
+}
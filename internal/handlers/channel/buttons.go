@@ -0,0 +1,186 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"tribute-chatbot/internal/models"
+	"tribute-chatbot/internal/services"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// StartPrefix префикс параметра /start в ссылках кнопок под постами:
+// channel_tip_<chat_id> и channel_sub_<chat_id>
+const StartPrefix = "channel_"
+
+// unavailableText ответ на переход по кнопке под постом, пока API недоступно
+const unavailableText = "⚠️ Сервис временно недоступен. Попробуйте позже."
+
+// Действия ссылок кнопок под постами
+const (
+	startActionTip       = "tip_"
+	startActionSubscribe = "sub_"
+)
+
+// HandleChannelPost отслеживает изменения канала по посту и прикрепляет к посту
+// кнопки доната и подписки, если они включены в настройках канала
+func (h *Handler) HandleChannelPost(c tele.Context, me *tele.User) error {
+	if err := h.HandleChannelUpdate(c); err != nil {
+		return err
+	}
+
+	msg := c.Message()
+	if msg == nil || msg.Chat == nil || !acceptsButtons(msg) {
+		return nil
+	}
+	h.attachButtons(services.WithUpdateID(context.Background(), c.Update().ID), c.Bot(), msg, me)
+	return nil
+}
+
+// attachButtons прикрепляет кнопки к посту канала, подключенного в API. Пост пропускается,
+// если кнопки выключены, у поста есть хэштег исключения или бот не может редактировать посты.
+func (h *Handler) attachButtons(ctx context.Context, api tele.API, msg *tele.Message, me *tele.User) {
+	// Подключен ли канал, решает API: сохраненных данных может не быть
+	// у каналов, подключенных до их появления
+	settings, err := h.settings.Get(ctx, msg.Chat.ID)
+	if errors.Is(err, services.ErrChannelNotFound) {
+		return
+	}
+	if err != nil {
+		h.logger.Warn(fmt.Sprintf("Failed to get settings of channel %d, post %d left without buttons: %v",
+			msg.Chat.ID, msg.ID, err))
+		return
+	}
+	if !settings.PostButtons() {
+		return
+	}
+
+	hashtag := settings.ExcludeHashtag
+	if hashtag == "" {
+		hashtag = h.config.PostButtonsExcludeHashtag
+	}
+	if hasHashtag(msg, hashtag) {
+		h.logger.Info(fmt.Sprintf("Post %d in channel %d excluded by %s", msg.ID, msg.Chat.ID, hashtag))
+		return
+	}
+
+	member, err := api.ChatMemberOf(msg.Chat, me)
+	if err != nil {
+		h.logger.Warn(fmt.Sprintf("Failed to check bot rights in channel %d: %v", msg.Chat.ID, err))
+		return
+	}
+	if !member.Rights.CanEditMessages {
+		h.logger.Info(fmt.Sprintf("Bot cannot edit posts in channel %d, post %d left without buttons", msg.Chat.ID, msg.ID))
+		return
+	}
+
+	if _, err := api.EditReplyMarkup(msg, postMarkup(me.Username, msg.Chat.ID, settings)); err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to attach buttons to post %d in channel %d:", msg.ID, msg.Chat.ID), err)
+		return
+	}
+	h.logger.Info(fmt.Sprintf("Buttons attached to post %d in channel %d", msg.ID, msg.Chat.ID))
+}
+
+// HandleStartLink отвечает на переход по кнопке под постом: присылает ссылку
+// на донат автору канала или на оформление подписки
+func (h *Handler) HandleStartLink(c tele.Context) error {
+	payload := strings.TrimPrefix(c.Message().Payload, StartPrefix)
+
+	var action string
+	for _, prefix := range []string{startActionTip, startActionSubscribe} {
+		if strings.HasPrefix(payload, prefix) {
+			action = prefix
+		}
+	}
+	chatID, err := strconv.ParseInt(strings.TrimPrefix(payload, action), 10, 64)
+	if action == "" || err != nil {
+		return c.Send("⚠️ Ссылка недействительна.")
+	}
+
+	ctx := services.WithUpdateID(context.Background(), c.Update().ID)
+	stored, _ := h.channelService.Get(chatID)
+	title := channelName(stored.Title, chatID)
+
+	if action == startActionTip {
+		settings, err := h.settings.Get(ctx, chatID)
+		if err != nil && !errors.Is(err, services.ErrChannelNotFound) {
+			h.logger.Error(fmt.Sprintf("Failed to get settings of channel %d:", chatID), err)
+			return c.Send(unavailableText)
+		}
+		if err != nil || settings.TipURL == "" {
+			return c.Send("⚠️ Донат автору этого канала недоступен.")
+		}
+		markup := &tele.ReplyMarkup{}
+		markup.Inline(markup.Row(markup.URL("💸 Поддержать автора", settings.TipURL)))
+		return c.Send(fmt.Sprintf("Спасибо, что хотите поддержать автора канала «%s»!", title), markup)
+	}
+
+	access, err := h.backend.GetChannelAccess(ctx, chatID, c.Sender().ID)
+	if err != nil && !errors.Is(err, services.ErrChannelNotFound) {
+		h.logger.Error(fmt.Sprintf("Failed to check access to channel %d for user %d:", chatID, c.Sender().ID), err)
+		return c.Send(unavailableText)
+	}
+	if err != nil {
+		return c.Send("⚠️ Подписка на этот канал недоступна.")
+	}
+	if access.HasSubscription {
+		return c.Send(fmt.Sprintf("✅ У вас уже есть активная подписка на канал «%s».", title))
+	}
+	if access.SubscribeURL == "" {
+		return c.Send("⚠️ Подписка на этот канал недоступна.")
+	}
+	markup := &tele.ReplyMarkup{}
+	markup.Inline(markup.Row(markup.URL("⭐ Оформить подписку", access.SubscribeURL)))
+	return c.Send(fmt.Sprintf("Оформите подписку на канал «%s»:", title), markup)
+}
+
+// acceptsButtons сообщает, что к посту можно прикрепить кнопки: это не служебное
+// сообщение, не часть альбома (Telegram не прикрепляет кнопки к альбомам)
+// и у поста еще нет своих кнопок
+func acceptsButtons(msg *tele.Message) bool {
+	if msg.NewGroupTitle != "" || msg.NewGroupPhoto != nil || msg.GroupPhotoDeleted || msg.PinnedMessage != nil {
+		return false
+	}
+	if msg.AlbumID != "" {
+		return false
+	}
+	return msg.ReplyMarkup == nil || len(msg.ReplyMarkup.InlineKeyboard) == 0
+}
+
+// hasHashtag сообщает, что в тексте или подписи поста есть хэштег без учета регистра
+func hasHashtag(msg *tele.Message, hashtag string) bool {
+	hashtag = strings.TrimPrefix(strings.TrimSpace(hashtag), "#")
+	if hashtag == "" {
+		return false
+	}
+
+	entities := append(append(tele.Entities(nil), msg.Entities...), msg.CaptionEntities...)
+	for _, entity := range entities {
+		if entity.Type == tele.EntityHashtag && strings.EqualFold(msg.EntityText(entity), "#"+hashtag) {
+			return true
+		}
+	}
+	return false
+}
+
+// postMarkup кнопки под постом со ссылками на бота
+func postMarkup(botUsername string, chatID int64, settings models.ChannelSettings) *tele.ReplyMarkup {
+	markup := &tele.ReplyMarkup{}
+	var row tele.Row
+	if settings.TipButton {
+		row = append(row, markup.URL("💸 Поддержать", startLink(botUsername, startActionTip, chatID)))
+	}
+	if settings.SubscribeButton {
+		row = append(row, markup.URL("⭐ Подписаться", startLink(botUsername, startActionSubscribe, chatID)))
+	}
+	markup.Inline(row)
+	return markup
+}
+
+// startLink ссылка на бота с параметром /start для кнопки под постом
+func startLink(botUsername, action string, chatID int64) string {
+	return fmt.Sprintf("https://t.me/%s?start=%s%s%d", botUsername, StartPrefix, action, chatID)
+}
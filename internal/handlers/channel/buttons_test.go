@@ -0,0 +1,271 @@
+package channel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"tribute-chatbot/internal/config"
+	"tribute-chatbot/internal/fakebackend"
+	"tribute-chatbot/internal/faketelegram"
+	"tribute-chatbot/internal/services"
+	"tribute-chatbot/internal/storage"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	channelID = -1001
+	userID    = 42
+)
+
+// botUser бот, от имени которого прикрепляются кнопки
+var botUser = &tele.User{ID: 1, Username: "tribute_bot", IsBot: true}
+
+// testEnv обработчик каналов, подключенный к фейковым API и Telegram
+type testEnv struct {
+	handler  *Handler
+	cfg      *config.Config
+	outbox   *services.OutboxService
+	channels *services.ChannelService
+	links    *services.ChannelLinkService
+	backend  *fakebackend.Server
+	telegram *faketelegram.Server
+	bot      *tele.Bot
+	updateID int
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	backend := fakebackend.New(fakebackend.Options{})
+	backendServer := httptest.NewServer(backend)
+	t.Cleanup(backendServer.Close)
+	telegram := faketelegram.New()
+	telegramServer := httptest.NewServer(telegram)
+	t.Cleanup(telegramServer.Close)
+
+	cfg := &config.Config{
+		APIBaseURL:                backendServer.URL,
+		BackendTimeout:            time.Second,
+		BackendMaxRetries:         1,
+		BackendLookupTimeout:      time.Second,
+		BackendRetryBaseDelay:     time.Millisecond,
+		BackendRetryMaxDelay:      time.Millisecond,
+		PostButtonsExcludeHashtag: "#nobuttons",
+		OutboxPollInterval:        time.Hour,
+	}
+	api, err := services.NewAPIService(cfg)
+	if err != nil {
+		t.Fatalf("api service: %v", err)
+	}
+	dir := t.TempDir()
+	outbox, err := services.NewOutboxService(storage.NewJSONFile(filepath.Join(dir, "outbox.json")), api, cfg)
+	if err != nil {
+		t.Fatalf("outbox: %v", err)
+	}
+	channels, err := services.NewChannelService(storage.NewJSONFile(filepath.Join(dir, "channels.json")))
+	if err != nil {
+		t.Fatalf("channels: %v", err)
+	}
+	links, err := services.NewChannelLinkService(storage.NewJSONFile(filepath.Join(dir, "channel_links.json")))
+	if err != nil {
+		t.Fatalf("channel links: %v", err)
+	}
+	bot, err := faketelegram.NewBot(telegramServer.URL)
+	if err != nil {
+		t.Fatalf("bot: %v", err)
+	}
+	if err := telegram.SetResult("getChatMember", map[string]interface{}{
+		"status":            "administrator",
+		"user":              map[string]interface{}{"id": botUser.ID, "is_bot": true},
+		"can_edit_messages": true,
+	}); err != nil {
+		t.Fatalf("set result: %v", err)
+	}
+
+	handler := NewHandler(outbox, api,
+		services.NewUserService(api, 0, cfg.BackendLookupTimeout),
+		channels, links,
+		services.NewChannelSettingsService(api, 0, cfg.BackendLookupTimeout),
+		cfg)
+
+	return &testEnv{
+		handler:  handler,
+		cfg:      cfg,
+		outbox:   outbox,
+		channels: channels,
+		links:    links,
+		backend:  backend,
+		telegram: telegram,
+		bot:      bot,
+	}
+}
+
+// post публикует пост в канале
+func (e *testEnv) post(t *testing.T, msg *tele.Message) {
+	t.Helper()
+	e.updateID++
+	msg.Chat = &tele.Chat{ID: channelID, Title: "News", Type: tele.ChatChannel}
+	if err := e.handler.HandleChannelPost(e.bot.NewContext(tele.Update{ID: e.updateID, ChannelPost: msg}), botUser); err != nil {
+		t.Fatalf("handle channel post: %v", err)
+	}
+}
+
+// start открывает ссылку кнопки под постом
+func (e *testEnv) start(t *testing.T, payload string) string {
+	t.Helper()
+	e.updateID++
+	msg := &tele.Message{
+		ID:      e.updateID,
+		Text:    "/start " + payload,
+		Payload: payload,
+		Sender:  &tele.User{ID: userID},
+		Chat:    &tele.Chat{ID: userID, Type: tele.ChatPrivate},
+	}
+	if err := e.handler.HandleStartLink(e.bot.NewContext(tele.Update{ID: e.updateID, Message: msg})); err != nil {
+		t.Fatalf("handle start link: %v", err)
+	}
+	messages := e.telegram.CallsTo("sendMessage")
+	if len(messages) == 0 {
+		t.Fatal("no reply to start link")
+	}
+	return messages[len(messages)-1].Param("text")
+}
+
+// addChannel подключает канал с кнопками под постами в фейковом API
+func (e *testEnv) addChannel() {
+	e.backend.State().AddChannel(fakebackend.Channel{
+		ChatID:          channelID,
+		Title:           "News",
+		Active:          true,
+		TipButton:       true,
+		SubscribeButton: true,
+	})
+}
+
+func TestAttachesPostButtons(t *testing.T) {
+	env := newTestEnv(t)
+	env.addChannel()
+
+	env.post(t, &tele.Message{ID: 100, Text: "Hello"})
+
+	edits := env.telegram.CallsTo("editMessageReplyMarkup")
+	if len(edits) != 1 {
+		t.Fatalf("edits = %d, want 1", len(edits))
+	}
+	if edits[0].Param("message_id") != "100" {
+		t.Errorf("edited message = %s, want 100", edits[0].Param("message_id"))
+	}
+	markup := edits[0].Param("reply_markup")
+	for _, link := range []string{"start=channel_tip_-1001", "start=channel_sub_-1001"} {
+		if !strings.Contains(markup, link) {
+			t.Errorf("markup %s has no %s", markup, link)
+		}
+	}
+
+	requests := env.backend.RequestsTo("/v1/channel-settings")
+	if len(requests) != 1 {
+		t.Fatalf("settings requests = %d, want 1", len(requests))
+	}
+	if got := requests[0].Header.Get("Idempotency-Key"); got != "tg-update-1-v1-channel-settings" {
+		t.Errorf("Idempotency-Key = %q", got)
+	}
+	if requests[0].Header.Get("X-Request-ID") == "" {
+		t.Error("X-Request-ID is empty")
+	}
+}
+
+func TestSkipsPostsWithoutButtons(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		setup func(env *testEnv)
+		msg   *tele.Message
+	}{
+		{
+			name:  "unconnected channel",
+			setup: func(env *testEnv) {},
+			msg:   &tele.Message{ID: 100, Text: "Hello"},
+		},
+		{
+			name:  "excluded by hashtag",
+			setup: func(env *testEnv) { env.addChannel() },
+			msg: &tele.Message{ID: 100, Text: "Hello #NoButtons", Entities: tele.Entities{
+				{Type: tele.EntityHashtag, Offset: 6, Length: 10},
+			}},
+		},
+		{
+			name:  "album",
+			setup: func(env *testEnv) { env.addChannel() },
+			msg:   &tele.Message{ID: 100, AlbumID: "album"},
+		},
+		{
+			name: "api unavailable",
+			setup: func(env *testEnv) {
+				env.addChannel()
+				env.backend.Inject(fakebackend.Fault{Path: "/v1/channel-settings", Status: http.StatusServiceUnavailable})
+			},
+			msg: &tele.Message{ID: 100, Text: "Hello"},
+		},
+		{
+			name: "api timeout",
+			setup: func(env *testEnv) {
+				env.addChannel()
+				env.cfg.BackendTimeout = 20 * time.Millisecond
+				env.backend.Inject(fakebackend.Fault{Path: "/v1/channel-settings", Latency: time.Second})
+			},
+			msg: &tele.Message{ID: 100, Text: "Hello"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			tc.setup(env)
+
+			env.post(t, tc.msg)
+
+			if edits := env.telegram.CallsTo("editMessageReplyMarkup"); len(edits) != 0 {
+				t.Fatalf("edits = %d, want 0", len(edits))
+			}
+		})
+	}
+}
+
+func TestStartLinks(t *testing.T) {
+	env := newTestEnv(t)
+	env.addChannel()
+
+	if text := env.start(t, "channel_sub_-1001"); !strings.Contains(text, "Оформите подписку") {
+		t.Errorf("subscribe reply = %q", text)
+	}
+	env.backend.State().AddSubscription(channelID, userID)
+	if text := env.start(t, "channel_sub_-1001"); !strings.Contains(text, "уже есть активная подписка") {
+		t.Errorf("subscriber reply = %q", text)
+	}
+	if text := env.start(t, "channel_tip_-1001"); !strings.Contains(text, "поддержать автора") {
+		t.Errorf("tip reply = %q", text)
+	}
+	if text := env.start(t, "channel_sub_-2002"); !strings.Contains(text, "недоступна") {
+		t.Errorf("unknown channel reply = %q", text)
+	}
+	if text := env.start(t, "channel_bad"); !strings.Contains(text, "недействительна") {
+		t.Errorf("invalid link reply = %q", text)
+	}
+}
+
+func TestStartLinksWhenAPIUnavailable(t *testing.T) {
+	env := newTestEnv(t)
+	env.addChannel()
+	env.backend.Inject(fakebackend.Fault{Status: http.StatusBadGateway})
+
+	for _, payload := range []string{"channel_sub_-1001", "channel_tip_-1001"} {
+		if text := env.start(t, payload); text != unavailableText {
+			t.Errorf("%s reply = %q, want %q", payload, text, unavailableText)
+		}
+	}
+	// Проверка доступа повторяется, пока не исчерпаны попытки
+	if requests := env.backend.RequestsTo("/v1/channel-access"); len(requests) != env.cfg.BackendMaxRetries+1 {
+		t.Errorf("access requests = %d, want %d", len(requests), env.cfg.BackendMaxRetries+1)
+	}
+}
@@ -1,10 +1,13 @@
 package channel
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"tribute-chatbot/internal/config"
 	"tribute-chatbot/internal/logger"
+	"tribute-chatbot/internal/models"
 	"tribute-chatbot/internal/services"
 
 	tele "gopkg.in/telebot.v4"
@@ -12,20 +15,52 @@ import (
 
 // Handler обработчик каналов
 type Handler struct {
-	apiService *services.APIService
-	config     *config.Config
-	logger     logger.Logger
+	outboxService  *services.OutboxService
+	backend        services.BackendClient
+	userService    *services.UserService
+	channelService *services.ChannelService
+	linkService    *services.ChannelLinkService
+	settings       *services.ChannelSettingsService
+	config         *config.Config
+	logger         logger.Logger
+	stop           chan struct{}
 }
 
 // NewHandler создает новый обработчик каналов
-func NewHandler(apiService *services.APIService, config *config.Config) *Handler {
+func NewHandler(
+	outboxService *services.OutboxService,
+	backend services.BackendClient,
+	userService *services.UserService,
+	channelService *services.ChannelService,
+	linkService *services.ChannelLinkService,
+	settings *services.ChannelSettingsService,
+	config *config.Config,
+) *Handler {
 	return &Handler{
-		apiService: apiService,
-		config:     config,
-		logger:     logger.New(),
+		outboxService:  outboxService,
+		backend:        backend,
+		userService:    userService,
+		channelService: channelService,
+		linkService:    linkService,
+		settings:       settings,
+		config:         config,
+		logger:         logger.New(),
+		stop:           make(chan struct{}),
 	}
 }
 
+// StartWorkers подписывается на результаты доставки регистраций и отключений каналов в API
+// и запускает отмену просроченных подтверждений привязки каналов
+func (h *Handler) StartWorkers(api tele.API) {
+	h.linkService.Start(func(link models.PendingChannelLink) {
+		h.expireLink(api, link)
+	})
+	h.outboxService.OnResult(models.OutboxKindChannelRegistration, func(item models.OutboxItem, err error) {
+		h.handleDeliveryResult(api, item, err)
+	})
+	h.outboxService.OnResult(models.OutboxKindChannelDeactivation, h.handleDeactivationResult)
+}
+
 // HandleMyChatMember обрабатывает события добавления бота в каналы
 func (h *Handler) HandleMyChatMember(c tele.Context) error {
 	upd := c.ChatMember()
@@ -38,28 +73,231 @@ func (h *Handler) HandleMyChatMember(c tele.Context) error {
 	// Если бот стал админом
 	if oldStatus != "administrator" && newStatus == "administrator" {
 		userID := c.Sender().ID
-		channelTitle := upd.Chat.Title
-		channelUsername := upd.Chat.Username
 
-		h.logger.Info(fmt.Sprintf("userID: %d, channelTitle: '%s', channelUsername: '%s'", userID, channelTitle, channelUsername))
+		h.logger.Info(fmt.Sprintf("userID: %d, chatID: %d, type: %s, channelTitle: '%s', channelUsername: '%s'",
+			userID, upd.Chat.ID, upd.Chat.Type, upd.Chat.Title, upd.Chat.Username))
 
-		if userID == 0 || channelTitle == "" {
-			h.logger.Error("userID or channelTitle is empty, not calling AddBotToChannel")
+		if userID == 0 {
+			h.logger.Error("userID is empty, not calling AddBotToChannel")
 			return nil
 		}
 
-		h.logger.Info("Calling AddBotToChannel endpoint...")
-		err := h.apiService.AddBotToChannel(userID, channelTitle, channelUsername)
+		// Подключить канал к своему аккаунту может только его владелец
+		owner, allowed, err := h.checkOwner(c.Bot(), upd.Chat, upd.Sender)
 		if err != nil {
-			if strings.Contains(err.Error(), "channel is already added") {
-				c.Bot().Send(upd.Sender, "Channel is already added")
-			} else {
-				h.logger.Error("Failed to add bot to channel:", err)
-			}
+			h.logger.Error("Failed to verify channel owner:", err)
+			c.Bot().Send(upd.Sender, fmt.Sprintf("⚠️ Не удалось проверить, что вы владелец канала «%s». "+
+				"Снимите бота с должности администратора и назначьте заново позже.", channelName(upd.Chat.Title, upd.Chat.ID)))
+			return nil
+		}
+		if !allowed {
+			h.refuseRegistration(c.Bot(), upd.Chat, upd.Sender, owner)
+			return nil
 		}
+
+		// Канал привязывается к аккаунту только после подтверждения владельцем
+		channel := h.channelInfo(c.Bot(), upd.Chat, models.Channel{OwnerID: userID})
+		return h.requestLink(c, upd, channel)
+	}
+
+	// Название и username канала в обновлении актуальные
+	h.syncChannel(c, upd.Chat, false)
+
+	// Если у бота-администратора изменились права
+	if oldStatus == "administrator" && newStatus == "administrator" &&
+		upd.OldChatMember.Rights != upd.NewChatMember.Rights {
+		h.auditPermissions(c, upd, true)
 		return nil
 	}
 
+	// Если бота лишили прав администратора или удалили из канала
+	if oldStatus == "administrator" && isRemovedStatus(newStatus) {
+		return h.handleBotRemoved(c, upd, newStatus)
+	}
+
 	h.logger.Info("my_chat_member update: ", oldStatus, " -> ", newStatus)
 	return nil
 }
+
+// handleBotRemoved отключает канал в API и объясняет владельцу канала, как возобновить
+// подписки. Права бота мог изменить другой администратор, поэтому владелец берется
+// из сохраненных данных канала, а пользователь из обновления — только для неизвестного канала.
+func (h *Handler) handleBotRemoved(c tele.Context, upd *tele.ChatMemberUpdate, newStatus string) error {
+	senderID := c.Sender().ID
+
+	h.logger.Info(fmt.Sprintf("Bot lost admin rights: chat_id=%d, title='%s', status=%s, by user %d",
+		upd.Chat.ID, upd.Chat.Title, newStatus, senderID))
+
+	// Канал, ожидавший подтверждения, еще не подключен, отключать в API нечего
+	if h.cancelLink(c.Bot(), upd.Chat.ID) {
+		return nil
+	}
+
+	ownerID := senderID
+	if stored, ok := h.channelService.Get(upd.Chat.ID); ok && stored.OwnerID != 0 {
+		ownerID = stored.OwnerID
+	}
+
+	_, err := h.outboxService.EnqueueChannelDeactivation(models.ChannelDeactivation{
+		UserID:          ownerID,
+		ChatID:          upd.Chat.ID,
+		ChannelTitle:    upd.Chat.Title,
+		ChannelUsername: upd.Chat.Username,
+		Reason:          newStatus,
+	}, fmt.Sprintf("deactivate-%d-update-%d", upd.Chat.ID, c.Update().ID))
+	if err != nil {
+		h.logger.Error("Failed to enqueue channel deactivation:", err)
+		return nil
+	}
+
+	// Отключенный канал больше не получает кнопки под постами и обновления данных
+	if err := h.channelService.Remove(upd.Chat.ID); err != nil {
+		h.logger.Error("Failed to remove deactivated channel:", err)
+	}
+
+	if ownerID != 0 {
+		if _, err := c.Bot().Send(&tele.User{ID: ownerID}, removedText(upd.Chat.Title, newStatus)); err != nil {
+			h.logger.Error("Failed to notify channel owner about deactivation:", err)
+		}
+	}
+	return nil
+}
+
+// auditPermissions сверяет права бота с необходимыми, отправляет владельцу
+// список недостающих прав и передает результат проверки в API.
+// При повторной проверке владелец также узнает, что все права выданы.
+func (h *Handler) auditPermissions(c tele.Context, upd *tele.ChatMemberUpdate, recheck bool) {
+	missing := auditPermissions(upd.Chat, upd.NewChatMember.Rights)
+	granted := recheck && len(auditPermissions(upd.Chat, upd.OldChatMember.Rights)) > 0
+	h.reportPermissions(c.Bot(), upd.Chat, upd.Sender, missing, granted,
+		fmt.Sprintf("permissions-%d-update-%d", upd.Chat.ID, c.Update().ID))
+}
+
+// reportPermissions передает результат проверки прав в API и отправляет владельцу
+// список недостающих прав или, если notifyGranted, сообщение, что все права выданы
+func (h *Handler) reportPermissions(api tele.API, chat *tele.Chat, owner *tele.User, missing []permission, notifyGranted bool, idempotencyKey string) {
+	var userID int64
+	if owner != nil {
+		userID = owner.ID
+	}
+
+	h.logger.Info(fmt.Sprintf("Permission audit: chat_id=%d, missing=%d", chat.ID, len(missing)))
+
+	_, err := h.outboxService.EnqueueChannelPermissions(permissionReport(userID, chat, missing), idempotencyKey)
+	if err != nil {
+		h.logger.Error("Failed to enqueue channel permission report:", err)
+	}
+
+	if userID == 0 {
+		return
+	}
+
+	var text string
+	switch {
+	case len(missing) > 0:
+		text = checklistText(chat.Title, missing)
+	case notifyGranted:
+		text = fmt.Sprintf("✅ Все необходимые права выданы, функции Tribute в «%s» работают.", chat.Title)
+	default:
+		return
+	}
+	if _, err := api.Send(owner, text); err != nil {
+		h.logger.Error("Failed to send permission checklist:", err)
+	}
+}
+
+// handleDeactivationResult обновляет кэш профиля после отключения канала в API
+func (h *Handler) handleDeactivationResult(item models.OutboxItem, deliveryErr error) {
+	var deactivation models.ChannelDeactivation
+	if err := json.Unmarshal(item.Payload, &deactivation); err != nil {
+		h.logger.Error("Failed to decode channel deactivation from outbox:", err)
+		return
+	}
+
+	switch {
+	case deliveryErr == nil:
+		// Регистрация, доставленная раньше отключения, могла снова сохранить канал
+		if err := h.channelService.Remove(deactivation.ChatID); err != nil {
+			h.logger.Error("Failed to remove deactivated channel:", err)
+		}
+		h.userService.Invalidate(deactivation.UserID)
+	case errors.Is(deliveryErr, services.ErrChannelNotFound):
+		// Канал не был зарегистрирован, отключать нечего
+		h.logger.Info(fmt.Sprintf("Deactivated channel %d is unknown to API", deactivation.ChatID))
+	case !errors.Is(deliveryErr, services.ErrOutboxItemDiscarded):
+		h.logger.Error("Failed to deactivate channel:", deliveryErr)
+	}
+}
+
+// isRemovedStatus сообщает, что бот больше не может управлять каналом
+func isRemovedStatus(status string) bool {
+	switch tele.MemberStatus(status) {
+	case tele.Member, tele.Left, tele.Kicked:
+		return true
+	default:
+		return false
+	}
+}
+
+// removedText объясняет владельцу, что подписки канала приостановлены
+func removedText(channelTitle, newStatus string) string {
+	if tele.MemberStatus(newStatus) == tele.Member {
+		return fmt.Sprintf("⏸ Бот больше не администратор канала «%s», поэтому подписки на этот канал приостановлены.\n\n"+
+			"Чтобы возобновить их, снова назначьте бота администратором: "+
+			"Настройки канала → Администраторы → Добавить администратора.", channelTitle)
+	}
+	return fmt.Sprintf("⏸ Бот удален из канала «%s», поэтому подписки на этот канал приостановлены.\n\n"+
+		"Чтобы возобновить их, добавьте бота администратором: "+
+		"Настройки канала → Администраторы → Добавить администратора.", channelTitle)
+}
+
+// handleDeliveryResult обновляет кэш профиля владельца после регистрации канала
+// или сообщает ему, что API отклонило регистрацию
+func (h *Handler) handleDeliveryResult(api tele.API, item models.OutboxItem, deliveryErr error) {
+	if errors.Is(deliveryErr, services.ErrOutboxItemDiscarded) {
+		return
+	}
+
+	var registration models.ChannelRegistration
+	if err := json.Unmarshal(item.Payload, &registration); err != nil {
+		h.logger.Error("Failed to decode channel registration from outbox:", err)
+		return
+	}
+
+	if deliveryErr == nil {
+		// Канал считается подключенным только после того, как API принял регистрацию
+		if err := h.channelService.Register(registration.Channel()); err != nil {
+			h.logger.Error("Failed to save channel:", err)
+		}
+		// Список каналов владельца в API изменился
+		h.userService.Invalidate(registration.UserID)
+		return
+	}
+
+	var text string
+	switch {
+	case errors.Is(deliveryErr, services.ErrChannelAlreadyAdded):
+		text = fmt.Sprintf("ℹ️ Канал «%s» уже подключен.", channelName(registration.ChannelTitle, registration.ChatID))
+	case errors.Is(deliveryErr, services.ErrValidation):
+		h.logger.Error("Channel registration failed validation:", deliveryErr)
+		text = fmt.Sprintf("❌ Не удалось подключить канал «%s»", channelName(registration.ChannelTitle, registration.ChatID))
+		if details := services.ValidationDetails(deliveryErr); len(details) > 0 {
+			text += ":\n" + strings.Join(details, "\n")
+		}
+	default:
+		h.logger.Error("Failed to add bot to channel:", deliveryErr)
+		return
+	}
+
+	if _, err := api.Send(&tele.User{ID: registration.UserID}, text); err != nil {
+		h.logger.Error("Failed to notify channel owner about registration result:", err)
+	}
+}
+
+// channelName возвращает название канала для сообщений или его ID, если названия нет
+func channelName(title string, chatID int64) string {
+	if title != "" {
+		return title
+	}
+	return fmt.Sprintf("%d", chatID)
+}
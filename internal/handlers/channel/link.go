@@ -0,0 +1,200 @@
+package channel
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"tribute-chatbot/internal/models"
+	"tribute-chatbot/internal/services"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// CallbackPrefix префикс данных кнопок подтверждения привязки канала
+const CallbackPrefix = "channel_"
+
+const (
+	linkConfirmPrefix = CallbackPrefix + "link_"
+	linkCancelPrefix  = CallbackPrefix + "cancel_"
+)
+
+// requestLink сохраняет канал до подтверждения и отправляет владельцу
+// данные канала с кнопками «Привязать к моему аккаунту» и «Отмена»
+func (h *Handler) requestLink(c tele.Context, upd *tele.ChatMemberUpdate, channel models.Channel) error {
+	now := time.Now()
+	link := models.PendingChannelLink{
+		Channel:     channel,
+		BotID:       upd.NewChatMember.User.ID,
+		UpdateID:    c.Update().ID,
+		RequestedAt: now,
+		ExpiresAt:   now.Add(h.config.ChannelLinkTimeout),
+	}
+	if err := h.linkService.Request(link); err != nil {
+		h.logger.Error("Failed to save pending channel link:", err)
+		return nil
+	}
+
+	msg, err := c.Bot().Send(upd.Sender, linkText(channel, link.ExpiresAt), linkMarkup(channel.ChatID))
+	if err != nil {
+		// Без личного чата подтвердить привязку нельзя
+		h.logger.Warn(fmt.Sprintf("Failed to ask user %d to confirm channel %d link: %v", upd.Sender.ID, channel.ChatID, err))
+		h.linkService.Resolve(channel.ChatID)
+		return nil
+	}
+	if err := h.linkService.SetMessageID(channel.ChatID, msg.ID); err != nil {
+		h.logger.Error("Failed to save channel link message ID:", err)
+	}
+	return nil
+}
+
+// HandleCallback обрабатывает кнопки подтверждения привязки канала
+func (h *Handler) HandleCallback(c tele.Context) error {
+	data := strings.TrimSpace(c.Callback().Data)
+
+	var confirm bool
+	var rawID string
+	switch {
+	case strings.HasPrefix(data, linkConfirmPrefix):
+		confirm, rawID = true, strings.TrimPrefix(data, linkConfirmPrefix)
+	case strings.HasPrefix(data, linkCancelPrefix):
+		rawID = strings.TrimPrefix(data, linkCancelPrefix)
+	default:
+		return c.Respond(&tele.CallbackResponse{Text: "❌ Неизвестное действие"})
+	}
+
+	chatID, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil {
+		h.logger.Error("Invalid channel link callback data:", data)
+		return c.Respond(&tele.CallbackResponse{Text: "❌ Ошибка обработки запроса"})
+	}
+
+	// Кнопки отправляются только владельцу, но данные callback можно подделать
+	if pending, ok := h.linkService.Pending(chatID); ok && pending.Channel.OwnerID != c.Sender().ID {
+		return c.Respond(&tele.CallbackResponse{Text: "⛔ Подтвердить может только владелец канала"})
+	}
+
+	link, err := h.linkService.Resolve(chatID)
+	if errors.Is(err, services.ErrLinkNotFound) {
+		c.Edit("⌛ Запрос на подключение канала больше не действует.")
+		return c.Respond(&tele.CallbackResponse{Text: "Запрос уже обработан или истек"})
+	}
+
+	name := channelName(link.Channel.Title, link.Channel.ChatID)
+	if !confirm {
+		h.logger.Info(fmt.Sprintf("User %d cancelled channel %d link", c.Sender().ID, chatID))
+		c.Edit(fmt.Sprintf("✖️ Канал «%s» не подключен.", name))
+		return c.Respond()
+	}
+
+	if err := h.registerChannel(c.Bot(), link, c.Sender()); err != nil {
+		c.Edit(fmt.Sprintf("❌ Не удалось подключить канал «%s». Снимите бота с должности администратора и назначьте заново.", name))
+		return c.Respond()
+	}
+
+	text := fmt.Sprintf("✅ Канал «%s» привязывается к вашему аккаунту Tribute.", name)
+	if !h.outboxService.BackendAvailable() {
+		text = "⚠️ Сервис временно недоступен. Канал будет подключен автоматически, как только связь восстановится."
+	}
+	c.Edit(text)
+	return c.Respond()
+}
+
+// registerChannel отправляет подтвержденную регистрацию канала в API
+// и проверяет текущие права бота в канале
+func (h *Handler) registerChannel(api tele.API, link *models.PendingChannelLink, owner *tele.User) error {
+	channel := link.Channel
+	h.logger.Info("Enqueueing AddBotToChannel call...")
+	_, err := h.outboxService.EnqueueChannelRegistration(
+		channel.Registration(),
+		fmt.Sprintf("add-bot-%d-update-%d", channel.ChatID, link.UpdateID),
+	)
+	if err != nil {
+		h.logger.Error("Failed to enqueue channel registration:", err)
+		return err
+	}
+
+	// Права могли измениться, пока владелец подтверждал привязку
+	chat := &tele.Chat{
+		ID:       channel.ChatID,
+		Type:     tele.ChatType(channel.Type),
+		Title:    channel.Title,
+		Username: channel.Username,
+	}
+	member, err := api.ChatMemberOf(chat, &tele.User{ID: link.BotID})
+	if err != nil {
+		h.logger.Error("Failed to get bot rights in channel:", err)
+		return nil
+	}
+	if member.Role == tele.Administrator {
+		h.reportPermissions(api, chat, owner, auditPermissions(chat, member.Rights), false,
+			fmt.Sprintf("permissions-%d-update-%d", channel.ChatID, link.UpdateID))
+	}
+	return nil
+}
+
+// cancelLink отменяет ожидающее подтверждение, если бот удален из канала.
+// Возвращает true, если канал ожидал подтверждения и еще не был подключен.
+func (h *Handler) cancelLink(api tele.API, chatID int64) bool {
+	link, err := h.linkService.Resolve(chatID)
+	if err != nil {
+		return false
+	}
+	h.editLinkMessage(api, *link, fmt.Sprintf("✖️ Бот больше не администратор канала «%s», подключение отменено.",
+		channelName(link.Channel.Title, link.Channel.ChatID)))
+	return true
+}
+
+// expireLink сообщает владельцу, что время подтверждения истекло
+func (h *Handler) expireLink(api tele.API, link models.PendingChannelLink) {
+	h.logger.Info(fmt.Sprintf("Channel %d link confirmation expired", link.Channel.ChatID))
+	h.editLinkMessage(api, link, fmt.Sprintf("⌛ Время подтверждения истекло, канал «%s» не подключен.\n\n"+
+		"Чтобы подключить канал, снимите бота с должности администратора и назначьте заново.",
+		channelName(link.Channel.Title, link.Channel.ChatID)))
+}
+
+// editLinkMessage заменяет сообщение с кнопками подтверждения итоговым текстом
+func (h *Handler) editLinkMessage(api tele.API, link models.PendingChannelLink, text string) {
+	if link.MessageID == 0 {
+		return
+	}
+	msg := &tele.Message{ID: link.MessageID, Chat: &tele.Chat{ID: link.Channel.OwnerID}}
+	if _, err := api.Edit(msg, text); err != nil {
+		h.logger.Error("Failed to update channel link message:", err)
+	}
+}
+
+// linkText описывает канал, который владелец подтверждает
+func linkText(channel models.Channel, expiresAt time.Time) string {
+	username := "приватный канал"
+	if channel.Username != "" {
+		username = "@" + channel.Username
+	}
+	lines := []string{
+		"🔗 Подключение канала к Tribute",
+		"",
+		"Название: " + channelName(channel.Title, channel.ChatID),
+		"Ссылка: " + username,
+		fmt.Sprintf("ID: %d", channel.ChatID),
+	}
+	if channel.Description != "" {
+		lines = append(lines, "Описание: "+channel.Description)
+	}
+	lines = append(lines,
+		"",
+		"Привязать канал к вашему аккаунту Tribute?",
+		fmt.Sprintf("Запрос действует до %s.", expiresAt.Format("02.01.2006 15:04")),
+	)
+	return strings.Join(lines, "\n")
+}
+
+// linkMarkup создает кнопки подтверждения привязки канала
+func linkMarkup(chatID int64) *tele.ReplyMarkup {
+	markup := &tele.ReplyMarkup{}
+	markup.Inline(markup.Row(
+		markup.Data("🔗 Привязать к моему аккаунту", fmt.Sprintf("%s%d", linkConfirmPrefix, chatID)),
+		markup.Data("✖️ Отмена", fmt.Sprintf("%s%d", linkCancelPrefix, chatID)),
+	))
+	return markup
+}
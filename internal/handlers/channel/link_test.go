@@ -0,0 +1,127 @@
+package channel
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"tribute-chatbot/internal/fakebackend"
+	"tribute-chatbot/internal/models"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// confirmLink запрашивает подтверждение привязки канала и подтверждает его от имени владельца
+func (e *testEnv) confirmLink(t *testing.T) {
+	t.Helper()
+	err := e.links.Request(models.PendingChannelLink{
+		Channel:   models.Channel{ChatID: channelID, Type: string(tele.ChatChannel), Title: "News", OwnerID: userID},
+		BotID:     botUser.ID,
+		UpdateID:  1,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("request link: %v", err)
+	}
+
+	e.updateID++
+	c := e.bot.NewContext(tele.Update{
+		ID: e.updateID,
+		Callback: &tele.Callback{
+			ID:      "callback",
+			Sender:  &tele.User{ID: userID},
+			Data:    linkConfirmPrefix + "-1001",
+			Message: &tele.Message{ID: 1, Chat: &tele.Chat{ID: userID, Type: tele.ChatPrivate}},
+		},
+	})
+	if err := e.handler.HandleCallback(c); err != nil {
+		t.Fatalf("handle callback: %v", err)
+	}
+}
+
+// deliver запускает outbox и ждет выполнения условия done
+func (e *testEnv) deliver(t *testing.T, done func() bool) {
+	t.Helper()
+	e.handler.StartWorkers(e.bot)
+	e.outbox.Start()
+	t.Cleanup(e.outbox.Stop)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(e.outbox.Items()) > 0 || !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("outbox not delivered: %+v", e.outbox.Items())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// ownerNotified сообщает, что владелец получил сообщение с текстом text
+func (e *testEnv) ownerNotified(text string) func() bool {
+	return func() bool {
+		for _, call := range e.telegram.CallsTo("sendMessage") {
+			if call.Param("chat_id") == "42" && strings.Contains(call.Param("text"), text) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func TestRegistersChannelAfterAPIAccepts(t *testing.T) {
+	env := newTestEnv(t)
+
+	env.confirmLink(t)
+	if _, ok := env.channels.Get(channelID); ok {
+		t.Fatal("channel saved before API accepted registration")
+	}
+
+	env.deliver(t, func() bool {
+		_, ok := env.channels.Get(channelID)
+		return ok
+	})
+
+	channel, _ := env.channels.Get(channelID)
+	if channel.OwnerID != userID || channel.Title != "News" {
+		t.Fatalf("saved channel = %+v", channel)
+	}
+}
+
+func TestKeepsRejectedChannelUnregistered(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		setup func(env *testEnv)
+		text  string
+	}{
+		{
+			name: "validation error",
+			setup: func(env *testEnv) {
+				env.backend.Inject(fakebackend.Fault{
+					Path:    "/v1/add-bot",
+					Status:  http.StatusUnprocessableEntity,
+					Code:    "validation_error",
+					Message: "invalid channel",
+				})
+			},
+			text: "Не удалось подключить канал",
+		},
+		{
+			name: "already added",
+			setup: func(env *testEnv) {
+				env.backend.State().AddChannel(fakebackend.Channel{ChatID: channelID, Active: true})
+			},
+			text: "уже подключен",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			tc.setup(env)
+
+			env.confirmLink(t)
+			env.deliver(t, env.ownerNotified(tc.text))
+
+			if channel, ok := env.channels.Get(channelID); ok {
+				t.Fatalf("rejected channel saved: %+v", channel)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package channel
+
+import (
+	"fmt"
+	"tribute-chatbot/internal/models"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// HandleChannelUpdate отслеживает смену названия, username и аватарки
+// зарегистрированного канала по его постам и служебным сообщениям
+func (h *Handler) HandleChannelUpdate(c tele.Context) error {
+	msg := c.Message()
+	if msg == nil || msg.Chat == nil {
+		return nil
+	}
+
+	// Аватарка и описание не приходят в сообщениях, их нужно запросить заново
+	refresh := msg.NewGroupPhoto != nil || msg.GroupPhotoDeleted
+	h.syncChannel(c, msg.Chat, refresh)
+	return nil
+}
+
+// syncChannel сравнивает название и username чата с отправленными в API
+// и при расхождении отправляет обновленные данные канала
+func (h *Handler) syncChannel(c tele.Context, chat *tele.Chat, refresh bool) {
+	stored, ok := h.channelService.Get(chat.ID)
+	if !ok {
+		return
+	}
+	if !refresh && stored.Title == chat.Title && stored.Username == chat.Username {
+		return
+	}
+
+	channel, changed, err := h.channelService.Update(h.channelInfo(c.Bot(), chat, stored))
+	if err != nil {
+		h.logger.Error("Failed to save channel update:", err)
+	}
+	if !changed {
+		return
+	}
+
+	h.logger.Info(fmt.Sprintf("Channel %d changed: title '%s' -> '%s', username '%s' -> '%s'",
+		chat.ID, stored.Title, channel.Title, stored.Username, channel.Username))
+
+	_, err = h.outboxService.EnqueueChannelUpdate(
+		channel.Update(),
+		fmt.Sprintf("update-channel-%d-update-%d", chat.ID, c.Update().ID),
+	)
+	if err != nil {
+		h.logger.Error("Failed to enqueue channel update:", err)
+	}
+}
+
+// channelInfo собирает данные канала для API. Описание и аватарка есть только
+// в полном объекте чата; если его не удалось получить, они берутся из base.
+func (h *Handler) channelInfo(api tele.API, chat *tele.Chat, base models.Channel) models.Channel {
+	channel := base
+	channel.ChatID = chat.ID
+	channel.Type = string(chat.Type)
+	channel.Title = chat.Title
+	channel.Username = chat.Username
+
+	full, err := api.ChatByID(chat.ID)
+	if err != nil {
+		h.logger.Warn(fmt.Sprintf("Failed to get chat %d metadata: %v", chat.ID, err))
+		return channel
+	}
+
+	channel.Description = full.Description
+	channel.PhotoFileID = ""
+	channel.PhotoUniqueID = ""
+	if full.Photo != nil {
+		channel.PhotoFileID = full.Photo.BigFileID
+		channel.PhotoUniqueID = full.Photo.BigUniqueID
+	}
+	return channel
+}
@@ -0,0 +1,74 @@
+package channel
+
+import (
+	"fmt"
+	"strings"
+	"tribute-chatbot/internal/config"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// checkOwner находит владельца канала среди администраторов и проверяет,
+// что отправитель может подключить канал с учетом CHANNEL_OWNER_ROLE
+func (h *Handler) checkOwner(api tele.API, chat *tele.Chat, sender *tele.User) (owner *tele.User, allowed bool, err error) {
+	admins, err := api.AdminsOf(chat)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get channel administrators: %w", err)
+	}
+
+	for _, admin := range admins {
+		if admin.User == nil {
+			continue
+		}
+		if admin.Role == tele.Creator {
+			owner = admin.User
+		}
+		if admin.User.ID != sender.ID {
+			continue
+		}
+		switch admin.Role {
+		case tele.Creator:
+			allowed = true
+		case tele.Administrator:
+			// Администратор без права назначать администраторов не мог добавить бота сам
+			allowed = h.config.ChannelOwnerRole == config.ChannelOwnerRoleAdministrator && admin.CanPromoteMembers
+		}
+	}
+	return owner, allowed, nil
+}
+
+// refuseRegistration объясняет отправителю, что подключить канал может только владелец,
+// и предупреждает владельца о попытке подключить его канал к чужому аккаунту
+func (h *Handler) refuseRegistration(api tele.API, chat *tele.Chat, sender, owner *tele.User) {
+	h.logger.Warn(fmt.Sprintf("User %d is not allowed to register channel %d", sender.ID, chat.ID))
+
+	text := fmt.Sprintf("❌ Канал «%s» может подключить только его владелец. "+
+		"Попросите владельца назначить бота администратором.", channelName(chat.Title, chat.ID))
+	if _, err := api.Send(sender, text); err != nil {
+		h.logger.Error("Failed to notify user about refused channel registration:", err)
+	}
+
+	if owner == nil || owner.ID == sender.ID {
+		return
+	}
+	text = fmt.Sprintf("⚠️ %s назначил бота администратором канала «%s» и пытался подключить канал "+
+		"к своему аккаунту Tribute. Канал не подключен.\n\n"+
+		"Чтобы подключить канал к своему аккаунту, снимите бота с должности администратора и назначьте заново.",
+		userName(sender), channelName(chat.Title, chat.ID))
+	if _, err := api.Send(owner, text); err != nil {
+		// Владелец мог ни разу не запускать бота
+		h.logger.Warn(fmt.Sprintf("Failed to notify channel %d owner %d: %v", chat.ID, owner.ID, err))
+	}
+}
+
+// userName возвращает имя пользователя для сообщений владельцу канала
+func userName(user *tele.User) string {
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if user.Username != "" {
+		name = strings.TrimSpace(name + " @" + user.Username)
+	}
+	if name == "" {
+		return fmt.Sprintf("Пользователь %d", user.ID)
+	}
+	return fmt.Sprintf("%s (ID %d)", name, user.ID)
+}
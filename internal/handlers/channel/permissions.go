@@ -0,0 +1,94 @@
+package channel
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"tribute-chatbot/internal/models"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// permission право администратора, необходимое боту для одной из функций
+type permission struct {
+	ID          string // название права в Bot API
+	Name        string // название в настройках администратора Telegram
+	Feature     string // что перестанет работать без права
+	ChannelOnly bool   // право есть только у администраторов каналов
+	granted     func(tele.Rights) bool
+}
+
+// requiredPermissions права, без которых функции Tribute в канале не работают
+var requiredPermissions = []permission{
+	{
+		ID:      "can_invite_users",
+		Name:    "Пригласительные ссылки",
+		Feature: "доступ подписчиков по ссылкам и заявки на вступление",
+		granted: func(r tele.Rights) bool { return r.CanInviteUsers },
+	},
+	{
+		ID:      "can_restrict_members",
+		Name:    "Блокировка пользователей",
+		Feature: "удаление подписчиков с истекшей подпиской",
+		granted: func(r tele.Rights) bool { return r.CanRestrictMembers },
+	},
+	{
+		ID:          "can_post_messages",
+		Name:        "Публикация сообщений",
+		Feature:     "кнопки доната и подписки под постами",
+		ChannelOnly: true,
+		granted:     func(r tele.Rights) bool { return r.CanPostMessages },
+	},
+	{
+		ID:          "can_edit_messages",
+		Name:        "Редактирование сообщений",
+		Feature:     "кнопки доната и подписки под постами",
+		ChannelOnly: true,
+		granted:     func(r tele.Rights) bool { return r.CanEditMessages },
+	},
+}
+
+// auditPermissions возвращает права, которых не хватает боту в чате
+func auditPermissions(chat *tele.Chat, rights tele.Rights) []permission {
+	var missing []permission
+	for _, perm := range requiredPermissions {
+		if perm.ChannelOnly && chat.Type != tele.ChatChannel {
+			continue
+		}
+		if !perm.granted(rights) {
+			missing = append(missing, perm)
+		}
+	}
+	return missing
+}
+
+// permissionReport формирует результат проверки прав для API
+func permissionReport(userID int64, chat *tele.Chat, missing []permission) models.ChannelPermissionReport {
+	report := models.ChannelPermissionReport{
+		UserID:          userID,
+		ChatID:          chat.ID,
+		ChannelTitle:    chat.Title,
+		ChannelUsername: chat.Username,
+		CheckedAt:       time.Now(),
+	}
+	for _, perm := range missing {
+		report.Missing = append(report.Missing, perm.ID)
+	}
+	return report
+}
+
+// checklistText формирует список недостающих прав с инструкцией
+func checklistText(channelTitle string, missing []permission) string {
+	lines := []string{
+		fmt.Sprintf("⚠️ Боту не хватает прав в «%s», часть функций Tribute работать не будет:", channelTitle),
+		"",
+	}
+	for _, perm := range missing {
+		lines = append(lines, fmt.Sprintf("☐ %s — %s", perm.Name, perm.Feature))
+	}
+	lines = append(lines,
+		"",
+		"Как исправить: Настройки канала → Администраторы → выберите бота → включите отмеченные права → Сохранить.",
+	)
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,232 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"tribute-chatbot/internal/models"
+	"tribute-chatbot/internal/services"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// maxSummaryLines число расхождений, перечисляемых в сводке для администраторов
+const maxSummaryLines = 30
+
+// StartReconciliation дополняет сохраненные каналы подключенными в API и периодически
+// сверяет их с фактическим статусом бота, если задан CHANNEL_RECONCILE_INTERVAL
+func (h *Handler) StartReconciliation(api tele.API, botID int64) {
+	go h.backfill()
+
+	if h.config.ChannelReconcileInterval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(h.config.ChannelReconcileInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-h.stop:
+				return
+			case <-ticker.C:
+				h.reconcile(api, botID)
+			}
+		}
+	}()
+}
+
+// StopReconciliation останавливает периодическую сверку каналов
+func (h *Handler) StopReconciliation() {
+	select {
+	case <-h.stop:
+	default:
+		close(h.stop)
+	}
+}
+
+// reconcile получает список каналов из API, проверяет статус бота в каждом,
+// передает расхождения в API и отправляет сводку в админский чат, если есть
+// расхождения или каналы, которые не удалось проверить
+func (h *Handler) reconcile(api tele.API, botID int64) {
+	requestID := services.NewRequestID()
+	ctx := services.WithRequestID(context.Background(), requestID)
+
+	channels, err := h.backend.ListChannels(ctx)
+	if err != nil {
+		h.logger.Error("Failed to list channels for reconciliation:", err)
+		h.sendAdmin(api, fmt.Sprintf("⚠️ Сверка каналов не выполнена: не удалось получить список каналов из API (%v)", err))
+		return
+	}
+
+	h.backfillChannels(channels)
+
+	reconciliation := models.ChannelReconciliation{CheckedAt: time.Now()}
+	for _, channel := range channels {
+		status, err := botStatus(api, channel.ChatID, botID)
+		if err != nil {
+			h.logger.Warn(fmt.Sprintf("Failed to check bot status in channel %d: %v", channel.ChatID, err))
+			reconciliation.Failed++
+			continue
+		}
+		reconciliation.Checked++
+
+		if kind := discrepancyKind(channel.Active, status); kind != "" {
+			reconciliation.Discrepancies = append(reconciliation.Discrepancies, models.ChannelDiscrepancy{
+				ChatID:          channel.ChatID,
+				UserID:          channel.UserID,
+				ChannelTitle:    channel.Title,
+				ChannelUsername: channel.Username,
+				Kind:            kind,
+				BotStatus:       status,
+			})
+		}
+	}
+
+	h.logger.Info(fmt.Sprintf("Channel reconciliation: checked=%d, failed=%d, discrepancies=%d",
+		reconciliation.Checked, reconciliation.Failed, len(reconciliation.Discrepancies)))
+
+	// Сверка без расхождений и ошибок остается только в логе
+	if len(reconciliation.Discrepancies) == 0 && reconciliation.Failed == 0 {
+		return
+	}
+
+	if len(reconciliation.Discrepancies) > 0 {
+		_, err := h.outboxService.EnqueueReconciliation(reconciliation, "reconcile-"+requestID)
+		if err != nil {
+			h.logger.Error("Failed to enqueue channel reconciliation:", err)
+		}
+	}
+
+	h.sendAdmin(api, reconciliationText(reconciliation))
+}
+
+// backfill загружает список каналов из API при запуске, чтобы каналы, подключенные
+// до появления сохраненных данных каналов, получали кнопки под постами и обновления данных
+func (h *Handler) backfill() {
+	ctx := services.WithRequestID(context.Background(), services.NewRequestID())
+	channels, err := h.backend.ListChannels(ctx)
+	if err != nil {
+		h.logger.Warn(fmt.Sprintf("Failed to list channels for backfill: %v", err))
+		return
+	}
+	h.backfillChannels(channels)
+}
+
+// backfillChannels сохраняет активные в API каналы, о которых бот не знает,
+// и забывает отключенные в API
+func (h *Handler) backfillChannels(channels []models.ChannelSummary) {
+	added, removed := 0, 0
+	for _, channel := range channels {
+		_, known := h.channelService.Get(channel.ChatID)
+		switch {
+		case channel.Active && !known:
+			err := h.channelService.Register(models.Channel{
+				ChatID:   channel.ChatID,
+				Title:    channel.Title,
+				Username: channel.Username,
+				OwnerID:  channel.UserID,
+			})
+			if err != nil {
+				h.logger.Error("Failed to save backfilled channel:", err)
+				continue
+			}
+			added++
+		case !channel.Active && known:
+			if err := h.channelService.Remove(channel.ChatID); err != nil {
+				h.logger.Error("Failed to remove inactive channel:", err)
+				continue
+			}
+			removed++
+		}
+	}
+	if added > 0 || removed > 0 {
+		h.logger.Info(fmt.Sprintf("Channels backfilled from API: added=%d, removed=%d", added, removed))
+	}
+}
+
+// botStatus возвращает статус бота в канале по данным Bot API. Удаление бота
+// и отсутствие канала возвращаются статусом, а не ошибкой.
+func botStatus(api tele.API, chatID, botID int64) (string, error) {
+	member, err := api.ChatMemberOf(&tele.Chat{ID: chatID}, &tele.User{ID: botID})
+	switch {
+	case err == nil:
+		return string(member.Role), nil
+	case errors.Is(err, tele.ErrChatNotFound):
+		return "chat_not_found", nil
+	case errors.Is(err, tele.ErrKickedFromChannel),
+		errors.Is(err, tele.ErrKickedFromSuperGroup),
+		errors.Is(err, tele.ErrKickedFromGroup):
+		return string(tele.Kicked), nil
+	case errors.Is(err, tele.ErrNotChannelMember):
+		return string(tele.Left), nil
+	default:
+		return "", err
+	}
+}
+
+// discrepancyKind сравнивает статус канала в API со статусом бота в канале
+func discrepancyKind(active bool, status string) string {
+	isAdmin := tele.MemberStatus(status) == tele.Administrator
+	switch {
+	case active && isAdmin, !active && !isAdmin:
+		return ""
+	case !active:
+		return models.ChannelDiscrepancyInactive
+	case tele.MemberStatus(status) == tele.Member || tele.MemberStatus(status) == tele.Restricted:
+		return models.ChannelDiscrepancyBotNotAdmin
+	default:
+		return models.ChannelDiscrepancyBotRemoved
+	}
+}
+
+// reconciliationText формирует сводку сверки каналов для администраторов
+func reconciliationText(reconciliation models.ChannelReconciliation) string {
+	lines := []string{
+		"🔄 Сверка каналов с API",
+		"",
+		fmt.Sprintf("Проверено каналов: %d", reconciliation.Checked),
+		fmt.Sprintf("Расхождений: %d", len(reconciliation.Discrepancies)),
+	}
+	if reconciliation.Failed > 0 {
+		lines = append(lines, fmt.Sprintf("Не удалось проверить: %d", reconciliation.Failed))
+	}
+	if len(reconciliation.Discrepancies) == 0 {
+		return strings.Join(lines, "\n")
+	}
+
+	lines = append(lines, "")
+	for i, d := range reconciliation.Discrepancies {
+		if i == maxSummaryLines {
+			lines = append(lines, fmt.Sprintf("… и еще %d", len(reconciliation.Discrepancies)-maxSummaryLines))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("• «%s» (ID %d, владелец %d): %s",
+			channelName(d.ChannelTitle, d.ChatID), d.ChatID, d.UserID, discrepancyText(d)))
+	}
+	lines = append(lines, "", "Расхождения переданы в API.")
+	return strings.Join(lines, "\n")
+}
+
+// discrepancyText описывает расхождение для администраторов
+func discrepancyText(d models.ChannelDiscrepancy) string {
+	switch d.Kind {
+	case models.ChannelDiscrepancyBotRemoved:
+		return fmt.Sprintf("канал активен в API, но бот удален из канала (%s)", d.BotStatus)
+	case models.ChannelDiscrepancyBotNotAdmin:
+		return "канал активен в API, но бот больше не администратор"
+	case models.ChannelDiscrepancyInactive:
+		return "канал отключен в API, но бот остается администратором"
+	default:
+		return d.Kind
+	}
+}
+
+// sendAdmin отправляет сообщение в админский чат
+func (h *Handler) sendAdmin(api tele.API, text string) {
+	if _, err := api.Send(&tele.Chat{ID: h.config.TelegramAdminChatID}, text); err != nil {
+		h.logger.Error("Failed to send message to admin chat:", err)
+	}
+}
@@ -0,0 +1,40 @@
+package channel
+
+import (
+	"testing"
+	"tribute-chatbot/internal/fakebackend"
+)
+
+const adminChatID = -100
+
+func TestReconcileReportsOnlyProblems(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		channel fakebackend.Channel
+		reports int
+	}{
+		{"all clear", fakebackend.Channel{ChatID: channelID, Title: "News", Active: true}, 0},
+		{"bot admin in inactive channel", fakebackend.Channel{ChatID: channelID, Title: "News"}, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.cfg.TelegramAdminChatID = adminChatID
+			env.backend.State().AddChannel(tc.channel)
+
+			env.handler.reconcile(env.bot, botUser.ID)
+
+			reports := 0
+			for _, call := range env.telegram.CallsTo("sendMessage") {
+				if call.Param("chat_id") == "-100" {
+					reports++
+				}
+			}
+			if reports != tc.reports {
+				t.Fatalf("admin reports = %d, want %d", reports, tc.reports)
+			}
+			if requests := env.backend.RequestsTo("/v1/channels"); len(requests) != 1 {
+				t.Fatalf("channel list requests = %d, want 1", len(requests))
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"text/template"
+	"tribute-chatbot/internal/handlers/invite"
+	"tribute-chatbot/internal/logger"
+	"tribute-chatbot/internal/models"
+	"tribute-chatbot/internal/services"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+var (
+	// ErrUnknownEvent возвращается для событий неизвестного типа
+	ErrUnknownEvent = errors.New("unknown event type")
+	// ErrInvalidEvent возвращается, если данные события не разбираются или неполны
+	ErrInvalidEvent = errors.New("invalid event data")
+)
+
+// Шаблоны сообщений для событий бэкенда
+var templates = map[string]*template.Template{
+	models.EventSubscriptionStarted: template.Must(template.New(models.EventSubscriptionStarted).Parse(
+		`🎉 Новый подписчик{{if .SubscriberName}} {{.SubscriberName}}{{end}} в канале «{{.ChannelTitle}}»!`)),
+	models.EventSubscriptionEnded: template.Must(template.New(models.EventSubscriptionEnded).Parse(
+		`⌛ Ваша подписка на канал «{{.ChannelTitle}}» закончилась. Продлите ее, чтобы не потерять доступ.`)),
+	models.EventPaymentReceived: template.Must(template.New(models.EventPaymentReceived).Parse(
+		`💸 Выплата {{.Amount}} {{.Currency}} зачислена{{if .Description}}: {{.Description}}{{end}}`)),
+	models.EventVerificationChanged: template.Must(template.New(models.EventVerificationChanged).Parse(
+		`{{if .IsVerified}}✅ Ваша верификация подтверждена{{else}}❌ Ваша верификация отозвана{{end}}` +
+			"{{if .Reason}}\nПричина: {{.Reason}}{{end}}")),
+}
+
+// Handler отправляет пользователям сообщения о событиях бэкенда
+type Handler struct {
+	api           tele.API
+	eventLog      *services.EventLog
+	userService   *services.UserService
+	inviteHandler *invite.Handler
+	logger        logger.Logger
+}
+
+// inviteRequest ссылка в канал, которую нужно выдать подписчику по событию
+type inviteRequest struct {
+	chatID       int64
+	subscriberID int64
+	channelTitle string
+}
+
+// NewHandler создает обработчик событий бэкенда
+func NewHandler(api tele.API, eventLog *services.EventLog, userService *services.UserService, inviteHandler *invite.Handler) *Handler {
+	return &Handler{
+		api:           api,
+		eventLog:      eventLog,
+		userService:   userService,
+		inviteHandler: inviteHandler,
+		logger:        logger.New(),
+	}
+}
+
+// HandleEvent отправляет сообщение о событии получателю и выдает подписчику
+// ссылку в канал, если событие этого требует. Повторно доставленное событие
+// с тем же ID пропускается; если отправка не удалась, событие можно доставить снова.
+func (h *Handler) HandleEvent(event models.BackendEvent) (duplicate bool, err error) {
+	if event.ID == "" {
+		return false, fmt.Errorf("%w: missing event id", ErrInvalidEvent)
+	}
+	tmpl, known := templates[event.Type]
+	if !known && event.Type != models.EventInviteRequested {
+		return false, fmt.Errorf("%w: %s", ErrUnknownEvent, event.Type)
+	}
+	recipient, data, inv, err := decodeEvent(event)
+	if err != nil {
+		return false, err
+	}
+
+	// Бэк-офис изменил верификацию: кэшированный профиль устарел
+	if event.Type == models.EventVerificationChanged {
+		h.userService.Invalidate(recipient)
+	}
+
+	first, err := h.eventLog.Begin(event.ID)
+	if err != nil {
+		return false, err
+	}
+	if !first {
+		h.logger.Info(fmt.Sprintf("Skipping duplicate event: id=%s, type=%s", event.ID, event.Type))
+		return true, nil
+	}
+
+	if inv != nil {
+		err := h.inviteHandler.Issue(h.api, inv.chatID, inv.subscriberID, inv.channelTitle)
+		if err != nil && !isUnreachable(err) {
+			h.eventLog.Abort(event.ID)
+			return false, err
+		} else if err != nil {
+			h.logger.Warn(fmt.Sprintf("Invite link for subscriber %d not delivered: %v", inv.subscriberID, err))
+		}
+	}
+
+	if tmpl != nil {
+		var text bytes.Buffer
+		if err := tmpl.Execute(&text, data); err != nil {
+			h.eventLog.Abort(event.ID)
+			return false, fmt.Errorf("failed to render event message: %w", err)
+		}
+
+		if _, err := h.api.Send(&tele.User{ID: recipient}, text.String()); err != nil && !isUnreachable(err) {
+			h.eventLog.Abort(event.ID)
+			return false, fmt.Errorf("failed to send event message: %w", err)
+		} else if err != nil {
+			// Пользователь недоступен: повтор события не поможет
+			h.logger.Warn(fmt.Sprintf("Event recipient %d is unreachable: %v", recipient, err))
+		}
+	}
+
+	if err := h.eventLog.Complete(event.ID); err != nil {
+		h.logger.Error("Failed to persist event log:", err)
+	}
+	h.logger.Info(fmt.Sprintf("Event processed: id=%s, type=%s, recipient=%d", event.ID, event.Type, recipient))
+	return false, nil
+}
+
+// decodeEvent разбирает данные события, определяет получателя сообщения
+// и ссылку в канал, которую нужно выдать подписчику
+func decodeEvent(event models.BackendEvent) (int64, interface{}, *inviteRequest, error) {
+	var recipient int64
+	var data interface{}
+	var inv *inviteRequest
+
+	switch event.Type {
+	case models.EventSubscriptionStarted, models.EventSubscriptionEnded:
+		var subscription models.SubscriptionEventData
+		if err := json.Unmarshal(event.Data, &subscription); err != nil {
+			return 0, nil, nil, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+		}
+		// О новом подписчике узнает автор, об окончании подписки — подписчик
+		recipient = subscription.CreatorID
+		if event.Type == models.EventSubscriptionEnded {
+			recipient = subscription.SubscriberID
+		}
+		data = subscription
+		// Новый подписчик сразу получает ссылку в канал
+		if event.Type == models.EventSubscriptionStarted && subscription.ChannelID != 0 && subscription.SubscriberID != 0 {
+			inv = &inviteRequest{
+				chatID:       subscription.ChannelID,
+				subscriberID: subscription.SubscriberID,
+				channelTitle: subscription.ChannelTitle,
+			}
+		}
+
+	case models.EventInviteRequested:
+		var request models.InviteEventData
+		if err := json.Unmarshal(event.Data, &request); err != nil {
+			return 0, nil, nil, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+		}
+		if request.ChannelID == 0 {
+			return 0, nil, nil, fmt.Errorf("%w: missing channel_id", ErrInvalidEvent)
+		}
+		recipient = request.SubscriberID
+		data = request
+		inv = &inviteRequest{
+			chatID:       request.ChannelID,
+			subscriberID: request.SubscriberID,
+			channelTitle: request.ChannelTitle,
+		}
+
+	case models.EventPaymentReceived:
+		var payment models.PaymentEventData
+		if err := json.Unmarshal(event.Data, &payment); err != nil {
+			return 0, nil, nil, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+		}
+		recipient = payment.UserID
+		data = payment
+
+	case models.EventVerificationChanged:
+		var verification models.VerificationEventData
+		if err := json.Unmarshal(event.Data, &verification); err != nil {
+			return 0, nil, nil, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+		}
+		recipient = verification.UserID
+		data = verification
+	}
+
+	if recipient == 0 {
+		return 0, nil, nil, fmt.Errorf("%w: missing recipient", ErrInvalidEvent)
+	}
+	return recipient, data, inv, nil
+}
+
+// isUnreachable сообщает, что пользователь заблокировал бота или недоступен
+func isUnreachable(err error) bool {
+	return errors.Is(err, tele.ErrBlockedByUser) ||
+		errors.Is(err, tele.ErrNotStartedByUser) ||
+		errors.Is(err, tele.ErrUserIsDeactivated) ||
+		errors.Is(err, tele.ErrChatNotFound)
+}
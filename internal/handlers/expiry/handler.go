@@ -0,0 +1,171 @@
+package expiry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"tribute-chatbot/internal/config"
+	"tribute-chatbot/internal/logger"
+	"tribute-chatbot/internal/models"
+	"tribute-chatbot/internal/services"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// Handler удаляет из каналов подписчиков с истекшей подпиской:
+// сначала напоминает о продлении, а после льготного периода удаляет
+type Handler struct {
+	expiryService *services.ExpiryService
+	outboxService *services.OutboxService
+	backend       services.BackendClient
+	config        *config.Config
+	logger        logger.Logger
+	stop          chan struct{}
+}
+
+// NewHandler создает обработчик истекших подписок
+func NewHandler(
+	expiryService *services.ExpiryService,
+	outboxService *services.OutboxService,
+	backend services.BackendClient,
+	config *config.Config,
+) *Handler {
+	return &Handler{
+		expiryService: expiryService,
+		outboxService: outboxService,
+		backend:       backend,
+		config:        config,
+		logger:        logger.New(),
+		stop:          make(chan struct{}),
+	}
+}
+
+// Start периодически запрашивает истекшие подписки, если задан EXPIRY_CHECK_INTERVAL
+func (h *Handler) Start(api tele.API) {
+	if h.config.ExpiryCheckInterval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(h.config.ExpiryCheckInterval)
+		defer ticker.Stop()
+		for {
+			h.enforce(api)
+			select {
+			case <-h.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop останавливает проверку истекших подписок
+func (h *Handler) Stop() {
+	select {
+	case <-h.stop:
+	default:
+		close(h.stop)
+	}
+}
+
+// enforce предупреждает новых подписчиков с истекшей подпиской и удаляет тех,
+// чей льготный период закончился. Если API недоступно, удаления не выполняются:
+// без актуального списка нельзя узнать, не продлена ли подписка.
+func (h *Handler) enforce(api tele.API) {
+	ctx := services.WithRequestID(context.Background(), services.NewRequestID())
+	expired, err := h.backend.ListExpiredSubscriptions(ctx)
+	if err != nil {
+		h.logger.Error("Failed to list expired subscriptions:", err)
+		return
+	}
+
+	now := time.Now()
+	added, renewed, err := h.expiryService.Sync(expired, now, h.config.ExpiryGracePeriod)
+	if err != nil {
+		h.logger.Error("Failed to save expired subscriptions:", err)
+	}
+	for _, subscription := range renewed {
+		h.logger.Info(fmt.Sprintf("Subscription renewed during grace period: chat_id=%d, subscriber_id=%d",
+			subscription.ChatID, subscription.SubscriberID))
+	}
+	for _, subscription := range added {
+		h.remind(api, subscription)
+	}
+
+	for _, subscription := range h.expiryService.Due(now) {
+		h.remove(api, subscription)
+	}
+}
+
+// remind напоминает подписчику о продлении до удаления из канала
+func (h *Handler) remind(api tele.API, subscription models.ExpiredSubscription) {
+	text := fmt.Sprintf("⌛ Ваша подписка на %s закончилась. Продлите ее до %s, иначе доступ к каналу будет закрыт.",
+		channelName(subscription), subscription.RemoveAt.Format("02.01.2006 15:04"))
+
+	var opts []interface{}
+	if subscription.RenewURL != "" {
+		markup := &tele.ReplyMarkup{}
+		markup.Inline(markup.Row(markup.URL("🔄 Продлить подписку", subscription.RenewURL)))
+		opts = append(opts, markup)
+	}
+
+	if _, err := api.Send(&tele.User{ID: subscription.SubscriberID}, text, opts...); err != nil {
+		// Напоминание не обязательно для удаления, льготный период все равно действует
+		h.logger.Warn(fmt.Sprintf("Failed to remind subscriber %d about renewal: %v", subscription.SubscriberID, err))
+	}
+}
+
+// remove удаляет подписчика из канала блокировкой с немедленной разблокировкой,
+// чтобы после продления он мог вернуться, и сообщает об удалении в API
+func (h *Handler) remove(api tele.API, subscription models.ExpiredSubscription) {
+	chat := &tele.Chat{ID: subscription.ChatID}
+	user := &tele.User{ID: subscription.SubscriberID}
+
+	if err := api.Ban(chat, &tele.ChatMember{User: user}); err != nil {
+		if errors.Is(err, tele.ErrChatNotFound) {
+			// Канал удален или бот исключен: удалять подписчика неоткуда
+			h.logger.Warn(fmt.Sprintf("Channel %d not found, dropping expired subscriber %d", subscription.ChatID, subscription.SubscriberID))
+			h.complete(subscription, time.Now())
+			return
+		}
+		// Повторим при следующей проверке
+		h.logger.Error(fmt.Sprintf("Failed to remove subscriber %d from channel %d:", subscription.SubscriberID, subscription.ChatID), err)
+		return
+	}
+	if err := api.Unban(chat, user, true); err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to unban removed subscriber %d in channel %d:", subscription.SubscriberID, subscription.ChatID), err)
+	}
+
+	h.logger.Info(fmt.Sprintf("Expired subscriber removed: chat_id=%d, subscriber_id=%d", subscription.ChatID, subscription.SubscriberID))
+	subscription.RemovedAt = time.Now()
+	h.complete(subscription, subscription.RemovedAt)
+
+	_, err := h.outboxService.EnqueueSubscriptionRemoval(subscription,
+		fmt.Sprintf("remove-%d-%d-%d", subscription.ChatID, subscription.SubscriberID, subscription.ExpiredAt.Unix()))
+	if err != nil {
+		h.logger.Error("Failed to enqueue subscription removal:", err)
+	}
+
+	text := fmt.Sprintf("🔒 Доступ к %s закрыт, потому что подписка не продлена. "+
+		"После продления вы сможете вернуться в канал.", channelName(subscription))
+	if _, err := api.Send(user, text); err != nil {
+		h.logger.Warn(fmt.Sprintf("Failed to notify removed subscriber %d: %v", subscription.SubscriberID, err))
+	}
+}
+
+// complete отмечает подписчика удаленным
+func (h *Handler) complete(subscription models.ExpiredSubscription, removedAt time.Time) {
+	if err := h.expiryService.Complete(subscription.ChatID, subscription.SubscriberID, removedAt); err != nil {
+		h.logger.Error("Failed to save expired subscriptions:", err)
+	}
+}
+
+// channelName возвращает название канала для сообщений подписчику
+func channelName(subscription models.ExpiredSubscription) string {
+	if subscription.ChannelTitle != "" {
+		return fmt.Sprintf("канал «%s»", subscription.ChannelTitle)
+	}
+	return "канал"
+}
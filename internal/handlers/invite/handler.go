@@ -0,0 +1,152 @@
+package invite
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+	"tribute-chatbot/internal/config"
+	"tribute-chatbot/internal/logger"
+	"tribute-chatbot/internal/models"
+	"tribute-chatbot/internal/services"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// Handler выдает подписчикам персональные ссылки в каналы и отслеживает,
+// кто по какой ссылке вступил
+type Handler struct {
+	inviteService *services.InviteService
+	outboxService *services.OutboxService
+	config        *config.Config
+	logger        logger.Logger
+}
+
+// NewHandler создает обработчик пригласительных ссылок
+func NewHandler(inviteService *services.InviteService, outboxService *services.OutboxService, config *config.Config) *Handler {
+	return &Handler{
+		inviteService: inviteService,
+		outboxService: outboxService,
+		config:        config,
+		logger:        logger.New(),
+	}
+}
+
+// StartWorkers запускает отзыв неиспользованных ссылок по истечении срока
+func (h *Handler) StartWorkers(api tele.API) {
+	h.inviteService.Start(func(link models.InviteLink) {
+		h.revoke(api, link)
+	})
+}
+
+// Issue выдает подписчику ссылку в канал с ограничением в одного участника
+// и отправляет ее в личные сообщения. Повторный запрос, пока ссылка действует
+// и не использована, отправляет ту же ссылку.
+func (h *Handler) Issue(api tele.API, chatID, subscriberID int64, channelTitle string) error {
+	link, exists := h.inviteService.Active(chatID, subscriberID)
+	if !exists {
+		created, err := h.create(api, chatID, subscriberID)
+		if err != nil {
+			return err
+		}
+		link = created
+	}
+
+	if _, err := api.Send(&tele.User{ID: subscriberID}, inviteText(*link, channelTitle), tele.NoPreview); err != nil {
+		return fmt.Errorf("failed to send invite link: %w", err)
+	}
+	return nil
+}
+
+// create создает ссылку в Telegram, сохраняет ее и сообщает о выдаче в API
+func (h *Handler) create(api tele.API, chatID, subscriberID int64) (*models.InviteLink, error) {
+	now := time.Now()
+	expiresAt := now.Add(h.config.InviteLinkTTL)
+	name := fmt.Sprintf("sub-%d-%s", subscriberID, strconv.FormatInt(now.UnixNano(), 36))
+
+	created, err := api.CreateInviteLink(&tele.Chat{ID: chatID}, &tele.ChatInviteLink{
+		Name:           name,
+		ExpireUnixtime: expiresAt.Unix(),
+		MemberLimit:    1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invite link: %w", err)
+	}
+
+	link := models.InviteLink{
+		Name:         name,
+		Link:         created.InviteLink,
+		ChatID:       chatID,
+		SubscriberID: subscriberID,
+		CreatedAt:    now,
+		ExpiresAt:    expiresAt,
+	}
+	if err := h.inviteService.Add(link); err != nil {
+		// Несохраненную ссылку нельзя будет отозвать, поэтому отзываем сразу
+		api.RevokeInviteLink(&tele.Chat{ID: chatID}, link.Link)
+		return nil, err
+	}
+
+	h.logger.Info(fmt.Sprintf("Invite link issued: chat_id=%d, subscriber_id=%d, name=%s", chatID, subscriberID, name))
+	h.report(link)
+	return &link, nil
+}
+
+// HandleChatMember запоминает, по какой персональной ссылке вступил участник
+func (h *Handler) HandleChatMember(c tele.Context) error {
+	upd := c.ChatMember()
+	if upd == nil || upd.InviteLink == nil || upd.NewChatMember == nil || upd.NewChatMember.User == nil {
+		return nil
+	}
+	if upd.NewChatMember.Role != tele.Member {
+		return nil
+	}
+
+	userID := upd.NewChatMember.User.ID
+	link, err := h.inviteService.MarkUsed(upd.Chat.ID, upd.InviteLink.Name, upd.InviteLink.InviteLink, userID)
+	if errors.Is(err, services.ErrInviteNotFound) {
+		// Ссылка выдана не ботом
+		return nil
+	}
+	if err != nil {
+		h.logger.Error("Failed to record invite link use:", err)
+		return nil
+	}
+
+	if link.SubscriberID != userID {
+		h.logger.Warn(fmt.Sprintf("Invite link %s of subscriber %d used by user %d", link.Name, link.SubscriberID, userID))
+	}
+	h.logger.Info(fmt.Sprintf("User %d joined channel %d with invite link %s", userID, upd.Chat.ID, link.Name))
+	h.report(*link)
+	return nil
+}
+
+// revoke отзывает неиспользованную ссылку в Telegram и сообщает об этом в API
+func (h *Handler) revoke(api tele.API, link models.InviteLink) {
+	if _, err := api.RevokeInviteLink(&tele.Chat{ID: link.ChatID}, link.Link); err != nil {
+		// Срок действия ссылки в Telegram тоже истек, поэтому вступить по ней уже нельзя
+		h.logger.Warn(fmt.Sprintf("Failed to revoke invite link %s: %v", link.Name, err))
+	}
+	h.logger.Info(fmt.Sprintf("Unused invite link revoked: chat_id=%d, subscriber_id=%d, name=%s",
+		link.ChatID, link.SubscriberID, link.Name))
+	h.report(link)
+}
+
+// report передает текущий статус ссылки в API через outbox
+func (h *Handler) report(link models.InviteLink) {
+	_, err := h.outboxService.EnqueueInviteLink(link, fmt.Sprintf("invite-%s-%s", link.Name, link.Status()))
+	if err != nil {
+		h.logger.Error("Failed to enqueue invite link report:", err)
+	}
+}
+
+// inviteText формирует сообщение подписчику со ссылкой в канал
+func inviteText(link models.InviteLink, channelTitle string) string {
+	channel := "канал"
+	if channelTitle != "" {
+		channel = fmt.Sprintf("канал «%s»", channelTitle)
+	}
+	return fmt.Sprintf("🔑 Ваша персональная ссылка в %s:\n%s\n\n"+
+		"Ссылка одноразовая и действует до %s. Не передавайте ее другим.",
+		channel, link.Link, link.ExpiresAt.Format("02.01.2006 15:04"))
+}
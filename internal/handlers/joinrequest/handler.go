@@ -0,0 +1,114 @@
+package joinrequest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"tribute-chatbot/internal/logger"
+	"tribute-chatbot/internal/models"
+	"tribute-chatbot/internal/services"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// Handler решает заявки на вступление в подключенные каналы по данным API
+type Handler struct {
+	backend services.BackendClient
+	logger  logger.Logger
+}
+
+// NewHandler создает обработчик заявок на вступление
+func NewHandler(backend services.BackendClient) *Handler {
+	return &Handler{
+		backend: backend,
+		logger:  logger.New(),
+	}
+}
+
+// HandleChatJoinRequest одобряет заявку, если у пользователя есть активная подписка
+// или верификация — в зависимости от правила канала, — и отклоняет ее иначе.
+// Заявки в каналы, не подключенные в API, и заявки, которые не удалось проверить,
+// остаются на рассмотрении администраторов канала.
+func (h *Handler) HandleChatJoinRequest(c tele.Context) error {
+	req := c.ChatJoinRequest()
+	if req == nil || req.Chat == nil || req.Sender == nil {
+		return nil
+	}
+
+	ctx := services.WithUpdateID(context.Background(), c.Update().ID)
+	access, err := h.backend.GetChannelAccess(ctx, req.Chat.ID, req.Sender.ID)
+	if errors.Is(err, services.ErrChannelNotFound) {
+		h.logger.Info(fmt.Sprintf("Join request to unconnected channel %d from user %d left to admins", req.Chat.ID, req.Sender.ID))
+		return nil
+	}
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to check access to channel %d for user %d, join request left pending:", req.Chat.ID, req.Sender.ID), err)
+		h.notifyUnavailable(c.Bot(), req)
+		return nil
+	}
+
+	if access.Allowed() {
+		if err := c.Bot().ApproveJoinRequest(req.Chat, req.Sender); err != nil {
+			h.logger.Error(fmt.Sprintf("Failed to approve join request: chat_id=%d, user_id=%d:", req.Chat.ID, req.Sender.ID), err)
+			return nil
+		}
+		h.logger.Info(fmt.Sprintf("Join request approved: chat_id=%d, user_id=%d, policy=%s", req.Chat.ID, req.Sender.ID, access.Policy))
+		return nil
+	}
+
+	// Написать пользователю по заявке можно только до ее рассмотрения
+	h.notifyDeclined(c.Bot(), req, access)
+	if err := c.Bot().DeclineJoinRequest(req.Chat, req.Sender); err != nil {
+		h.logger.Error(fmt.Sprintf("Failed to decline join request: chat_id=%d, user_id=%d:", req.Chat.ID, req.Sender.ID), err)
+		return nil
+	}
+	h.logger.Info(fmt.Sprintf("Join request declined: chat_id=%d, user_id=%d, policy=%s", req.Chat.ID, req.Sender.ID, access.Policy))
+	return nil
+}
+
+// notifyDeclined объясняет пользователю, почему заявка отклонена, и присылает ссылку на подписку
+func (h *Handler) notifyDeclined(api tele.API, req *tele.ChatJoinRequest, access models.ChannelAccess) {
+	text := fmt.Sprintf("❌ Заявка на вступление в %s отклонена: нужна активная подписка. "+
+		"Оформите подписку и отправьте заявку снова.", channelName(req.Chat))
+	if access.Policy == models.ChannelJoinPolicyVerification {
+		text = fmt.Sprintf("❌ Заявка на вступление в %s отклонена: канал доступен только верифицированным "+
+			"пользователям. Пройдите верификацию командой /verificate и отправьте заявку снова.", channelName(req.Chat))
+	}
+
+	var opts []interface{}
+	if access.SubscribeURL != "" {
+		markup := &tele.ReplyMarkup{}
+		markup.Inline(markup.Row(markup.URL("💳 Оформить подписку", access.SubscribeURL)))
+		opts = append(opts, markup)
+	}
+
+	if _, err := api.Send(joinRecipient(req), text, opts...); err != nil {
+		h.logger.Warn(fmt.Sprintf("Failed to notify user %d about declined join request: %v", req.Sender.ID, err))
+	}
+}
+
+// notifyUnavailable сообщает пользователю, что заявку не удалось проверить из-за
+// недоступности сервиса и она осталась на рассмотрении
+func (h *Handler) notifyUnavailable(api tele.API, req *tele.ChatJoinRequest) {
+	text := fmt.Sprintf("⚠️ Сервис временно недоступен, поэтому заявка на вступление в %s пока не рассмотрена. "+
+		"Ее рассмотрят администраторы канала, или отправьте заявку снова позже.", channelName(req.Chat))
+	if _, err := api.Send(joinRecipient(req), text); err != nil {
+		h.logger.Warn(fmt.Sprintf("Failed to notify user %d about pending join request: %v", req.Sender.ID, err))
+	}
+}
+
+// joinRecipient возвращает чат для сообщения пользователю, отправившему заявку
+func joinRecipient(req *tele.ChatJoinRequest) *tele.User {
+	if req.UserChatID != 0 {
+		return &tele.User{ID: req.UserChatID}
+	}
+	return &tele.User{ID: req.Sender.ID}
+}
+
+// channelName возвращает название канала для сообщений пользователю
+func channelName(chat *tele.Chat) string {
+	if chat.Title != "" {
+		return fmt.Sprintf("канал «%s»", chat.Title)
+	}
+	return "канал"
+}
@@ -0,0 +1,205 @@
+package joinrequest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"tribute-chatbot/internal/config"
+	"tribute-chatbot/internal/fakebackend"
+	"tribute-chatbot/internal/faketelegram"
+	"tribute-chatbot/internal/services"
+	"tribute-chatbot/internal/signing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	channelID  = -1001
+	userID     = 42
+	userChatID = 4242
+	updateID   = 5
+	hmacSecret = "secret"
+)
+
+// testEnv обработчик заявок, подключенный к фейковым API и Telegram
+type testEnv struct {
+	handler  *Handler
+	cfg      *config.Config
+	backend  *fakebackend.Server
+	telegram *faketelegram.Server
+	bot      *tele.Bot
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	backend := fakebackend.New(fakebackend.Options{HMACSecret: hmacSecret})
+	backendServer := httptest.NewServer(backend)
+	t.Cleanup(backendServer.Close)
+	telegram := faketelegram.New()
+	telegramServer := httptest.NewServer(telegram)
+	t.Cleanup(telegramServer.Close)
+
+	cfg := &config.Config{
+		APIBaseURL:            backendServer.URL,
+		BackendTimeout:        time.Second,
+		BackendMaxRetries:     1,
+		BackendRetryBaseDelay: time.Millisecond,
+		BackendRetryMaxDelay:  time.Millisecond,
+		BackendAuthScheme:     config.BackendAuthHMAC,
+		BackendHMACSecret:     hmacSecret,
+	}
+	api, err := services.NewAPIService(cfg)
+	if err != nil {
+		t.Fatalf("api service: %v", err)
+	}
+	bot, err := faketelegram.NewBot(telegramServer.URL)
+	if err != nil {
+		t.Fatalf("bot: %v", err)
+	}
+
+	return &testEnv{
+		handler:  NewHandler(api),
+		cfg:      cfg,
+		backend:  backend,
+		telegram: telegram,
+		bot:      bot,
+	}
+}
+
+// request отправляет обработчику заявку пользователя на вступление в канал
+func (e *testEnv) request(t *testing.T) {
+	t.Helper()
+	c := e.bot.NewContext(tele.Update{
+		ID: updateID,
+		ChatJoinRequest: &tele.ChatJoinRequest{
+			Chat:       &tele.Chat{ID: channelID, Title: "News", Type: tele.ChatChannel},
+			Sender:     &tele.User{ID: userID},
+			UserChatID: userChatID,
+		},
+	})
+	if err := e.handler.HandleChatJoinRequest(c); err != nil {
+		t.Fatalf("handle join request: %v", err)
+	}
+}
+
+// methods возвращает вызванные методы Bot API по порядку
+func (e *testEnv) methods() []string {
+	var methods []string
+	for _, call := range e.telegram.Calls() {
+		methods = append(methods, call.Method)
+	}
+	return methods
+}
+
+func assertMethods(t *testing.T, env *testEnv, want ...string) {
+	t.Helper()
+	if got := env.methods(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("telegram calls = %v, want %v", got, want)
+	}
+}
+
+func TestApprovesSubscriber(t *testing.T) {
+	env := newTestEnv(t)
+	env.backend.State().AddChannel(fakebackend.Channel{ChatID: channelID, Active: true})
+	env.backend.State().AddSubscription(channelID, userID)
+
+	env.request(t)
+
+	assertMethods(t, env, "approveChatJoinRequest")
+	call := env.telegram.CallsTo("approveChatJoinRequest")[0]
+	if call.Param("chat_id") != "-1001" || call.Param("user_id") != "42" {
+		t.Errorf("approve params = %v", call.Params)
+	}
+
+	requests := env.backend.RequestsTo("/v1/channel-access")
+	if len(requests) != 1 {
+		t.Fatalf("access requests = %d, want 1", len(requests))
+	}
+	header := requests[0].Header
+	if got := header.Get("Idempotency-Key"); got != "tg-update-5-v1-channel-access" {
+		t.Errorf("Idempotency-Key = %q", got)
+	}
+	if header.Get("X-Request-ID") == "" || header.Get(signing.HeaderSignature) == "" {
+		t.Errorf("request headers = %v", header)
+	}
+}
+
+func TestApprovesVerifiedUser(t *testing.T) {
+	env := newTestEnv(t)
+	env.backend.State().AddChannel(fakebackend.Channel{ChatID: channelID, Active: true, JoinPolicy: "verification"})
+	api, err := services.NewAPIService(env.cfg)
+	if err != nil {
+		t.Fatalf("api service: %v", err)
+	}
+	if err := api.UpdateUserVerification(context.Background(), userID, true, nil); err != nil {
+		t.Fatalf("verify user: %v", err)
+	}
+
+	env.request(t)
+
+	assertMethods(t, env, "approveChatJoinRequest")
+}
+
+func TestDeclinesWithoutSubscription(t *testing.T) {
+	env := newTestEnv(t)
+	env.backend.State().AddChannel(fakebackend.Channel{ChatID: channelID, Active: true})
+
+	env.request(t)
+
+	// Сообщение отправляется до отклонения: после него user_chat_id недействителен
+	assertMethods(t, env, "sendMessage", "declineChatJoinRequest")
+	message := env.telegram.CallsTo("sendMessage")[0]
+	if message.Param("chat_id") != "4242" {
+		t.Errorf("message chat_id = %s, want user chat 4242", message.Param("chat_id"))
+	}
+	if !strings.Contains(message.Param("text"), "нужна активная подписка") {
+		t.Errorf("message text = %q", message.Param("text"))
+	}
+	if !strings.Contains(message.Param("reply_markup"), "startapp=channel-1001") {
+		t.Errorf("message markup = %s, want subscribe link", message.Param("reply_markup"))
+	}
+}
+
+func TestLeavesUnconnectedChannelToAdmins(t *testing.T) {
+	env := newTestEnv(t)
+
+	env.request(t)
+
+	assertMethods(t, env)
+	if requests := env.backend.RequestsTo("/v1/channel-access"); len(requests) != 1 {
+		t.Fatalf("access requests = %d, want 1", len(requests))
+	}
+}
+
+func TestKeepsRequestPendingWhenAPIUnavailable(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		fault fakebackend.Fault
+	}{
+		{"server error", fakebackend.Fault{Path: "/v1/channel-access", Status: http.StatusServiceUnavailable}},
+		{"timeout", fakebackend.Fault{Path: "/v1/channel-access", Latency: time.Second}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.cfg.BackendTimeout = 20 * time.Millisecond
+			env.backend.State().AddChannel(fakebackend.Channel{ChatID: channelID, Active: true})
+			env.backend.State().AddSubscription(channelID, userID)
+			env.backend.Inject(tc.fault)
+
+			env.request(t)
+
+			// Заявка не одобряется и не отклоняется, пользователь узнает о задержке
+			assertMethods(t, env, "sendMessage")
+			if text := env.telegram.CallsTo("sendMessage")[0].Param("text"); !strings.Contains(text, "временно недоступен") {
+				t.Errorf("message text = %q", text)
+			}
+			if requests := env.backend.RequestsTo("/v1/channel-access"); len(requests) != env.cfg.BackendMaxRetries+1 {
+				t.Fatalf("access requests = %d, want %d", len(requests), env.cfg.BackendMaxRetries+1)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package verification
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"tribute-chatbot/internal/config"
+	"tribute-chatbot/internal/models"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// finalizeAdminMessages фиксирует решение в сообщениях заявки в админском чате
+// согласно настроенному режиму: редактирование или удаление
+func (h *Handler) finalizeAdminMessages(api tele.API, decision models.PendingDecision) {
+	if decision.ControlMessageID == 0 {
+		h.logger.Error("Control message is unknown, cannot finalize admin messages")
+		return
+	}
+
+	state := decision.State
+	control := controlMessage(decision)
+	var passport *tele.Message
+	if state != nil && state.PassportMessageID > 0 {
+		passport = &tele.Message{ID: state.PassportMessageID, Chat: control.Chat}
+	}
+
+	caption := decision.ControlCaption + "\n\n" + outcomeText(decision.ReviewerName, decision.IsVerified, decision.DecidedAt)
+
+	if h.config.AdminMessageMode == config.AdminMessageModeDelete {
+		h.deleteAdminMessages(api, state, control, passport, caption)
+		return
+	}
+
+	h.editAdminMessage(api, control, selfieFileID(state), caption, "selfie")
+	if passport != nil {
+		h.editAdminMessage(api, passport, state.PassportID, "📄 Фотография паспорта", "passport")
+	}
+}
+
+// deleteAdminMessages удаляет сообщения заявки. Если Telegram уже не позволяет
+// удалить сообщение (старше 48 часов), оно редактируется вместо удаления.
+func (h *Handler) deleteAdminMessages(api tele.API, state *models.VerificationState, control, passport *tele.Message, caption string) {
+	if err := api.Delete(control); err != nil {
+		h.logger.Error("Failed to delete selfie message:", err)
+		if errors.Is(err, tele.ErrNoRightsToDelete) {
+			h.editAdminMessage(api, control, selfieFileID(state), caption, "selfie")
+		}
+	} else {
+		h.logger.Info(fmt.Sprintf("Successfully deleted selfie message ID: %d", control.ID))
+	}
+
+	if passport == nil {
+		// Fallback: состояние потеряно, удаляем предыдущее сообщение
+		if control.ID <= 1 {
+			return
+		}
+		passport = &tele.Message{ID: control.ID - 1, Chat: control.Chat}
+	}
+
+	if err := api.Delete(passport); err != nil {
+		h.logger.Error("Failed to delete passport message:", err)
+		if errors.Is(err, tele.ErrNoRightsToDelete) && state != nil {
+			h.editAdminMessage(api, passport, state.PassportID, "📄 Фотография паспорта", "passport")
+		}
+	} else {
+		h.logger.Info(fmt.Sprintf("Successfully deleted passport message ID: %d", passport.ID))
+	}
+}
+
+// editAdminMessage заменяет фотографию заглушкой или скрывает ее под спойлером,
+// обновляет подпись и убирает кнопки
+func (h *Handler) editAdminMessage(api tele.API, msg *tele.Message, fileID, caption, kind string) {
+	photo := &tele.Photo{Caption: caption}
+	switch {
+	case h.config.AdminPlaceholderPhoto != "":
+		photo.File = tele.FromDisk(h.config.AdminPlaceholderPhoto)
+	case fileID != "":
+		photo.File = tele.File{FileID: fileID}
+		photo.HasSpoiler = true
+	}
+
+	var err error
+	if photo.File.InCloud() || photo.File.OnDisk() {
+		_, err = api.EditMedia(msg, photo)
+	} else {
+		// Идентификатор фотографии неизвестен, меняем только подпись
+		_, err = api.EditCaption(msg, caption)
+	}
+
+	if err != nil && !errors.Is(err, tele.ErrMessageNotModified) {
+		h.logger.Error(fmt.Sprintf("Failed to edit %s message:", kind), err)
+		return
+	}
+	h.logger.Info(fmt.Sprintf("Successfully edited %s message ID: %d", kind, msg.ID))
+}
+
+// selfieFileID возвращает идентификатор селфи, если состояние известно
+func selfieFileID(state *models.VerificationState) string {
+	if state == nil {
+		return ""
+	}
+	return state.SelfieID
+}
+
+// selfieCaption формирует подпись к селфи в заявке
+func selfieCaption(userID int64) string {
+	return fmt.Sprintf("🔐 Заявка на верификацию\n👤 Пользователь: %d\n📸 Селфи", userID)
+}
+
+// adminCaption формирует подпись к заявке с данными MRZ и результатами проверок
+func (h *Handler) adminCaption(state *models.VerificationState) string {
+	caption := selfieCaption(state.UserID)
+	if state.Identity != nil {
+		caption += "\n\n" + identityCaption(state.Identity)
+	}
+	if state.Policy != nil {
+		caption += "\n\n" + policyCaption(state.Policy)
+	}
+	switch {
+	case state.Screening != nil:
+		caption += "\n\n" + screeningCaption(state.Screening)
+	case state.Identity == nil && (h.config.MRZMode != config.MRZModeOff || h.screeningService.Enabled()):
+		caption += "\n\n⚠️ MRZ не введен: правила возраста и срока действия документа " +
+			"и проверка по списку не применялись"
+	}
+	return caption
+}
+
+// outcomeText формирует строку с решением, проверяющим и временем
+func outcomeText(reviewer string, isVerified bool, at time.Time) string {
+	status := "✅ Подтверждено"
+	if !isVerified {
+		status = "❌ Отклонено"
+	}
+	return fmt.Sprintf("%s %s в %s", status, reviewer, at.Format("15:04"))
+}
+
+// reviewerName возвращает имя проверяющего для подписи
+func reviewerName(reviewer *tele.User) string {
+	switch {
+	case reviewer == nil:
+		return "администратором"
+	case reviewer.Username != "":
+		return "@" + reviewer.Username
+	case reviewer.FirstName != "":
+		return reviewer.FirstName
+	default:
+		return fmt.Sprintf("id%d", reviewer.ID)
+	}
+}
@@ -0,0 +1,226 @@
+package verification
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"tribute-chatbot/internal/models"
+	"tribute-chatbot/internal/services"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// StartWorkers запускает применение отложенных решений, включая сохраненные
+// до перезапуска, и обработку результатов их доставки в API.
+// Вызывается до запуска outbox, чтобы не пропустить результаты.
+func (h *Handler) StartWorkers(api tele.API) {
+	h.outboxService.OnResult(models.OutboxKindVerification, func(item models.OutboxItem, err error) {
+		h.handleDeliveryResult(api, item, err)
+	})
+	h.decisionService.Start(func(decision models.PendingDecision) {
+		if err := h.applyDecision(decision); err != nil {
+			h.restoreControlMessage(api, decision, "⚠️ Не удалось применить решение, попробуйте еще раз")
+		}
+	})
+}
+
+// deferDecision откладывает решение на время окна отмены
+// и показывает в заявке кнопку отмены
+func (h *Handler) deferDecision(c tele.Context, decision models.PendingDecision) error {
+	decision.ExecuteAt = decision.DecidedAt.Add(h.config.DecisionUndoWindow)
+
+	if err := h.decisionService.Schedule(decision); err != nil {
+		h.logger.Error("Failed to schedule verification decision:", err)
+		h.restoreReview(decision)
+		return c.Respond(&tele.CallbackResponse{Text: "❌ Ошибка при обновлении статуса верификации"})
+	}
+
+	h.logger.Info(fmt.Sprintf("Verification decision deferred: user_id=%d, verified=%t, execute_at=%s",
+		decision.UserID, decision.IsVerified, decision.ExecuteAt.Format("15:04:05")))
+
+	caption := fmt.Sprintf("%s\n\n⏳ %s\nБудет применено в %s",
+		decision.ControlCaption,
+		outcomeText(decision.ReviewerName, decision.IsVerified, decision.DecidedAt),
+		decision.ExecuteAt.Format("15:04:05"))
+	if _, err := c.Bot().EditCaption(controlMessage(decision), caption, undoMarkup(decision.UserID)); err != nil {
+		h.logger.Error("Failed to show undo button:", err)
+	}
+
+	return c.Respond(&tele.CallbackResponse{
+		Text: fmt.Sprintf("⏳ Решение будет применено через %s", h.config.DecisionUndoWindow),
+	})
+}
+
+// handleUndoCallback отменяет отложенное решение и возвращает заявку в ожидание
+func (h *Handler) handleUndoCallback(c tele.Context, data string) error {
+	// Парсим данные: verify_undo_<user_id>
+	parts := strings.Split(data, "_")
+	if len(parts) != 3 {
+		h.logger.Error("Invalid undo callback data format:", data)
+		return c.Respond(&tele.CallbackResponse{Text: "❌ Ошибка обработки запроса"})
+	}
+
+	userID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		h.logger.Error("Failed to parse user ID:", parts[2], err)
+		return c.Respond(&tele.CallbackResponse{Text: "❌ Ошибка обработки запроса"})
+	}
+
+	decision, err := h.decisionService.Cancel(userID)
+	if errors.Is(err, services.ErrDecisionNotFound) {
+		return c.Respond(&tele.CallbackResponse{Text: "⌛ Решение уже применено"})
+	}
+	if err != nil {
+		h.logger.Error("Failed to cancel verification decision:", err)
+		return c.Respond(&tele.CallbackResponse{Text: "❌ Ошибка обработки запроса"})
+	}
+
+	h.restoreReview(*decision)
+	h.restoreControlMessage(c.Bot(), *decision, "↩️ Решение отменено "+reviewerName(c.Callback().Sender))
+
+	h.logger.Info(fmt.Sprintf("Verification decision undone: user_id=%d", userID))
+
+	return c.Respond(&tele.CallbackResponse{Text: "↩️ Решение отменено"})
+}
+
+// applyDecision сохраняет решение в outbox для доставки в API.
+// Заявка фиксируется в админском чате после подтверждения доставки.
+func (h *Handler) applyDecision(decision models.PendingDecision) error {
+	item, err := h.outboxService.EnqueueVerification(decision, decisionIdempotencyKey(decision))
+	if err != nil {
+		h.logger.Error("Failed to enqueue verification decision:", err)
+		h.restoreReview(decision)
+		return err
+	}
+
+	h.logger.WithField("idempotency_key", item.IdempotencyKey).Info(fmt.Sprintf(
+		"Verification decision enqueued: user_id=%d, verified=%t, outbox_id=%s",
+		decision.UserID, decision.IsVerified, item.ID))
+	return nil
+}
+
+// handleDeliveryResult завершает заявку после доставки решения в API
+// или возвращает ее в ожидание, если решение отклонено или удалено из outbox
+func (h *Handler) handleDeliveryResult(api tele.API, item models.OutboxItem, deliveryErr error) {
+	var decision models.PendingDecision
+	if err := json.Unmarshal(item.Payload, &decision); err != nil {
+		h.logger.Error("Failed to decode verification decision from outbox:", err)
+		return
+	}
+
+	switch {
+	case errors.Is(deliveryErr, services.ErrOutboxItemDiscarded):
+		h.restoreReview(decision)
+		h.restoreControlMessage(api, decision, "⚠️ Отправка решения отменена, примите решение заново")
+		return
+	case deliveryErr != nil:
+		note := "⚠️ API отклонило решение, попробуйте еще раз"
+		switch {
+		case errors.Is(deliveryErr, services.ErrUserNotFound):
+			note = "⚠️ API не нашло пользователя, решение не применено"
+		case errors.Is(deliveryErr, services.ErrValidation):
+			if details := services.ValidationDetails(deliveryErr); len(details) > 0 {
+				note = "⚠️ API отклонило данные заявки:\n" + strings.Join(details, "\n")
+			}
+		}
+		h.restoreReview(decision)
+		h.restoreControlMessage(api, decision, note)
+		return
+	}
+
+	h.completeDecision(api, decision)
+}
+
+// completeDecision фиксирует принятое API решение в админском чате
+// и уведомляет пользователя
+func (h *Handler) completeDecision(api tele.API, decision models.PendingDecision) {
+	userID := decision.UserID
+
+	// Статус в API изменился, кэшированный профиль устарел
+	h.userService.Invalidate(userID)
+
+	// Фиксируем решение в сообщениях админского чата
+	h.finalizeAdminMessages(api, decision)
+	if decision.State != nil {
+		h.verificationService.FinishReview(userID)
+	}
+
+	// Отправляем уведомление пользователю
+	userChat := &tele.Chat{ID: userID}
+	statusText := "✅ Верификация подтверждена!"
+	if !decision.IsVerified {
+		statusText = "❌ Верификация отклонена"
+	}
+
+	_, err := api.Send(userChat, statusText)
+	if err != nil {
+		h.logger.Error("Failed to send notification to user:", err)
+	}
+
+	h.logger.Info(fmt.Sprintf("Verification processed successfully: user_id=%d, verified=%t", userID, decision.IsVerified))
+}
+
+// restoreReview возвращает заявку в ожидание решения. Если состояние
+// было потеряно при перезапуске, оно восстанавливается из снимка решения.
+func (h *Handler) restoreReview(decision models.PendingDecision) {
+	err := h.verificationService.CancelReview(decision.UserID)
+	if errors.Is(err, services.ErrStateNotFound) && decision.State != nil {
+		state := decision.State.Clone()
+		state.Step = models.VerificationStepCompleted
+		h.verificationService.SetState(decision.UserID, state)
+		return
+	}
+	if err != nil && !errors.Is(err, services.ErrStateNotFound) {
+		h.logger.Error("Failed to cancel verification review:", err)
+	}
+}
+
+// restoreControlMessage возвращает в заявку кнопки решения с пояснением
+func (h *Handler) restoreControlMessage(api tele.API, decision models.PendingDecision, note string) {
+	caption := decision.ControlCaption + "\n\n" + note
+	markup := decisionMarkup(decision.UserID)
+	if decision.State != nil {
+		markup = reviewMarkup(decision.State)
+	}
+	if _, err := api.EditCaption(controlMessage(decision), caption, markup); err != nil {
+		h.logger.Error("Failed to restore verification buttons:", err)
+	}
+}
+
+// decisionIdempotencyKey определяет ключ идемпотентности решения по заявке:
+// повторное нажатие или повторная отправка того же решения по той же заявке
+// дают тот же ключ
+func decisionIdempotencyKey(decision models.PendingDecision) string {
+	outcome := "rejected"
+	if decision.IsVerified {
+		outcome = "approved"
+	}
+	if decision.ControlMessageID == 0 {
+		return fmt.Sprintf("verification-%d-%d-%s", decision.UserID, decision.DecidedAt.UnixNano(), outcome)
+	}
+	return fmt.Sprintf("verification-%d-%d-%d-%s", decision.UserID, decision.ChatID, decision.ControlMessageID, outcome)
+}
+
+// controlMessage возвращает сообщение заявки с кнопками
+func controlMessage(decision models.PendingDecision) *tele.Message {
+	return &tele.Message{ID: decision.ControlMessageID, Chat: &tele.Chat{ID: decision.ChatID}}
+}
+
+// decisionMarkup создает кнопки решения по заявке
+func decisionMarkup(userID int64) *tele.ReplyMarkup {
+	markup := &tele.ReplyMarkup{}
+	approveBtn := markup.Data("✅ Подтвердить", fmt.Sprintf("verify_user_%d_true", userID))
+	rejectBtn := markup.Data("❌ Отозвать", fmt.Sprintf("verify_user_%d_false", userID))
+	markup.Inline(markup.Row(approveBtn, rejectBtn))
+	return markup
+}
+
+// undoMarkup создает кнопку отмены решения
+func undoMarkup(userID int64) *tele.ReplyMarkup {
+	markup := &tele.ReplyMarkup{}
+	undoBtn := markup.Data("↩️ Отменить", fmt.Sprintf("verify_undo_%d", userID))
+	markup.Inline(markup.Row(undoBtn))
+	return markup
+}
@@ -1,9 +1,12 @@
 package verification
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 	"tribute-chatbot/internal/config"
 	"tribute-chatbot/internal/logger"
 	"tribute-chatbot/internal/models"
@@ -15,7 +18,11 @@ import (
 // Handler обработчик верификации
 type Handler struct {
 	verificationService *services.VerificationService
-	apiService          *services.APIService
+	decisionService     *services.DecisionService
+	policyService       *services.PolicyService
+	screeningService    *services.ScreeningService
+	outboxService       *services.OutboxService
+	userService         *services.UserService
 	config              *config.Config
 	logger              logger.Logger
 }
@@ -23,12 +30,20 @@ type Handler struct {
 // NewHandler создает новый обработчик верификации
 func NewHandler(
 	verificationService *services.VerificationService,
-	apiService *services.APIService,
+	decisionService *services.DecisionService,
+	policyService *services.PolicyService,
+	screeningService *services.ScreeningService,
+	outboxService *services.OutboxService,
+	userService *services.UserService,
 	config *config.Config,
 ) *Handler {
 	return &Handler{
 		verificationService: verificationService,
-		apiService:          apiService,
+		decisionService:     decisionService,
+		policyService:       policyService,
+		screeningService:    screeningService,
+		outboxService:       outboxService,
+		userService:         userService,
 		config:              config,
 		logger:              logger.New(),
 	}
@@ -38,6 +53,15 @@ func NewHandler(
 func (h *Handler) HandleStartVerification(c tele.Context) error {
 	userID := c.Sender().ID
 
+	// Повторная верификация не нужна. Если API недоступно, процесс не блокируем.
+	profile, err := h.userService.Get(services.WithUpdateID(context.Background(), c.Update().ID), userID)
+	switch {
+	case err == nil && profile.IsVerified:
+		return c.Send("✅ Вы уже прошли верификацию, повторно отправлять документы не нужно.")
+	case err != nil && !errors.Is(err, services.ErrUserNotFound):
+		h.logger.Warn(fmt.Sprintf("Failed to get user profile %d, continuing verification: %v", userID, err))
+	}
+
 	// Инициализируем состояние верификации
 	h.verificationService.InitializeState(userID)
 
@@ -47,11 +71,6 @@ func (h *Handler) HandleStartVerification(c tele.Context) error {
 // HandlePhoto обрабатывает фотографии для верификации
 func (h *Handler) HandlePhoto(c tele.Context) error {
 	userID := c.Sender().ID
-	state := h.verificationService.GetState(userID)
-
-	if state == nil {
-		return c.Send("❌ Сначала используйте команду /verificate для начала процесса верификации.")
-	}
 
 	photo := c.Message().Photo
 	if photo == nil {
@@ -60,13 +79,26 @@ func (h *Handler) HandlePhoto(c tele.Context) error {
 
 	fileID := photo.FileID
 
+	state, err := h.verificationService.SubmitPhoto(userID, fileID)
+	if errors.Is(err, services.ErrStateNotFound) {
+		return c.Send("❌ Сначала используйте команду /verificate для начала процесса верификации.")
+	}
+	if h.isWaitingMRZ(userID, err) {
+		return c.Send(h.mrzPrompt())
+	}
+	if err != nil {
+		h.logger.Error("Failed to update verification state:", err)
+		return c.Send("❌ Неожиданное состояние. Используйте /verificate для начала заново.")
+	}
+
 	switch state.Step {
-	case models.VerificationStepWaitingSelfie:
-		h.verificationService.UpdateSelfie(userID, fileID)
+	case models.VerificationStepWaitingPassport:
 		return c.Send("✅ Селфи получено!\n\n📄 Теперь отправьте фотографию паспорта (страница с фото и данными).")
 
-	case models.VerificationStepWaitingPassport:
-		h.verificationService.UpdatePassport(userID, fileID)
+	case models.VerificationStepWaitingMRZ:
+		return c.Send("✅ Фотография паспорта получена!\n\n" + h.mrzPrompt())
+
+	case models.VerificationStepCompleted:
 		return h.sendVerificationToAdmin(c, state)
 
 	default:
@@ -85,9 +117,21 @@ func (h *Handler) HandleCallback(c tele.Context) error {
 	data := strings.TrimSpace(callback.Data)
 	h.logger.Info(fmt.Sprintf("Received callback data: '%s' from user: %d", data, callback.Sender.ID))
 
+	// Решения принимаются только в админском чате, данные callback можно подделать
+	if !h.isAdminChat(callback) {
+		h.logger.Info(fmt.Sprintf("Rejected verification callback outside admin chat from user: %d", callback.Sender.ID))
+		return c.Respond(&tele.CallbackResponse{Text: "⛔ Недостаточно прав"})
+	}
+
 	if strings.HasPrefix(data, "verify_user_") {
 		h.logger.Info("Processing verification callback")
 		return h.handleVerificationCallback(c, data)
+	} else if strings.HasPrefix(data, "verify_undo_") {
+		h.logger.Info("Processing verification undo callback")
+		return h.handleUndoCallback(c, data)
+	} else if strings.HasPrefix(data, "verify_release_") {
+		h.logger.Info("Processing screening release callback")
+		return h.handleReleaseCallback(c, data)
 	} else {
 		h.logger.Info("Callback data does not match verify_user_ pattern")
 	}
@@ -95,6 +139,12 @@ func (h *Handler) HandleCallback(c tele.Context) error {
 	return nil
 }
 
+// isAdminChat проверяет, что кнопка нажата в сообщении админского чата
+func (h *Handler) isAdminChat(callback *tele.Callback) bool {
+	return callback.Message != nil && callback.Message.Chat != nil &&
+		callback.Message.Chat.ID == h.config.TelegramAdminChatID
+}
+
 // sendVerificationToAdmin отправляет фотографии верификации в админский чат
 func (h *Handler) sendVerificationToAdmin(c tele.Context, state *models.VerificationState) error {
 	adminChat := &tele.Chat{ID: h.config.TelegramAdminChatID}
@@ -102,20 +152,12 @@ func (h *Handler) sendVerificationToAdmin(c tele.Context, state *models.Verifica
 	h.logger.Info(fmt.Sprintf("Sending verification to admin chat: %d", h.config.TelegramAdminChatID))
 
 	// Создаем inline кнопки
-	markup := &tele.ReplyMarkup{}
-	approveData := fmt.Sprintf("verify_user_%d_true", state.UserID)
-	rejectData := fmt.Sprintf("verify_user_%d_false", state.UserID)
-
-	h.logger.Info(fmt.Sprintf("Creating buttons with data: approve='%s', reject='%s'", approveData, rejectData))
-
-	approveBtn := markup.Data("✅ Подтвердить", approveData)
-	rejectBtn := markup.Data("❌ Отозвать", rejectData)
-	markup.Inline(markup.Row(approveBtn, rejectBtn))
+	markup := reviewMarkup(state)
 
 	// Отправляем селфи с кнопками
 	selfieMsg := &tele.Photo{
 		File:    tele.File{FileID: state.SelfieID},
-		Caption: fmt.Sprintf("🔐 Заявка на верификацию\n👤 Пользователь: %d\n📸 Селфи", state.UserID),
+		Caption: h.adminCaption(state),
 	}
 	selfieSentMsg, err := c.Bot().Send(adminChat, selfieMsg, markup)
 	if err != nil {
@@ -138,8 +180,10 @@ func (h *Handler) sendVerificationToAdmin(c tele.Context, state *models.Verifica
 
 	h.logger.Info(fmt.Sprintf("Successfully sent passport. Message ID: %d", passportSentMsg.ID))
 
-	// Сохраняем ID сообщений для последующего удаления
-	h.verificationService.UpdateMessageIDs(state.UserID, selfieSentMsg.ID, passportSentMsg.ID)
+	// Сохраняем ID сообщений для последующей фиксации решения
+	if err := h.verificationService.UpdateMessageIDs(state.UserID, selfieSentMsg.ID, passportSentMsg.ID); err != nil {
+		h.logger.Error("Failed to save verification message IDs:", err)
+	}
 
 	return c.Send("✅ Ваша заявка на верификацию отправлена администратору!\n\n⏳ Ожидайте решения. Мы уведомим вас о результате.")
 }
@@ -166,87 +210,51 @@ func (h *Handler) handleVerificationCallback(c tele.Context, data string) error
 
 	h.logger.Info(fmt.Sprintf("Processing verification callback: user_id=%d, verified=%t", userID, isVerified))
 
-	// Отправляем запрос к API
-	err = h.apiService.UpdateUserVerification(userID, isVerified)
-	if err != nil {
-		h.logger.Error("Failed to update user verification:", err)
-		return c.Respond(&tele.CallbackResponse{Text: "❌ Ошибка при обновлении статуса верификации"})
+	// Захватываем заявку, чтобы повторное нажатие не обработало ее второй раз.
+	// Удерживаемую заявку нельзя подтвердить до ручной проверки совпадения
+	state, err := h.verificationService.StartReview(userID, isVerified)
+	if errors.Is(err, services.ErrReviewHeld) {
+		return c.Respond(&tele.CallbackResponse{Text: "⛔ Заявка удержана: сначала проверьте совпадение со списком"})
+	}
+	if errors.Is(err, services.ErrUnexpectedStep) {
+		return c.Respond(&tele.CallbackResponse{Text: "⏳ Заявка уже обрабатывается"})
+	}
+	if err != nil && !errors.Is(err, services.ErrStateNotFound) {
+		h.logger.Error("Failed to start verification review:", err)
+		return c.Respond(&tele.CallbackResponse{Text: "❌ Ошибка обработки запроса"})
 	}
 
-	// Удаляем сообщения с фотографиями из админского чата
 	callback := c.Callback()
-	if callback != nil && callback.Message != nil {
-		h.logger.Info(fmt.Sprintf("Attempting to delete messages. Current message ID: %d", callback.Message.ID))
-
-		// Получаем состояние верификации для доступа к ID сообщений
-		state := h.verificationService.GetState(userID)
-		if state != nil && state.SelfieMessageID > 0 && state.PassportMessageID > 0 {
-			// Удаляем сообщение с селфи (с кнопками)
-			selfieMsg := &tele.Message{
-				ID:   state.SelfieMessageID,
-				Chat: callback.Message.Chat,
-			}
-			err = c.Bot().Delete(selfieMsg)
-			if err != nil {
-				h.logger.Error("Failed to delete selfie message:", err)
-			} else {
-				h.logger.Info(fmt.Sprintf("Successfully deleted selfie message ID: %d", state.SelfieMessageID))
-			}
-
-			// Удаляем сообщение с паспортом
-			passportMsg := &tele.Message{
-				ID:   state.PassportMessageID,
-				Chat: callback.Message.Chat,
-			}
-			err = c.Bot().Delete(passportMsg)
-			if err != nil {
-				h.logger.Error("Failed to delete passport message:", err)
-			} else {
-				h.logger.Info(fmt.Sprintf("Successfully deleted passport message ID: %d", state.PassportMessageID))
-			}
-
-			// Очищаем состояние
-			h.verificationService.ClearState(userID)
-		} else {
-			// Fallback: удаляем текущее сообщение и предыдущее
-			err = c.Bot().Delete(callback.Message)
-			if err != nil {
-				h.logger.Error("Failed to delete message with buttons:", err)
-			} else {
-				h.logger.Info("Successfully deleted message with buttons")
-			}
-
-			if callback.Message.ID > 1 {
-				prevMsg := &tele.Message{
-					ID:   callback.Message.ID - 1,
-					Chat: callback.Message.Chat,
-				}
-				err = c.Bot().Delete(prevMsg)
-				if err != nil {
-					h.logger.Error("Failed to delete passport message:", err)
-				} else {
-					h.logger.Info("Successfully deleted passport message")
-				}
-			}
-		}
+	decision := models.PendingDecision{
+		UserID:       userID,
+		IsVerified:   isVerified,
+		ReviewerID:   callback.Sender.ID,
+		ReviewerName: reviewerName(callback.Sender),
+		State:        state,
+		DecidedAt:    time.Now(),
+	}
+	if state != nil {
+		decision.ControlCaption = h.adminCaption(state)
 	} else {
-		h.logger.Error("Callback or callback.Message is nil, cannot delete messages")
+		decision.ControlCaption = selfieCaption(userID)
 	}
-
-	// Отправляем уведомление пользователю
-	userChat := &tele.Chat{ID: userID}
-	statusText := "✅ Верификация подтверждена!"
-	if !isVerified {
-		statusText = "❌ Верификация отклонена"
+	if callback.Message != nil {
+		decision.ChatID = callback.Message.Chat.ID
+		decision.ControlMessageID = callback.Message.ID
 	}
 
-	_, err = c.Bot().Send(userChat, statusText)
-	if err != nil {
-		h.logger.Error("Failed to send notification to user:", err)
+	// Без окна отмены применяем решение сразу
+	if h.config.DecisionUndoWindow <= 0 || decision.ControlMessageID == 0 {
+		if err := h.applyDecision(decision); err != nil {
+			return c.Respond(&tele.CallbackResponse{Text: "❌ Ошибка при обновлении статуса верификации"})
+		}
+		if !h.outboxService.BackendAvailable() {
+			return c.Respond(&tele.CallbackResponse{
+				Text: "⚠️ API временно недоступно. Решение сохранено и будет отправлено автоматически",
+			})
+		}
+		return c.Respond(&tele.CallbackResponse{Text: "✅ Решение принято и отправляется в API"})
 	}
 
-	h.logger.Info(fmt.Sprintf("Verification processed successfully: user_id=%d, verified=%t", userID, isVerified))
-
-	// Отвечаем на callback
-	return c.Respond(&tele.CallbackResponse{Text: "✅ Статус верификации обновлен"})
+	return h.deferDecision(c, decision)
 }
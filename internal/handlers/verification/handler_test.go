@@ -0,0 +1,374 @@
+package verification
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+	"tribute-chatbot/internal/config"
+	"tribute-chatbot/internal/fakebackend"
+	"tribute-chatbot/internal/faketelegram"
+	"tribute-chatbot/internal/models"
+	"tribute-chatbot/internal/services"
+	"tribute-chatbot/internal/storage"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	adminChatID      = -100
+	controlMessageID = 10
+	reviewerID       = 7
+)
+
+// testEnv обработчик верификации, подключенный к фейковым API и Telegram
+type testEnv struct {
+	handler  *Handler
+	states   *services.VerificationService
+	outbox   *services.OutboxService
+	backend  *fakebackend.Server
+	telegram *faketelegram.Server
+	bot      *tele.Bot
+	start    sync.Once
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	backend := fakebackend.New(fakebackend.Options{})
+	backendServer := httptest.NewServer(backend)
+	t.Cleanup(backendServer.Close)
+	telegram := faketelegram.New()
+	telegramServer := httptest.NewServer(telegram)
+	t.Cleanup(telegramServer.Close)
+
+	cfg := &config.Config{
+		TelegramAdminChatID:   adminChatID,
+		APIBaseURL:            backendServer.URL,
+		BackendTimeout:        time.Second,
+		BackendLookupTimeout:  time.Second,
+		BackendRetryBaseDelay: time.Millisecond,
+		BackendRetryMaxDelay:  time.Millisecond,
+		AdminMessageMode:      config.AdminMessageModeEdit,
+		MRZMode:               config.MRZModeOff,
+		PolicyViolationAction: config.PolicyActionFlag,
+		OutboxPollInterval:    10 * time.Millisecond,
+		OutboxRetryBaseDelay:  time.Millisecond,
+		OutboxRetryMaxDelay:   time.Millisecond,
+		OutboxMaxAttempts:     5,
+	}
+
+	api, err := services.NewAPIService(cfg)
+	if err != nil {
+		t.Fatalf("api service: %v", err)
+	}
+	dir := t.TempDir()
+	outbox, err := services.NewOutboxService(storage.NewJSONFile(filepath.Join(dir, "outbox.json")), api, cfg)
+	if err != nil {
+		t.Fatalf("outbox: %v", err)
+	}
+	decisions, err := services.NewDecisionService(storage.NewJSONFile(filepath.Join(dir, "decisions.json")))
+	if err != nil {
+		t.Fatalf("decisions: %v", err)
+	}
+	screening, err := services.NewScreeningService(cfg)
+	if err != nil {
+		t.Fatalf("screening: %v", err)
+	}
+	bot, err := faketelegram.NewBot(telegramServer.URL)
+	if err != nil {
+		t.Fatalf("bot: %v", err)
+	}
+
+	states := services.NewVerificationService(false)
+	handler := NewHandler(states, decisions, services.NewPolicyService(cfg), screening, outbox,
+		services.NewUserService(api, 0, cfg.BackendLookupTimeout), cfg)
+	handler.StartWorkers(bot)
+
+	return &testEnv{
+		handler:  handler,
+		states:   states,
+		outbox:   outbox,
+		backend:  backend,
+		telegram: telegram,
+		bot:      bot,
+	}
+}
+
+// submit переводит заявку пользователя в ожидание решения
+func (e *testEnv) submit(t *testing.T, userID int64) {
+	t.Helper()
+	e.states.InitializeState(userID)
+	for _, fileID := range []string{"selfie", "passport"} {
+		if _, err := e.states.SubmitPhoto(userID, fileID); err != nil {
+			t.Fatalf("submit %s: %v", fileID, err)
+		}
+	}
+	if err := e.states.UpdateMessageIDs(userID, controlMessageID, controlMessageID+1); err != nil {
+		t.Fatalf("message ids: %v", err)
+	}
+}
+
+// press нажимает кнопку решения в заявке
+func (e *testEnv) press(t *testing.T, updateID int, data string) {
+	t.Helper()
+	c := e.bot.NewContext(tele.Update{
+		ID: updateID,
+		Callback: &tele.Callback{
+			ID:      "callback",
+			Sender:  &tele.User{ID: reviewerID, FirstName: "Reviewer"},
+			Data:    data,
+			Message: &tele.Message{ID: controlMessageID, Chat: &tele.Chat{ID: adminChatID}},
+		},
+	})
+	if err := e.handler.HandleCallback(c); err != nil {
+		t.Errorf("handle callback: %v", err)
+	}
+}
+
+// pressConcurrently одновременно нажимает кнопки с указанными данными
+func (e *testEnv) pressConcurrently(t *testing.T, data []string) {
+	t.Helper()
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i, d := range data {
+		wg.Add(1)
+		go func(updateID int, data string) {
+			defer wg.Done()
+			<-start
+			e.press(t, updateID, data)
+		}(i+1, d)
+	}
+	close(start)
+	wg.Wait()
+}
+
+// deliver запускает outbox и ждет, пока все элементы будут доставлены, а результат
+// доставки обработан: done сообщает, что обработчик результата завершил работу
+func (e *testEnv) deliver(t *testing.T, done func() bool) {
+	t.Helper()
+	e.start.Do(func() {
+		e.outbox.Start()
+		t.Cleanup(e.outbox.Stop)
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(e.outbox.Items()) > 0 || !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("outbox not delivered: %+v", e.outbox.Items())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// notified сообщает, что пользователи получили результат верификации
+func (e *testEnv) notified(userIDs ...int64) func() bool {
+	return func() bool {
+		for _, userID := range userIDs {
+			if userMessages(e.telegram, userID) == 0 {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// answers возвращает тексты ответов на нажатия кнопок
+func (e *testEnv) answers() map[string]int {
+	answers := make(map[string]int)
+	for _, call := range e.telegram.CallsTo("answerCallbackQuery") {
+		answers[call.Param("text")]++
+	}
+	return answers
+}
+
+func TestDuplicateDecisionCallbacks(t *testing.T) {
+	env := newTestEnv(t)
+	const userID = 42
+	env.submit(t, userID)
+
+	const presses = 10
+	data := make([]string, presses)
+	for i := range data {
+		data[i] = "verify_user_42_true"
+	}
+	env.pressConcurrently(t, data)
+
+	if items := env.outbox.Items(); len(items) != 1 {
+		t.Fatalf("outbox items = %d, want 1", len(items))
+	}
+	answers := env.answers()
+	if answers["✅ Решение принято и отправляется в API"] != 1 || answers["⏳ Заявка уже обрабатывается"] != presses-1 {
+		t.Fatalf("callback answers = %v", answers)
+	}
+
+	env.deliver(t, env.notified(userID))
+
+	requests := env.backend.RequestsTo("/v1/check-verified-passport")
+	if len(requests) != 1 {
+		t.Fatalf("verification requests = %d, want 1", len(requests))
+	}
+	verification, ok := env.backend.State().Verification(userID)
+	if !ok || !verification.IsVerified {
+		t.Fatalf("backend verification = %+v, want verified", verification)
+	}
+	if state := env.states.GetState(userID); state != nil {
+		t.Fatalf("state left after decision: step %q", state.Step)
+	}
+	if notified := userMessages(env.telegram, userID); notified != 1 {
+		t.Fatalf("user notified %d times, want 1", notified)
+	}
+}
+
+func TestConflictingDecisionCallbacks(t *testing.T) {
+	env := newTestEnv(t)
+	const userID = 42
+	env.submit(t, userID)
+
+	// Два администратора одновременно подтверждают и отклоняют заявку
+	env.pressConcurrently(t, []string{"verify_user_42_true", "verify_user_42_false"})
+
+	items := env.outbox.Items()
+	if len(items) != 1 {
+		t.Fatalf("outbox items = %d, want 1", len(items))
+	}
+	var decision models.PendingDecision
+	if err := json.Unmarshal(items[0].Payload, &decision); err != nil {
+		t.Fatalf("decode decision: %v", err)
+	}
+	answers := env.answers()
+	if answers["✅ Решение принято и отправляется в API"] != 1 || answers["⏳ Заявка уже обрабатывается"] != 1 {
+		t.Fatalf("callback answers = %v", answers)
+	}
+
+	env.deliver(t, env.notified(userID))
+
+	if requests := env.backend.RequestsTo("/v1/check-verified-passport"); len(requests) != 1 {
+		t.Fatalf("verification requests = %d, want 1", len(requests))
+	}
+	verification, ok := env.backend.State().Verification(userID)
+	if !ok || verification.IsVerified != decision.IsVerified {
+		t.Fatalf("backend verification = %+v, want verified=%t", verification, decision.IsVerified)
+	}
+	if state := env.states.GetState(userID); state != nil {
+		t.Fatalf("state left after decision: step %q", state.Step)
+	}
+}
+
+func TestConcurrentDecisionsForDifferentUsers(t *testing.T) {
+	env := newTestEnv(t)
+	userIDs := []int64{41, 42, 43}
+	var data []string
+	for _, userID := range userIDs {
+		env.submit(t, userID)
+		for i := 0; i < 3; i++ {
+			data = append(data, "verify_user_"+itoa(userID)+"_true")
+		}
+	}
+
+	env.pressConcurrently(t, data)
+	env.deliver(t, env.notified(userIDs...))
+
+	requests := env.backend.RequestsTo("/v1/check-verified-passport")
+	if len(requests) != len(userIDs) {
+		t.Fatalf("verification requests = %d, want %d", len(requests), len(userIDs))
+	}
+	for _, userID := range userIDs {
+		if verification, ok := env.backend.State().Verification(userID); !ok || !verification.IsVerified {
+			t.Errorf("user %d: backend verification = %+v, want verified", userID, verification)
+		}
+		if state := env.states.GetState(userID); state != nil {
+			t.Errorf("user %d: state left after decision: step %q", userID, state.Step)
+		}
+		if notified := userMessages(env.telegram, userID); notified != 1 {
+			t.Errorf("user %d notified %d times, want 1", userID, notified)
+		}
+	}
+}
+
+func TestRejectedDecisionReturnsToReview(t *testing.T) {
+	env := newTestEnv(t)
+	const userID = 42
+	env.submit(t, userID)
+	env.backend.Inject(fakebackend.Fault{
+		Path:    "/v1/check-verified-passport",
+		Times:   1,
+		Status:  404,
+		Code:    "user_not_found",
+		Message: "user not found",
+	})
+
+	env.press(t, 1, "verify_user_42_true")
+	env.deliver(t, func() bool {
+		state := env.states.GetState(userID)
+		return state != nil && state.Step == models.VerificationStepCompleted
+	})
+
+	// Заявку можно решить заново, а решение применено не было
+	state := env.states.GetState(userID)
+	if state == nil || state.Step != models.VerificationStepCompleted {
+		t.Fatalf("state = %+v, want completed", state)
+	}
+	if _, ok := env.backend.State().Verification(userID); ok {
+		t.Fatal("rejected decision applied in backend")
+	}
+
+	env.press(t, 2, "verify_user_42_true")
+	env.deliver(t, env.notified(userID))
+
+	if verification, ok := env.backend.State().Verification(userID); !ok || !verification.IsVerified {
+		t.Fatalf("backend verification = %+v, want verified", verification)
+	}
+	if notified := userMessages(env.telegram, userID); notified != 1 {
+		t.Fatalf("user notified %d times, want 1", notified)
+	}
+}
+
+func TestRejectsCallbacksOutsideAdminChat(t *testing.T) {
+	env := newTestEnv(t)
+	const userID = 42
+	env.submit(t, userID)
+
+	for i, data := range []string{"verify_user_42_true", "verify_undo_42", "verify_release_42"} {
+		c := env.bot.NewContext(tele.Update{
+			ID: i + 1,
+			Callback: &tele.Callback{
+				ID:      "callback",
+				Sender:  &tele.User{ID: userID},
+				Data:    data,
+				Message: &tele.Message{ID: controlMessageID, Chat: &tele.Chat{ID: userID, Type: tele.ChatPrivate}},
+			},
+		})
+		if err := env.handler.HandleCallback(c); err != nil {
+			t.Fatalf("handle callback %s: %v", data, err)
+		}
+	}
+
+	if answers := env.answers(); answers["⛔ Недостаточно прав"] != 3 {
+		t.Fatalf("callback answers = %v", answers)
+	}
+	if items := env.outbox.Items(); len(items) != 0 {
+		t.Fatalf("outbox items = %d, want 0", len(items))
+	}
+	if state := env.states.GetState(userID); state == nil || state.Step != models.VerificationStepCompleted {
+		t.Fatalf("state = %+v, want completed", state)
+	}
+}
+
+// userMessages считает сообщения, отправленные пользователю
+func userMessages(telegram *faketelegram.Server, userID int64) int {
+	count := 0
+	for _, call := range telegram.CallsTo("sendMessage") {
+		if call.Param("chat_id") == itoa(userID) {
+			count++
+		}
+	}
+	return count
+}
+
+func itoa(value int64) string {
+	return strconv.FormatInt(value, 10)
+}
@@ -0,0 +1,167 @@
+package verification
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"tribute-chatbot/internal/config"
+	"tribute-chatbot/internal/models"
+	"tribute-chatbot/internal/mrz"
+	"tribute-chatbot/internal/services"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// AwaitsText сообщает, ожидает ли верификация пользователя текстового ввода
+func (h *Handler) AwaitsText(userID int64) bool {
+	state := h.verificationService.GetState(userID)
+	return state != nil && state.Step == models.VerificationStepWaitingMRZ
+}
+
+// HandleText обрабатывает строки MRZ, введенные пользователем
+func (h *Handler) HandleText(c tele.Context) error {
+	userID := c.Sender().ID
+
+	passport, err := mrz.Parse(c.Text(), time.Now())
+	if err != nil {
+		h.logger.Info(fmt.Sprintf("Rejected MRZ from user %d: %v", userID, err))
+		return c.Send("❌ " + mrzErrorHint(err) + "\n\n" + h.mrzPrompt())
+	}
+
+	identity := identityFromPassport(passport)
+	policy := h.policyService.Evaluate(identity, time.Now())
+	if !policy.Passed {
+		h.logger.Info(fmt.Sprintf("Policy violations for user %d: %+v", userID, policy.Violations))
+		if h.config.PolicyViolationAction == config.PolicyActionBlock {
+			h.verificationService.ClearState(userID)
+			return c.Send("❌ Заявка не может быть отправлена:\n" + violationsText(policy.Violations))
+		}
+	}
+
+	details := models.VerificationDetails{Identity: &identity, Policy: &policy}
+	if h.screeningService.Enabled() {
+		screening := h.screeningService.Screen(identity)
+		if len(screening.Matches) > 0 {
+			h.logger.Info(fmt.Sprintf("Screening matches for user %d: %+v", userID, screening.Matches))
+		}
+		details.Screening = &screening
+	}
+
+	state, err := h.verificationService.SubmitMRZ(userID, details)
+	if err != nil {
+		h.logger.Error("Failed to save MRZ data:", err)
+		return c.Send("❌ Неожиданное состояние. Используйте /verificate для начала заново.")
+	}
+
+	return h.sendVerificationToAdmin(c, state)
+}
+
+// HandleSkip обрабатывает команду /skip на шаге ввода MRZ
+func (h *Handler) HandleSkip(c tele.Context) error {
+	userID := c.Sender().ID
+
+	if !h.AwaitsText(userID) {
+		return c.Send("Сейчас нечего пропускать. Используйте /verificate для начала верификации.")
+	}
+	if !h.mrzSkippable() {
+		return c.Send("❌ Ввод MRZ обязателен.\n\n" + h.mrzPrompt())
+	}
+
+	state, err := h.verificationService.SkipMRZ(userID)
+	if err != nil {
+		h.logger.Error("Failed to skip MRZ step:", err)
+		return c.Send("❌ Неожиданное состояние. Используйте /verificate для начала заново.")
+	}
+
+	return h.sendVerificationToAdmin(c, state)
+}
+
+// mrzPrompt возвращает инструкцию по вводу MRZ
+func (h *Handler) mrzPrompt() string {
+	prompt := "🔤 Введите две строки машиночитаемой зоны (MRZ) внизу страницы паспорта с фото — " +
+		"по 44 символа, каждая строка с новой строки. Символ «<» вводите как есть."
+	if h.mrzSkippable() {
+		prompt += "\n\nЧтобы пропустить этот шаг, отправьте /skip."
+	}
+	return prompt
+}
+
+// mrzSkippable сообщает, можно ли пропустить ввод MRZ. Без MRZ не проверить правила
+// и список, поэтому шаг обязателен, если нарушение правил блокирует заявку
+// или включена проверка по списку.
+func (h *Handler) mrzSkippable() bool {
+	return h.config.MRZMode == config.MRZModeOptional &&
+		h.config.PolicyViolationAction != config.PolicyActionBlock &&
+		!h.screeningService.Enabled()
+}
+
+// mrzErrorHint возвращает пользователю подсказку по ошибке разбора MRZ
+func mrzErrorHint(err error) string {
+	var checkErr *mrz.CheckDigitError
+	switch {
+	case errors.Is(err, mrz.ErrLineCount):
+		return "Нужно ровно две строки MRZ."
+	case errors.Is(err, mrz.ErrLineLength):
+		return "Каждая строка MRZ паспорта должна содержать 44 символа. Проверьте, что не пропущены символы «<»."
+	case errors.Is(err, mrz.ErrInvalidCharacter):
+		return "MRZ может содержать только латинские буквы, цифры и символ «<»."
+	case errors.Is(err, mrz.ErrNotPassport):
+		return "Первая строка MRZ паспорта должна начинаться с буквы P."
+	case errors.Is(err, mrz.ErrInvalidDate):
+		return "В MRZ указана некорректная дата."
+	case errors.As(err, &checkErr):
+		return fmt.Sprintf("Не совпала контрольная цифра поля «%s». Проверьте, нет ли опечатки.", checkFieldName(checkErr.Field))
+	default:
+		return "Не удалось разобрать MRZ."
+	}
+}
+
+// checkFieldName переводит название поля MRZ для пользователя
+func checkFieldName(field string) string {
+	switch field {
+	case mrz.FieldDocumentNumber:
+		return "номер документа"
+	case mrz.FieldBirthDate:
+		return "дата рождения"
+	case mrz.FieldExpiryDate:
+		return "срок действия"
+	case mrz.FieldPersonalNumber:
+		return "личный номер"
+	default:
+		return "вторая строка целиком"
+	}
+}
+
+// identityFromPassport переводит разобранный MRZ в данные личности
+func identityFromPassport(passport *mrz.Passport) models.IdentityData {
+	return models.IdentityData{
+		Surname:        passport.Surname,
+		GivenNames:     passport.GivenNames,
+		Nationality:    passport.Nationality,
+		IssuingCountry: passport.IssuingCountry,
+		Sex:            passport.Sex,
+		BirthDate:      passport.BirthDate,
+		DocumentNumber: passport.DocumentNumber,
+		ExpiryDate:     passport.ExpiryDate,
+	}
+}
+
+// identityCaption формирует блок с данными MRZ для подписи заявки
+func identityCaption(identity *models.IdentityData) string {
+	lines := []string{
+		"🪪 Данные MRZ:",
+		"Фамилия: " + identity.Surname,
+		"Имя: " + identity.GivenNames,
+		"Гражданство: " + identity.Nationality,
+		"Дата рождения: " + identity.BirthDate.Format("02.01.2006"),
+		"Документ: " + identity.DocumentNumber,
+		"Действителен до: " + identity.ExpiryDate.Format("02.01.2006"),
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isWaitingMRZ сообщает, что фото пришло на шаге ввода MRZ
+func (h *Handler) isWaitingMRZ(userID int64, err error) bool {
+	return errors.Is(err, services.ErrUnexpectedStep) && h.AwaitsText(userID)
+}
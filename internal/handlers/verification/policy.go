@@ -0,0 +1,40 @@
+package verification
+
+import (
+	"fmt"
+	"strings"
+	"tribute-chatbot/internal/models"
+)
+
+// policyCaption формирует блок с результатом проверки правил для подписи заявки
+func policyCaption(result *models.PolicyResult) string {
+	if result.Passed {
+		return fmt.Sprintf("✅ Правила соблюдены: возраст %d, документ действителен еще %d дн.",
+			result.Age, result.DocumentValidDays)
+	}
+	return "⚠️ Нарушены правила:\n" + violationsText(result.Violations)
+}
+
+// violationsText перечисляет нарушения правил по строкам
+func violationsText(violations []models.PolicyViolation) string {
+	lines := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		lines = append(lines, "• "+violationText(violation))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// violationText описывает нарушение правила
+func violationText(violation models.PolicyViolation) string {
+	switch violation.Rule {
+	case models.PolicyRuleMinAge:
+		return fmt.Sprintf("возраст %d, требуется не менее %d лет", violation.Actual, violation.Required)
+	case models.PolicyRuleDocumentValidity:
+		if violation.Actual < 0 {
+			return "срок действия документа истек"
+		}
+		return fmt.Sprintf("документ действителен еще %d дн., требуется не менее %d", violation.Actual, violation.Required)
+	default:
+		return violation.Rule
+	}
+}
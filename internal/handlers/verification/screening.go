@@ -0,0 +1,92 @@
+package verification
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"tribute-chatbot/internal/models"
+	"tribute-chatbot/internal/services"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// handleReleaseCallback снимает удержание заявки после ручной проверки совпадения
+func (h *Handler) handleReleaseCallback(c tele.Context, data string) error {
+	// Парсим данные: verify_release_<user_id>
+	parts := strings.Split(data, "_")
+	if len(parts) != 3 {
+		h.logger.Error("Invalid release callback data format:", data)
+		return c.Respond(&tele.CallbackResponse{Text: "❌ Ошибка обработки запроса"})
+	}
+
+	userID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		h.logger.Error("Failed to parse user ID:", parts[2], err)
+		return c.Respond(&tele.CallbackResponse{Text: "❌ Ошибка обработки запроса"})
+	}
+
+	state, err := h.verificationService.ReleaseHold(userID, reviewerName(c.Callback().Sender))
+	if errors.Is(err, services.ErrStateNotFound) || errors.Is(err, services.ErrUnexpectedStep) {
+		return c.Respond(&tele.CallbackResponse{Text: "Заявка не удерживается"})
+	}
+	if err != nil {
+		h.logger.Error("Failed to release screening hold:", err)
+		return c.Respond(&tele.CallbackResponse{Text: "❌ Ошибка обработки запроса"})
+	}
+
+	h.logger.Info(fmt.Sprintf("Screening hold released: user_id=%d, by=%s", userID, state.Screening.ReleasedBy))
+
+	if callback := c.Callback(); callback.Message != nil {
+		if _, err := c.Bot().EditCaption(callback.Message, h.adminCaption(state), reviewMarkup(state)); err != nil {
+			h.logger.Error("Failed to update held application:", err)
+		}
+	}
+
+	return c.Respond(&tele.CallbackResponse{Text: "🔓 Удержание снято"})
+}
+
+// isHeld сообщает, удерживается ли заявка из-за совпадения со списком
+func isHeld(state *models.VerificationState) bool {
+	return state != nil && state.Screening != nil && state.Screening.Held
+}
+
+// reviewMarkup возвращает кнопки заявки: для удерживаемой заявки вместо
+// подтверждения показывается кнопка снятия удержания
+func reviewMarkup(state *models.VerificationState) *tele.ReplyMarkup {
+	if !isHeld(state) {
+		return decisionMarkup(state.UserID)
+	}
+	markup := &tele.ReplyMarkup{}
+	releaseBtn := markup.Data("🔓 Снять удержание", fmt.Sprintf("verify_release_%d", state.UserID))
+	rejectBtn := markup.Data("❌ Отозвать", fmt.Sprintf("verify_user_%d_false", state.UserID))
+	markup.Inline(markup.Row(releaseBtn, rejectBtn))
+	return markup
+}
+
+// screeningCaption формирует блок с результатом проверки по списку для подписи заявки
+func screeningCaption(result *models.ScreeningResult) string {
+	if len(result.Matches) == 0 {
+		return "🔍 Совпадений со списком нет"
+	}
+
+	lines := []string{"🚩 Совпадения со списком:"}
+	for _, match := range result.Matches {
+		line := fmt.Sprintf("• %s — %.0f%%", match.Name, match.Score*100)
+		if match.Source != "" {
+			line += " (" + match.Source + ")"
+		}
+		if match.BirthDateMatch {
+			line += ", дата рождения совпадает"
+		}
+		lines = append(lines, line)
+	}
+
+	switch {
+	case result.Held:
+		lines = append(lines, "⛔ Заявка удержана до ручной проверки")
+	case result.ReleasedBy != "":
+		lines = append(lines, "🔓 Удержание снято "+result.ReleasedBy)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -16,9 +16,9 @@ type Logger interface {
 	WithField(key string, value interface{}) Logger
 }
 
-// logger реализация логгера
+// logger реализация логгера. entry хранит поля, добавленные через WithField.
 type logger struct {
-	log *logrus.Logger
+	entry *logrus.Entry
 }
 
 // New создает новый экземпляр логгера
@@ -44,29 +44,29 @@ func New() Logger {
 		log.SetLevel(logrus.InfoLevel)
 	}
 
-	return &logger{log: log}
+	return &logger{entry: logrus.NewEntry(log)}
 }
 
 func (l *logger) Info(args ...interface{}) {
-	l.log.Info(args...)
+	l.entry.Info(args...)
 }
 
 func (l *logger) Error(args ...interface{}) {
-	l.log.Error(args...)
+	l.entry.Error(args...)
 }
 
 func (l *logger) Fatal(args ...interface{}) {
-	l.log.Fatal(args...)
+	l.entry.Fatal(args...)
 }
 
 func (l *logger) Debug(args ...interface{}) {
-	l.log.Debug(args...)
+	l.entry.Debug(args...)
 }
 
 func (l *logger) Warn(args ...interface{}) {
-	l.log.Warn(args...)
+	l.entry.Warn(args...)
 }
 
 func (l *logger) WithField(key string, value interface{}) Logger {
-	return &logger{log: l.log.WithField(key, value).Logger}
+	return &logger{entry: l.entry.WithField(key, value)}
 }
@@ -0,0 +1,123 @@
+package models
+
+import "time"
+
+// Channel канал, зарегистрированный ботом, в том виде, в котором он отправлен в API
+type Channel struct {
+	ChatID        int64
+	Type          string
+	Title         string
+	Username      string
+	Description   string
+	PhotoFileID   string
+	PhotoUniqueID string
+	OwnerID       int64
+	UpdatedAt     time.Time
+}
+
+// Registration возвращает данные регистрации канала в API
+func (c Channel) Registration() ChannelRegistration {
+	return ChannelRegistration{
+		UserID:          c.OwnerID,
+		ChatID:          c.ChatID,
+		ChatType:        c.Type,
+		ChannelTitle:    c.Title,
+		ChannelUsername: c.Username,
+		Description:     c.Description,
+		PhotoFileID:     c.PhotoFileID,
+		PhotoUniqueID:   c.PhotoUniqueID,
+	}
+}
+
+// Update возвращает данные обновления канала в API
+func (c Channel) Update() ChannelUpdate {
+	return ChannelUpdate{
+		UserID:          c.OwnerID,
+		ChatID:          c.ChatID,
+		ChannelTitle:    c.Title,
+		ChannelUsername: c.Username,
+		Description:     c.Description,
+		PhotoFileID:     c.PhotoFileID,
+		PhotoUniqueID:   c.PhotoUniqueID,
+		UpdatedAt:       c.UpdatedAt,
+	}
+}
+
+// PendingChannelLink канал, ожидающий подтверждения привязки к аккаунту владельца
+type PendingChannelLink struct {
+	Channel     Channel
+	BotID       int64 // ID бота в канале, чтобы после подтверждения проверить его права
+	UpdateID    int   // ID обновления Telegram, из которого строится ключ идемпотентности регистрации
+	MessageID   int   // сообщение с кнопками подтверждения в личном чате владельца
+	RequestedAt time.Time
+	ExpiresAt   time.Time
+}
+
+// Виды расхождений между списком каналов в API и состоянием бота в канале
+const (
+	// ChannelDiscrepancyBotRemoved канал активен в API, но бот удален из канала или канал не найден
+	ChannelDiscrepancyBotRemoved = "bot_removed"
+	// ChannelDiscrepancyBotNotAdmin канал активен в API, но бот больше не администратор
+	ChannelDiscrepancyBotNotAdmin = "bot_not_admin"
+	// ChannelDiscrepancyInactive канал отключен в API, но бот остается администратором
+	ChannelDiscrepancyInactive = "bot_admin_inactive"
+)
+
+// ChannelDiscrepancy расхождение между API и фактическим статусом бота в канале
+type ChannelDiscrepancy struct {
+	ChatID          int64
+	UserID          int64
+	ChannelTitle    string
+	ChannelUsername string
+	Kind            string
+	BotStatus       string // статус бота по данным Bot API, например kicked или chat_not_found
+}
+
+// ChannelReconciliation результат сверки подключенных каналов с Bot API
+type ChannelReconciliation struct {
+	Checked       int
+	Failed        int // каналы, которые не удалось проверить из-за ошибки Bot API
+	Discrepancies []ChannelDiscrepancy
+	CheckedAt     time.Time
+}
+
+// Правила вступления в канал по заявке
+const (
+	// ChannelJoinPolicySubscription заявка одобряется подписчикам с активной подпиской
+	ChannelJoinPolicySubscription = "subscription"
+	// ChannelJoinPolicyVerification заявка одобряется верифицированным пользователям
+	ChannelJoinPolicyVerification = "verification"
+)
+
+// ChannelAccess данные API для решения по заявке на вступление в канал
+type ChannelAccess struct {
+	ChatID          int64
+	UserID          int64
+	Policy          string
+	HasSubscription bool
+	IsVerified      bool
+	SubscribeURL    string // ссылка на оформление подписки для отклоненных заявок
+}
+
+// Allowed сообщает, можно ли одобрить заявку по правилу канала.
+// Неизвестное правило считается требованием подписки.
+func (a ChannelAccess) Allowed() bool {
+	if a.Policy == ChannelJoinPolicyVerification {
+		return a.IsVerified
+	}
+	return a.HasSubscription
+}
+
+// ChannelSettings настройки канала из API: кнопки под постами
+type ChannelSettings struct {
+	ChatID          int64
+	TipButton       bool   // кнопка доната автору
+	SubscribeButton bool   // кнопка оформления подписки
+	ExcludeHashtag  string // посты с этим хэштегом остаются без кнопок
+	TipURL          string // страница доната, на которую бот ведет по кнопке
+}
+
+// PostButtons сообщает, нужно ли прикреплять кнопки к постам канала
+func (s ChannelSettings) PostButtons() bool {
+	return s.TipButton || s.SubscribeButton
+}
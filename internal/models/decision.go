@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// PendingDecision решение по заявке, ожидающее окончания окна отмены
+type PendingDecision struct {
+	UserID           int64
+	IsVerified       bool
+	ReviewerID       int64
+	ReviewerName     string
+	ChatID           int64
+	ControlMessageID int
+	ControlCaption   string
+	State            *VerificationState // снимок заявки на момент решения
+	DecidedAt        time.Time
+	ExecuteAt        time.Time
+}
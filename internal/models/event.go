@@ -0,0 +1,56 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Типы событий, которые бэкенд отправляет боту
+const (
+	EventSubscriptionStarted = "subscription.started"
+	EventSubscriptionEnded   = "subscription.ended"
+	EventPaymentReceived     = "payment.received"
+	EventVerificationChanged = "verification.changed"
+	EventInviteRequested     = "invite.requested"
+)
+
+// BackendEvent событие бэкенда. Data содержит данные, зависящие от Type.
+type BackendEvent struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// SubscriptionEventData данные событий начала и окончания подписки.
+// Если задан ChannelID, при начале подписки подписчик получает ссылку в канал.
+type SubscriptionEventData struct {
+	CreatorID      int64     `json:"creator_id"`
+	SubscriberID   int64     `json:"subscriber_id"`
+	SubscriberName string    `json:"subscriber_name"`
+	ChannelID      int64     `json:"channel_id"`
+	ChannelTitle   string    `json:"channel_title"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// InviteEventData данные запроса бэкенда на выдачу ссылки в канал подписчику
+type InviteEventData struct {
+	ChannelID    int64  `json:"channel_id"`
+	ChannelTitle string `json:"channel_title"`
+	SubscriberID int64  `json:"subscriber_id"`
+}
+
+// PaymentEventData данные события зачисления выплаты
+type PaymentEventData struct {
+	UserID      int64  `json:"user_id"`
+	Amount      string `json:"amount"`
+	Currency    string `json:"currency"`
+	Description string `json:"description"`
+}
+
+// VerificationEventData данные события изменения верификации в бэк-офисе
+type VerificationEventData struct {
+	UserID     int64  `json:"user_id"`
+	IsVerified bool   `json:"is_verified"`
+	Reason     string `json:"reason"`
+}
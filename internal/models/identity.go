@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// IdentityData данные личности, извлеченные из MRZ паспорта
+type IdentityData struct {
+	Surname        string
+	GivenNames     string
+	Nationality    string
+	IssuingCountry string
+	Sex            string
+	BirthDate      time.Time
+	DocumentNumber string
+	ExpiryDate     time.Time
+}
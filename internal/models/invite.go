@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Статусы персональной пригласительной ссылки
+const (
+	InviteStatusIssued  = "issued"
+	InviteStatusUsed    = "used"
+	InviteStatusRevoked = "revoked"
+)
+
+// InviteLink персональная пригласительная ссылка подписчика в канал
+// с ограничением в одного участника
+type InviteLink struct {
+	Name         string // уникальное имя ссылки в Telegram, идентифицирует ссылку
+	Link         string
+	ChatID       int64
+	SubscriberID int64
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+	UsedBy       int64 // пользователь, вступивший по ссылке
+	UsedAt       time.Time
+	Revoked      bool
+}
+
+// Status возвращает статус ссылки
+func (l InviteLink) Status() string {
+	switch {
+	case l.UsedBy != 0:
+		return InviteStatusUsed
+	case l.Revoked:
+		return InviteStatusRevoked
+	default:
+		return InviteStatusIssued
+	}
+}
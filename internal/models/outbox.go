@@ -0,0 +1,90 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Типы элементов outbox
+const (
+	OutboxKindVerification        = "verification"
+	OutboxKindChannelRegistration = "channel_registration"
+	OutboxKindChannelDeactivation = "channel_deactivation"
+	OutboxKindChannelPermissions  = "channel_permissions"
+	OutboxKindChannelUpdate       = "channel_update"
+	OutboxKindReconciliation      = "channel_reconciliation"
+	OutboxKindInviteLink          = "invite_link"
+	OutboxKindSubscriptionRemoval = "subscription_removal"
+)
+
+// OutboxItem изменяющий вызов API, ожидающий доставки
+type OutboxItem struct {
+	ID             string
+	Kind           string
+	Payload        json.RawMessage
+	IdempotencyKey string
+	Attempts       int
+	LastError      string
+	Stuck          bool // превышено число попыток, нужен ручной повтор или удаление
+	CreatedAt      time.Time
+	NextAttemptAt  time.Time
+}
+
+// ChannelRegistration данные регистрации канала в API.
+// Канал идентифицируется по ChatID: у приватных каналов нет username, а название может меняться.
+type ChannelRegistration struct {
+	UserID          int64
+	ChatID          int64
+	ChatType        string // channel или supergroup
+	ChannelTitle    string
+	ChannelUsername string
+	Description     string
+	PhotoFileID     string // file_id большой аватарки канала
+	PhotoUniqueID   string
+}
+
+// Channel возвращает канал, данные которого отправлены в API при регистрации
+func (r ChannelRegistration) Channel() Channel {
+	return Channel{
+		ChatID:        r.ChatID,
+		Type:          r.ChatType,
+		Title:         r.ChannelTitle,
+		Username:      r.ChannelUsername,
+		Description:   r.Description,
+		PhotoFileID:   r.PhotoFileID,
+		PhotoUniqueID: r.PhotoUniqueID,
+		OwnerID:       r.UserID,
+	}
+}
+
+// ChannelUpdate новые название, username и метаданные зарегистрированного канала
+type ChannelUpdate struct {
+	UserID          int64
+	ChatID          int64
+	ChannelTitle    string
+	ChannelUsername string
+	Description     string
+	PhotoFileID     string
+	PhotoUniqueID   string
+	UpdatedAt       time.Time
+}
+
+// ChannelDeactivation данные отключения канала, из которого бот удален или лишен прав
+type ChannelDeactivation struct {
+	UserID          int64
+	ChatID          int64
+	ChannelTitle    string
+	ChannelUsername string
+	Reason          string // новый статус бота: member, left или kicked
+}
+
+// ChannelPermissionReport результат проверки прав бота в канале.
+// Missing содержит названия недостающих прав в Bot API, например can_invite_users.
+type ChannelPermissionReport struct {
+	UserID          int64
+	ChatID          int64
+	ChannelTitle    string
+	ChannelUsername string
+	Missing         []string
+	CheckedAt       time.Time
+}
@@ -0,0 +1,33 @@
+package models
+
+// Правила проверки данных личности
+const (
+	PolicyRuleMinAge           = "min_age"
+	PolicyRuleDocumentValidity = "document_validity"
+)
+
+// PolicyViolation нарушение правила: требуемое и фактическое значение
+// (возраст в годах или срок действия документа в днях)
+type PolicyViolation struct {
+	Rule     string
+	Required int
+	Actual   int
+}
+
+// PolicyResult результат проверки данных личности по правилам
+type PolicyResult struct {
+	Passed            bool
+	Age               int
+	DocumentValidDays int
+	Violations        []PolicyViolation
+}
+
+// Clone возвращает независимую копию результата
+func (r *PolicyResult) Clone() *PolicyResult {
+	if r == nil {
+		return nil
+	}
+	clone := *r
+	clone.Violations = append([]PolicyViolation(nil), r.Violations...)
+	return &clone
+}
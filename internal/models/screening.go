@@ -0,0 +1,27 @@
+package models
+
+// ScreeningMatch совпадение проверяемого лица с записью списка
+type ScreeningMatch struct {
+	EntryID        string
+	Name           string
+	Source         string
+	Score          float64
+	BirthDateMatch bool
+}
+
+// ScreeningResult результат проверки лица по списку
+type ScreeningResult struct {
+	Matches    []ScreeningMatch
+	Held       bool   // заявку нельзя подтвердить до ручной проверки совпадения
+	ReleasedBy string // кто снял удержание
+}
+
+// Clone возвращает независимую копию результата
+func (r *ScreeningResult) Clone() *ScreeningResult {
+	if r == nil {
+		return nil
+	}
+	clone := *r
+	clone.Matches = append([]ScreeningMatch(nil), r.Matches...)
+	return &clone
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// ExpiredSubscription подписка, срок которой истек, а подписчик еще не удален из канала
+type ExpiredSubscription struct {
+	ChatID       int64
+	ChannelTitle string
+	SubscriberID int64
+	ExpiredAt    time.Time
+	RenewURL     string    // ссылка на продление подписки
+	NotifiedAt   time.Time // когда подписчику отправлено напоминание о продлении
+	RemoveAt     time.Time // когда подписчик будет удален, если не продлит подписку
+	RemovedAt    time.Time
+}
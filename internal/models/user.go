@@ -0,0 +1,18 @@
+package models
+
+// UserProfile данные пользователя из бэкенда
+type UserProfile struct {
+	UserID     int64
+	IsVerified bool
+	IsCreator  bool
+	Channels   []ChannelSummary
+}
+
+// ChannelSummary канал, подключенный пользователем
+type ChannelSummary struct {
+	ChatID   int64
+	UserID   int64 // владелец канала
+	Title    string
+	Username string
+	Active   bool
+}
@@ -5,9 +5,43 @@ type VerificationState struct {
 	UserID            int64
 	SelfieID          string
 	PassportID        string
-	Step              string // "waiting_selfie", "waiting_passport", "completed"
+	Step              string // "waiting_selfie", "waiting_passport", "waiting_mrz", "completed", "reviewing"
 	SelfieMessageID   int
 	PassportMessageID int
+	Identity          *IdentityData    // данные из MRZ, если пользователь их ввел
+	Policy            *PolicyResult    // результат проверки данных из MRZ по правилам
+	Screening         *ScreeningResult // результат проверки по списку
+	Version           int64            // увеличивается при каждом сохранении состояния
+}
+
+// Clone возвращает независимую копию состояния
+func (s *VerificationState) Clone() *VerificationState {
+	if s == nil {
+		return nil
+	}
+	clone := *s
+	if s.Identity != nil {
+		identity := *s.Identity
+		clone.Identity = &identity
+	}
+	clone.Policy = s.Policy.Clone()
+	clone.Screening = s.Screening.Clone()
+	return &clone
+}
+
+// Details возвращает данные заявки для передачи в API вместе с решением
+func (s *VerificationState) Details() *VerificationDetails {
+	if s == nil || s.Identity == nil {
+		return nil
+	}
+	return &VerificationDetails{Identity: s.Identity, Policy: s.Policy, Screening: s.Screening}
+}
+
+// VerificationDetails данные заявки, передаваемые в API вместе с решением
+type VerificationDetails struct {
+	Identity  *IdentityData
+	Policy    *PolicyResult
+	Screening *ScreeningResult
 }
 
 // VerificationData хранит данные для отправки в админский чат
@@ -28,5 +62,7 @@ type VerificationCallbackData struct {
 const (
 	VerificationStepWaitingSelfie   = "waiting_selfie"
 	VerificationStepWaitingPassport = "waiting_passport"
+	VerificationStepWaitingMRZ      = "waiting_mrz"
 	VerificationStepCompleted       = "completed"
+	VerificationStepReviewing       = "reviewing"
 )
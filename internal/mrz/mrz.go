@@ -0,0 +1,218 @@
+package mrz
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Длина строки MRZ паспорта формата TD3 (ICAO 9303, часть 4)
+const td3LineLength = 44
+
+var (
+	// ErrLineCount возвращается, если передано не две строки
+	ErrLineCount = errors.New("mrz must contain exactly two lines")
+	// ErrLineLength возвращается, если длина строки не равна 44 символам
+	ErrLineLength = errors.New("mrz lines must be 44 characters long")
+	// ErrInvalidCharacter возвращается при символах вне A-Z, 0-9 и '<'
+	ErrInvalidCharacter = errors.New("mrz contains invalid characters")
+	// ErrNotPassport возвращается, если документ не является паспортом
+	ErrNotPassport = errors.New("mrz does not belong to a passport")
+	// ErrInvalidDate возвращается, если дата в MRZ не может быть разобрана
+	ErrInvalidDate = errors.New("mrz contains an invalid date")
+)
+
+// CheckDigitError возвращается, если контрольная цифра поля не совпала
+type CheckDigitError struct {
+	Field    string
+	Expected int
+	Actual   byte
+}
+
+func (e *CheckDigitError) Error() string {
+	return fmt.Sprintf("mrz check digit mismatch for %s: expected %d, got %q", e.Field, e.Expected, e.Actual)
+}
+
+// Названия полей, проверяемых контрольными цифрами
+const (
+	FieldDocumentNumber = "document number"
+	FieldBirthDate      = "date of birth"
+	FieldExpiryDate     = "expiry date"
+	FieldPersonalNumber = "personal number"
+	FieldComposite      = "composite"
+)
+
+// fieldCheck поле MRZ и его контрольная цифра
+type fieldCheck struct {
+	field string
+	data  string
+	digit byte
+}
+
+// Passport данные, извлеченные из MRZ паспорта
+type Passport struct {
+	DocumentCode   string
+	IssuingCountry string
+	Surname        string
+	GivenNames     string
+	DocumentNumber string
+	Nationality    string
+	BirthDate      time.Time
+	Sex            string
+	ExpiryDate     time.Time
+	PersonalNumber string
+}
+
+// Parse разбирает две строки MRZ паспорта (TD3) и проверяет контрольные цифры.
+// now используется для определения века в двузначных годах.
+func Parse(text string, now time.Time) (*Passport, error) {
+	lines := normalize(text)
+	if len(lines) != 2 {
+		return nil, ErrLineCount
+	}
+
+	line1, line2 := lines[0], lines[1]
+	if len(line1) != td3LineLength || len(line2) != td3LineLength {
+		return nil, ErrLineLength
+	}
+	for _, line := range lines {
+		for i := 0; i < len(line); i++ {
+			if charValue(line[i]) < 0 {
+				return nil, ErrInvalidCharacter
+			}
+		}
+	}
+	if line1[0] != 'P' {
+		return nil, ErrNotPassport
+	}
+
+	checks := []fieldCheck{
+		{FieldDocumentNumber, line2[0:9], line2[9]},
+		{FieldBirthDate, line2[13:19], line2[19]},
+		{FieldExpiryDate, line2[21:27], line2[27]},
+		{FieldComposite, line2[0:10] + line2[13:20] + line2[21:43], line2[43]},
+	}
+	// Контрольная цифра личного номера может быть заполнителем, если номера нет
+	if line2[42] != '<' || strings.Trim(line2[28:42], "<") != "" {
+		checks = append(checks, fieldCheck{FieldPersonalNumber, line2[28:42], line2[42]})
+	}
+	for _, check := range checks {
+		if err := verify(check.field, check.data, check.digit); err != nil {
+			return nil, err
+		}
+	}
+
+	birthDate, err := parseDate(line2[13:19], func(year int) int {
+		// Дата рождения не может быть в будущем
+		if year > now.Year()%100 {
+			return 1900 + year
+		}
+		return 2000 + year
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	expiryDate, err := parseDate(line2[21:27], func(year int) int {
+		// Срок действия паспорта не превышает нескольких десятилетий
+		if 2000+year > now.Year()+50 {
+			return 1900 + year
+		}
+		return 2000 + year
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	surname, givenNames := parseName(line1[5:])
+
+	return &Passport{
+		DocumentCode:   strings.TrimRight(line1[0:2], "<"),
+		IssuingCountry: strings.TrimRight(line1[2:5], "<"),
+		Surname:        surname,
+		GivenNames:     givenNames,
+		DocumentNumber: strings.TrimRight(line2[0:9], "<"),
+		Nationality:    strings.TrimRight(line2[10:13], "<"),
+		BirthDate:      birthDate,
+		Sex:            strings.TrimRight(line2[20:21], "<"),
+		ExpiryDate:     expiryDate,
+		PersonalNumber: strings.TrimRight(line2[28:42], "<"),
+	}, nil
+}
+
+// CheckDigit вычисляет контрольную цифру ICAO 9303 с весами 7, 3, 1
+func CheckDigit(data string) int {
+	weights := [3]int{7, 3, 1}
+	sum := 0
+	for i := 0; i < len(data); i++ {
+		value := charValue(data[i])
+		if value < 0 {
+			value = 0
+		}
+		sum += value * weights[i%3]
+	}
+	return sum % 10
+}
+
+// normalize приводит введенный текст к строкам MRZ: убирает пробелы,
+// переводит в верхний регистр и заменяет похожие на заполнитель символы
+func normalize(text string) []string {
+	replacer := strings.NewReplacer(" ", "", "\t", "", "\r", "", "«", "<<", "‹", "<")
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.ToUpper(replacer.Replace(line))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// charValue возвращает числовое значение символа MRZ или -1 для недопустимых символов
+func charValue(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0')
+	case c >= 'A' && c <= 'Z':
+		return int(c-'A') + 10
+	case c == '<':
+		return 0
+	default:
+		return -1
+	}
+}
+
+// verify сверяет контрольную цифру поля
+func verify(field, data string, digit byte) error {
+	expected := CheckDigit(data)
+	if digit < '0' || digit > '9' || int(digit-'0') != expected {
+		return &CheckDigitError{Field: field, Expected: expected, Actual: digit}
+	}
+	return nil
+}
+
+// parseDate разбирает дату YYMMDD, определяя век через century
+func parseDate(value string, century func(year int) int) (time.Time, error) {
+	var year, month, day int
+	if _, err := fmt.Sscanf(value, "%02d%02d%02d", &year, &month, &day); err != nil {
+		return time.Time{}, ErrInvalidDate
+	}
+	date := time.Date(century(year), time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if date.Month() != time.Month(month) || date.Day() != day {
+		return time.Time{}, ErrInvalidDate
+	}
+	return date, nil
+}
+
+// parseName разделяет поле имени на фамилию и имена
+func parseName(field string) (string, string) {
+	field = strings.TrimRight(field, "<")
+	parts := strings.SplitN(field, "<<", 2)
+	surname := strings.ReplaceAll(parts[0], "<", " ")
+	givenNames := ""
+	if len(parts) == 2 {
+		givenNames = strings.TrimSpace(strings.ReplaceAll(parts[1], "<", " "))
+	}
+	return strings.TrimSpace(surname), givenNames
+}
@@ -0,0 +1,204 @@
+package mrz
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Образец паспорта из ICAO 9303, часть 4
+const (
+	specimenLine1 = "P<UTOERIKSSON<<ANNA<MARIA<<<<<<<<<<<<<<<<<<<"
+	specimenLine2 = "L898902C36UTO7408122F1204159ZE184226B<<<<<10"
+)
+
+// now дата, относительно которой определяется век двузначных годов
+var now = time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+
+// line2 собирает вторую строку MRZ с верными контрольными цифрами
+func line2(document, birth, expiry, personal string) string {
+	digit := func(data string) string { return strconv.Itoa(CheckDigit(data)) }
+	personalDigit := digit(personal)
+	if strings.Trim(personal, "<") == "" {
+		personalDigit = "<"
+	}
+	line := document + digit(document) + "UTO" + birth + digit(birth) + "F" +
+		expiry + digit(expiry) + personal + personalDigit
+	return line + digit(line[0:10]+line[13:20]+line[21:43])
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestCheckDigit(t *testing.T) {
+	for _, tc := range []struct {
+		data string
+		want int
+	}{
+		{"L898902C3", 6},
+		{"740812", 2},
+		{"120415", 9},
+		{"ZE184226B<<<<<", 1},
+		{"D23145890", 7},
+		{"AB2134<<<", 5},
+		{"<<<<<<<<<", 0},
+		{"", 0},
+		// Композитная цифра образца: line2[0:10] + line2[13:20] + line2[21:43]
+		{"L898902C36" + "7408122" + "1204159ZE184226B<<<<<1", 0},
+	} {
+		if got := CheckDigit(tc.data); got != tc.want {
+			t.Errorf("CheckDigit(%q) = %d, want %d", tc.data, got, tc.want)
+		}
+	}
+}
+
+func TestParseSpecimen(t *testing.T) {
+	// Ввод с пробелами, в нижнем регистре и с типографскими кавычками
+	text := " p<utoeriksson«anna‹maria<<<<<<<<<<<<<<<<<<< \r\n\n" + specimenLine2 + "\n"
+	passport, err := Parse(text, now)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	want := Passport{
+		DocumentCode:   "P",
+		IssuingCountry: "UTO",
+		Surname:        "ERIKSSON",
+		GivenNames:     "ANNA MARIA",
+		DocumentNumber: "L898902C3",
+		Nationality:    "UTO",
+		BirthDate:      date(1974, time.August, 12),
+		Sex:            "F",
+		ExpiryDate:     date(2012, time.April, 15),
+		PersonalNumber: "ZE184226B",
+	}
+	if *passport != want {
+		t.Fatalf("passport = %+v, want %+v", *passport, want)
+	}
+}
+
+func TestParseCenturies(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		birth      string
+		expiry     string
+		wantBirth  time.Time
+		wantExpiry time.Time
+	}{
+		{"birth this year", "260101", "300101", date(2026, time.January, 1), date(2030, time.January, 1)},
+		{"birth next year is last century", "270101", "300101", date(1927, time.January, 1), date(2030, time.January, 1)},
+		{"birth in 2000", "000229", "300101", date(2000, time.February, 29), date(2030, time.January, 1)},
+		{"expiry at the horizon", "800101", "761231", date(1980, time.January, 1), date(2076, time.December, 31)},
+		{"expiry past the horizon is last century", "800101", "770101", date(1980, time.January, 1), date(1977, time.January, 1)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			passport, err := Parse(specimenLine1+"\n"+line2("L898902C3", tc.birth, tc.expiry, "ZE184226B<<<<<"), now)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !passport.BirthDate.Equal(tc.wantBirth) {
+				t.Errorf("birth date = %s, want %s", passport.BirthDate, tc.wantBirth)
+			}
+			if !passport.ExpiryDate.Equal(tc.wantExpiry) {
+				t.Errorf("expiry date = %s, want %s", passport.ExpiryDate, tc.wantExpiry)
+			}
+		})
+	}
+}
+
+func TestParseFillerPersonalNumber(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		digit byte
+	}{
+		{"filler check digit", '<'},
+		{"zero check digit", '0'},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			line := []byte(line2("L898902C3", "740812", "120415", "<<<<<<<<<<<<<<"))
+			line[42] = tc.digit
+			composite := string(line[0:10]) + string(line[13:20]) + string(line[21:43])
+			line[43] = byte('0' + CheckDigit(composite))
+
+			passport, err := Parse(specimenLine1+"\n"+string(line), now)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if passport.PersonalNumber != "" {
+				t.Errorf("personal number = %q, want empty", passport.PersonalNumber)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	// replace заменяет символ второй строки образца
+	replace := func(i int, c byte) string {
+		line := []byte(specimenLine2)
+		line[i] = c
+		return specimenLine1 + "\n" + string(line)
+	}
+	// replaceWithComposite заменяет символ и пересчитывает композитную цифру
+	replaceWithComposite := func(i int, c byte) string {
+		line := []byte(specimenLine2)
+		line[i] = c
+		line[43] = byte('0' + CheckDigit(string(line[0:10])+string(line[13:20])+string(line[21:43])))
+		return specimenLine1 + "\n" + string(line)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		text      string
+		wantErr   error
+		wantField string
+	}{
+		{"one line", specimenLine1, ErrLineCount, ""},
+		{"three lines", specimenLine1 + "\n" + specimenLine2 + "\n" + specimenLine2, ErrLineCount, ""},
+		{"short line", specimenLine1 + "\n" + specimenLine2[:43], ErrLineLength, ""},
+		{"long line", specimenLine1 + "<\n" + specimenLine2, ErrLineLength, ""},
+		{"td1 lines", strings.Repeat("<", 30) + "\n" + strings.Repeat("<", 30), ErrLineLength, ""},
+		{"invalid character", replace(37, '-'), ErrInvalidCharacter, ""},
+		{"not a passport", "I" + specimenLine1[1:] + "\n" + specimenLine2, ErrNotPassport, ""},
+		{"document number digit", replace(9, '7'), nil, FieldDocumentNumber},
+		{"document number digit is filler", replace(9, '<'), nil, FieldDocumentNumber},
+		{"document number changed", replace(0, 'M'), nil, FieldDocumentNumber},
+		{"birth date digit", replace(19, '3'), nil, FieldBirthDate},
+		{"expiry date digit", replace(27, '0'), nil, FieldExpiryDate},
+		{"personal number digit", replaceWithComposite(42, '2'), nil, FieldPersonalNumber},
+		{"personal number digit is filler", replaceWithComposite(42, '<'), nil, FieldPersonalNumber},
+		{"composite digit", replace(43, '1'), nil, FieldComposite},
+		{"composite digit is letter", replace(43, 'A'), nil, FieldComposite},
+		// Поля с верными собственными цифрами, но композитная рассчитана до изменения
+		{"personal number changed", specimenLine1 + "\n" + line2("L898902C3", "740812", "120415", "ZE184226C<<<<<")[:43] + "0", nil, FieldComposite},
+		{"nationality outside composite", replace(10, 'X'), nil, ""},
+		{"invalid day", specimenLine1 + "\n" + line2("L898902C3", "310299", "120415", "ZE184226B<<<<<"), ErrInvalidDate, ""},
+		{"february 30", specimenLine1 + "\n" + line2("L898902C3", "740230", "120415", "ZE184226B<<<<<"), ErrInvalidDate, ""},
+		{"invalid month", specimenLine1 + "\n" + line2("L898902C3", "740812", "121315", "ZE184226B<<<<<"), ErrInvalidDate, ""},
+		{"filler in date", specimenLine1 + "\n" + line2("L898902C3", "7408<<", "120415", "ZE184226B<<<<<"), ErrInvalidDate, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse(tc.text, now)
+			switch {
+			case tc.wantErr != nil:
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tc.wantErr)
+				}
+			case tc.wantField != "":
+				var checkErr *CheckDigitError
+				if !errors.As(err, &checkErr) {
+					t.Fatalf("err = %v, want check digit error", err)
+				}
+				if checkErr.Field != tc.wantField {
+					t.Fatalf("field = %q, want %q", checkErr.Field, tc.wantField)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("err = %v, want nil", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package screening
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Entry запись списка: лицо, его псевдонимы и дата рождения, если известна
+type Entry struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Aliases   []string  `json:"aliases"`
+	BirthDate time.Time `json:"-"`
+	Source    string    `json:"source"`
+}
+
+// jsonEntry формат записи в JSON-файле списка
+type jsonEntry struct {
+	Entry
+	BirthDate string `json:"birth_date"`
+}
+
+// LoadFile загружает список из CSV или JSON файла, формат определяется по расширению.
+//
+// CSV: первая строка — заголовок с колонками id, name, aliases, birth_date, source;
+// псевдонимы разделяются символом «;», дата в формате YYYY-MM-DD.
+// JSON: массив объектов с теми же полями, aliases — массив строк.
+func LoadFile(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open screening list: %w", err)
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return parseCSV(file)
+	case ".json":
+		return parseJSON(file)
+	default:
+		return nil, fmt.Errorf("unsupported screening list format: %s", path)
+	}
+}
+
+// parseCSV разбирает список в формате CSV
+func parseCSV(r io.Reader) ([]Entry, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read screening list header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, errors.New("screening list has no name column")
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var entries []Entry
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read screening list line %d: %w", line, err)
+		}
+
+		entry := Entry{
+			ID:     field(record, "id"),
+			Name:   field(record, "name"),
+			Source: field(record, "source"),
+		}
+		if entry.Name == "" {
+			continue
+		}
+		for _, alias := range strings.Split(field(record, "aliases"), ";") {
+			if alias = strings.TrimSpace(alias); alias != "" {
+				entry.Aliases = append(entry.Aliases, alias)
+			}
+		}
+		if entry.BirthDate, err = parseBirthDate(field(record, "birth_date")); err != nil {
+			return nil, fmt.Errorf("screening list line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// parseJSON разбирает список в формате JSON
+func parseJSON(r io.Reader) ([]Entry, error) {
+	var raw []jsonEntry
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode screening list: %w", err)
+	}
+
+	entries := make([]Entry, 0, len(raw))
+	for i, item := range raw {
+		if strings.TrimSpace(item.Name) == "" {
+			continue
+		}
+		entry := item.Entry
+		birthDate, err := parseBirthDate(item.BirthDate)
+		if err != nil {
+			return nil, fmt.Errorf("screening list entry %d: %w", i, err)
+		}
+		entry.BirthDate = birthDate
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// parseBirthDate разбирает дату рождения YYYY-MM-DD, пустая строка допустима
+func parseBirthDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid birth date %q", value)
+	}
+	return date, nil
+}
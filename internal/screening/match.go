@@ -0,0 +1,220 @@
+package screening
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// cyrillicToLatin транслитерация кириллицы по ICAO 9303 (используется в загранпаспортах РФ)
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "ie", 'ы': "y", 'ь': "", 'э': "e", 'ю': "iu",
+	'я': "ia", 'і': "i", 'ї': "i", 'є': "ie", 'ґ': "g", 'ў': "u",
+}
+
+// latinVariants сводит распространенные варианты латинского написания
+// русских имен к одному виду (Yakovlev/Iakovlev, Khabib/Habib, Tsoi/Coi)
+var latinVariants = strings.NewReplacer(
+	"shch", "sh", "sch", "sh",
+	"ya", "ia", "yu", "iu", "ye", "e", "yo", "e", "ie", "e",
+	"kh", "h", "ts", "c", "tz", "c", "cz", "c",
+	"ks", "x", "w", "v", "ph", "f", "y", "i", "j", "i",
+)
+
+// Match совпадение записи списка с проверяемым лицом
+type Match struct {
+	Entry          Entry
+	Score          float64
+	BirthDateMatch bool
+}
+
+// Normalize приводит имя к каноническому виду для сравнения: нижний регистр,
+// транслитерация кириллицы, сведение вариантов написания и сортировка частей
+func Normalize(name string) []string {
+	var builder strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case cyrillicToLatin[r] != "" || r == 'ь':
+			builder.WriteString(cyrillicToLatin[r])
+		case r >= 'a' && r <= 'z':
+			builder.WriteRune(r)
+		case unicode.IsLetter(r):
+			builder.WriteString(foldLatin(r))
+		default:
+			builder.WriteRune(' ')
+		}
+	}
+
+	tokens := strings.Fields(latinVariants.Replace(builder.String()))
+	sort.Strings(tokens)
+	return tokens
+}
+
+// foldLatin убирает диакритику у распространенных латинских букв
+func foldLatin(r rune) string {
+	switch r {
+	case 'á', 'à', 'â', 'ä', 'ã', 'å', 'ā':
+		return "a"
+	case 'é', 'è', 'ê', 'ë', 'ē', 'ě':
+		return "e"
+	case 'í', 'ì', 'î', 'ï', 'ī':
+		return "i"
+	case 'ó', 'ò', 'ô', 'ö', 'õ', 'ø', 'ō':
+		return "o"
+	case 'ú', 'ù', 'û', 'ü', 'ū', 'ů':
+		return "u"
+	case 'ç', 'č', 'ć':
+		return "c"
+	case 'š', 'ś':
+		return "s"
+	case 'ž', 'ź', 'ż':
+		return "z"
+	case 'ñ', 'ń', 'ň':
+		return "n"
+	case 'ř':
+		return "r"
+	case 'ł':
+		return "l"
+	case 'ý', 'ÿ':
+		return "i"
+	default:
+		return ""
+	}
+}
+
+// NameSimilarity оценивает сходство двух имен от 0 до 1 без учета порядка частей
+func NameSimilarity(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	wholeScore := jaroWinkler(strings.Join(a, " "), strings.Join(b, " "))
+
+	// Каждой части более короткого имени ищем лучшую пару в более длинном,
+	// так что отсутствие отчества не снижает оценку. Имя из одной части
+	// так не сравнивается, иначе оно совпадало бы с любым однофамильцем.
+	short, long := a, b
+	if len(short) > len(long) {
+		short, long = long, short
+	}
+	if len(short) < 2 {
+		return wholeScore
+	}
+	total := 0.0
+	for _, token := range short {
+		best := 0.0
+		for _, candidate := range long {
+			if score := jaroWinkler(token, candidate); score > best {
+				best = score
+			}
+		}
+		total += best
+	}
+	tokenScore := total / float64(len(short))
+
+	if wholeScore > tokenScore {
+		return wholeScore
+	}
+	return tokenScore
+}
+
+// Screen сравнивает имя и дату рождения со списком и возвращает совпадения
+// с оценкой не ниже threshold, отсортированные по убыванию оценки
+func Screen(entries []Entry, name string, birthDate time.Time, threshold float64) []Match {
+	tokens := Normalize(name)
+
+	var matches []Match
+	for _, entry := range entries {
+		score := 0.0
+		for _, candidate := range append([]string{entry.Name}, entry.Aliases...) {
+			if s := NameSimilarity(tokens, Normalize(candidate)); s > score {
+				score = s
+			}
+		}
+
+		birthDateMatch := false
+		if !entry.BirthDate.IsZero() && !birthDate.IsZero() {
+			if sameDate(entry.BirthDate, birthDate) {
+				birthDateMatch = true
+				score += (1 - score) / 2
+			} else {
+				// Разные даты рождения делают совпадение по имени маловероятным
+				score *= 0.8
+			}
+		}
+
+		if score >= threshold {
+			matches = append(matches, Match{Entry: entry, Score: score, BirthDateMatch: birthDateMatch})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	return matches
+}
+
+// sameDate сравнивает даты без учета времени
+func sameDate(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.Month() == b.Month() && a.Day() == b.Day()
+}
+
+// jaroWinkler вычисляет сходство Джаро — Винклера двух строк
+func jaroWinkler(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+
+	window := max(len(ra), len(rb))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+
+	matchedA := make([]bool, len(ra))
+	matchedB := make([]bool, len(rb))
+	matches := 0
+	for i := range ra {
+		start := max(0, i-window)
+		end := min(len(rb), i+window+1)
+		for j := start; j < end; j++ {
+			if matchedB[j] || ra[i] != rb[j] {
+				continue
+			}
+			matchedA[i], matchedB[j] = true, true
+			matches++
+			break
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions := 0
+	j := 0
+	for i := range ra {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if ra[i] != rb[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(ra)) + m/float64(len(rb)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < min(4, len(ra), len(rb)) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
@@ -0,0 +1,140 @@
+package screening
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// threshold порог сходства по умолчанию (SCREENING_THRESHOLD)
+const threshold = 0.88
+
+func TestNormalize(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		want string
+	}{
+		{"Ivan Petrov", "ivan petrov"},
+		{"PETROV, Ivan-Sergeevich", "ivan petrov sergeevich"},
+		{"  Petrov   Ivan  ", "ivan petrov"},
+		{"Иван Петров", "ivan petrov"},
+		{"Щукин Артём", "artem shukin"},
+		{"Хабиб Цой", "coi habib"},
+		{"Юлия Яковлева", "iakovleva iuliia"},
+		{"Наталья Подъячева", "natalia podeiacheva"},
+		{"Khabib Tsoi", "coi habib"},
+		{"Yulia Yakovleva", "iakovleva iulia"},
+		{"Shchukin Sergey", "sergei shukin"},
+		{"José Müller", "iose muller"},
+		{"Łukasz Dvořák", "dvorak lukasz"},
+		{"О'Брайен 007", "braen o"},
+		{"", ""},
+	} {
+		if got := strings.Join(Normalize(tc.name), " "); got != tc.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestJaroWinkler(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want float64
+	}{
+		{"martha", "marhta", 0.9611},
+		{"dwayne", "duane", 0.84},
+		{"dixon", "dicksonx", 0.8133},
+		{"petrov", "petrov", 1},
+		{"abc", "xyz", 0},
+		{"", "petrov", 0},
+	} {
+		if got := jaroWinkler(tc.a, tc.b); math.Abs(got-tc.want) > 0.0001 {
+			t.Errorf("jaroWinkler(%q, %q) = %.4f, want %.4f", tc.a, tc.b, got, tc.want)
+		}
+		if got, reverse := jaroWinkler(tc.a, tc.b), jaroWinkler(tc.b, tc.a); got != reverse {
+			t.Errorf("jaroWinkler(%q, %q) is not symmetric: %.4f and %.4f", tc.a, tc.b, got, reverse)
+		}
+	}
+}
+
+func TestNameSimilarity(t *testing.T) {
+	for _, tc := range []struct {
+		a, b  string
+		match bool
+	}{
+		// Транслитерация и варианты латинского написания
+		{"Иван Петров", "Ivan Petrov", true},
+		{"Яковлев Алексей", "Alexey Yakovlev", true},
+		{"Хабиб Нурмагомедов", "Khabib Nurmagomedov", true},
+		{"Виктор Цой", "Viktor Tsoi", true},
+		{"Sergei Ivanov", "Sergey Ivanov", true},
+		{"Dmitry Ivanov", "Dmitrii Ivanov", true},
+		{"Юлия Яковлева", "Yulia Yakovleva", true},
+		// Порядок частей и отчество
+		{"PETROV IVAN SERGEEVICH", "Ivan Petrov", true},
+		{"Ivan Petrov", "Petrov Ivan", true},
+		// Близкие написания
+		{"Ivan Petrov", "Ivan Petrova", true},
+		{"John Smith", "Jon Smith", true},
+		{"Ivan Petrov", "Ivan Petrenko", true},
+		// Чуть ниже порога
+		{"Ivan Petrov", "Ivan Pavlov", false},
+		{"Ivan Petrov", "Igor Petrov", false},
+		// Однофамилец без имени и другое лицо
+		{"Ivan Petrov", "Petrov", false},
+		{"Ivan Petrov", "Oleg Sidorov", false},
+		{"Ivan Petrov", "", false},
+	} {
+		score := NameSimilarity(Normalize(tc.a), Normalize(tc.b))
+		if got := score >= threshold; got != tc.match {
+			t.Errorf("NameSimilarity(%q, %q) = %.4f, match = %t, want %t", tc.a, tc.b, score, got, tc.match)
+		}
+	}
+}
+
+func TestScreen(t *testing.T) {
+	birthDate := time.Date(1980, time.May, 1, 0, 0, 0, 0, time.UTC)
+	otherDate := time.Date(1985, time.May, 1, 0, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{ID: "1", Name: "Ivan Pavlov", BirthDate: birthDate},
+		{ID: "2", Name: "Peter Parker", BirthDate: birthDate},
+		{ID: "3", Name: "Oleg Sidorov", Aliases: []string{"Иван Петров"}},
+	}
+
+	for _, tc := range []struct {
+		name      string
+		person    string
+		birthDate time.Time
+		threshold float64
+		want      []string
+	}{
+		// Сходство имен Ivan Petrov и Ivan Pavlov 0.866: ниже порога без даты
+		// рождения и выше при совпадении даты
+		{"name below threshold", "Ivan Petrov", time.Time{}, threshold, []string{"3"}},
+		{"birth date lifts above threshold", "Ivan Petrov", birthDate, threshold, []string{"3", "1"}},
+		// Сходство Petra Parkes и Peter Parker 0.92: выше порога, пока дата
+		// рождения не расходится
+		{"name above threshold", "Petra Parkes", time.Time{}, threshold, []string{"2"}},
+		{"other birth date drops below threshold", "Petra Parkes", otherDate, threshold, nil},
+		{"alias in cyrillic", "PETROV IVAN", otherDate, threshold, []string{"3"}},
+		{"threshold is inclusive", "Ivan Petrov", time.Time{}, NameSimilarity(Normalize("Ivan Petrov"), Normalize("Ivan Pavlov")), []string{"3", "1"}},
+		{"no matches", "Anna Smith", birthDate, threshold, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, match := range Screen(entries, tc.person, tc.birthDate, tc.threshold) {
+				got = append(got, match.Entry.ID)
+				if match.Score < tc.threshold || match.Score > 1 {
+					t.Errorf("entry %s: score = %.4f", match.Entry.ID, match.Score)
+				}
+				if match.BirthDateMatch != (!match.Entry.BirthDate.IsZero() && match.Entry.BirthDate.Equal(tc.birthDate)) {
+					t.Errorf("entry %s: birth date match = %t", match.Entry.ID, match.BirthDateMatch)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Fatalf("matches = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Ошибки API, которые обработчики проверяют через errors.Is
+var (
+	ErrChannelAlreadyAdded = errors.New("channel is already added")
+	ErrChannelNotFound     = errors.New("channel not found")
+	ErrUserNotFound        = errors.New("user not found")
+	ErrValidation          = errors.New("validation failed")
+	ErrUnauthorized        = errors.New("request is not authorized")
+	// ErrDuplicateRequest означает, что API уже выполнило запрос с этим ключом идемпотентности
+	ErrDuplicateRequest = errors.New("duplicate request")
+)
+
+// Коды ошибок из тела ответа API
+const (
+	ErrorCodeChannelAlreadyAdded = "channel_already_added"
+	ErrorCodeChannelNotFound     = "channel_not_found"
+	ErrorCodeUserNotFound        = "user_not_found"
+	ErrorCodeValidation          = "validation_error"
+	ErrorCodeUnauthorized        = "unauthorized"
+	ErrorCodeDuplicateRequest    = "duplicate_request"
+)
+
+// errorsByCode сопоставляет коды ошибок API типизированным ошибкам
+var errorsByCode = map[string]error{
+	ErrorCodeChannelAlreadyAdded: ErrChannelAlreadyAdded,
+	ErrorCodeChannelNotFound:     ErrChannelNotFound,
+	ErrorCodeUserNotFound:        ErrUserNotFound,
+	ErrorCodeValidation:          ErrValidation,
+	ErrorCodeUnauthorized:        ErrUnauthorized,
+	ErrorCodeDuplicateRequest:    ErrDuplicateRequest,
+}
+
+// ErrorDetail уточнение ошибки API, например поле, не прошедшее валидацию
+type ErrorDetail struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// errorEnvelope тело ответа API с ошибкой
+type errorEnvelope struct {
+	Code    string        `json:"code"`
+	Message string        `json:"message"`
+	Details []ErrorDetail `json:"details"`
+}
+
+// StatusError ответ API с неуспешным HTTP статусом. Если API вернуло тело
+// с ошибкой, заполняются Code, Message и Details.
+type StatusError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Details    []ErrorDetail
+	Body       []byte
+	RetryAfter time.Duration // задержка из заголовка Retry-After, если он был
+}
+
+// newStatusError разбирает тело ответа с ошибкой
+func newStatusError(statusCode int, body []byte, retryAfter time.Duration) *StatusError {
+	statusErr := &StatusError{StatusCode: statusCode, Body: body, RetryAfter: retryAfter}
+
+	var envelope errorEnvelope
+	if err := json.Unmarshal(body, &envelope); err == nil {
+		statusErr.Code = envelope.Code
+		statusErr.Message = envelope.Message
+		statusErr.Details = envelope.Details
+	}
+	return statusErr
+}
+
+func (e *StatusError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("API returned non-200 status: %d", e.StatusCode)
+	}
+	if e.Message == "" {
+		return fmt.Sprintf("API error %s (status %d)", e.Code, e.StatusCode)
+	}
+	return fmt.Sprintf("API error %s (status %d): %s", e.Code, e.StatusCode, e.Message)
+}
+
+// Is сопоставляет ответ API типизированной ошибке по коду,
+// а без кода — ответы 401 и 403 ошибке ErrUnauthorized
+func (e *StatusError) Is(target error) bool {
+	if typed, ok := errorsByCode[e.Code]; ok {
+		return typed == target
+	}
+	if e.Code == "" && (e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden) {
+		return target == ErrUnauthorized
+	}
+	return false
+}
+
+// ValidationDetails возвращает описание ошибок валидации из ответа API
+// в виде строк «поле: сообщение» или nil, если это не ошибка валидации
+func ValidationDetails(err error) []string {
+	var statusErr *StatusError
+	if !errors.Is(err, ErrValidation) || !errors.As(err, &statusErr) {
+		return nil
+	}
+
+	details := make([]string, 0, len(statusErr.Details)+1)
+	if len(statusErr.Details) == 0 && statusErr.Message != "" {
+		details = append(details, statusErr.Message)
+	}
+	for _, detail := range statusErr.Details {
+		message := strings.TrimSpace(detail.Message)
+		if detail.Field != "" {
+			message = detail.Field + ": " + message
+		}
+		details = append(details, message)
+	}
+	return details
+}
@@ -1,109 +1,705 @@
 package services
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 	"tribute-chatbot/internal/config"
 	"tribute-chatbot/internal/logger"
+	"tribute-chatbot/internal/models"
+	"tribute-chatbot/internal/signing"
 )
 
+// maxResponseSize ограничивает размер читаемого ответа API
+const maxResponseSize = 1 << 20
+
 // APIService сервис для работы с API
 type APIService struct {
-	client *http.Client
-	config *config.Config
-	logger logger.Logger
+	client  *http.Client
+	breaker *CircuitBreaker
+	config  *config.Config
+	logger  logger.Logger
 }
 
+// Проверяем, что APIService реализует BackendClient
+var _ BackendClient = (*APIService)(nil)
+
 // NewAPIService создает новый API сервис
-func NewAPIService(cfg *config.Config) *APIService {
-	return &APIService{
-		client: &http.Client{Timeout: 10 * time.Second},
-		config: cfg,
-		logger: logger.New(),
+func NewAPIService(cfg *config.Config) (*APIService, error) {
+	transport, err := newTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &APIService{
+		// Таймаут каждой попытки задается через context в doJSON
+		client:  &http.Client{Transport: transport},
+		breaker: NewCircuitBreaker(cfg.BackendBreakerFailureThreshold, cfg.BackendBreakerOpenTimeout, cfg.BackendBreakerHalfOpenSuccesses),
+		config:  cfg,
+		logger:  logger.New(),
+	}
+	if cfg.BackendAuthScheme == config.BackendAuthNone {
+		s.logger.Warn("Backend requests are not authenticated, set BACKEND_AUTH_SCHEME")
+	}
+	s.breaker.OnStateChange(func(from, to CircuitState) {
+		s.logger.Warn(fmt.Sprintf("Backend circuit breaker: %s -> %s", from, to))
+	})
+	return s, nil
+}
+
+// Available сообщает, принимает ли выключатель запросы к API
+func (s *APIService) Available() bool {
+	return s.breaker.State() != CircuitOpen
+}
+
+// OnCircuitChange добавляет обработчик смены состояния выключателя
+func (s *APIService) OnCircuitChange(fn func(from, to CircuitState)) {
+	s.breaker.OnStateChange(fn)
+}
+
+// newTransport создает HTTP транспорт, при необходимости с клиентским сертификатом
+func newTransport(cfg *config.Config) (http.RoundTripper, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.BackendTLSCertFile == "" && cfg.BackendTLSCAFile == "" {
+		return transport, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.BackendTLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.BackendTLSCertFile, cfg.BackendTLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if cfg.BackendTLSCAFile != "" {
+		caPEM, err := os.ReadFile(cfg.BackendTLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.BackendTLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// authenticate добавляет к запросу учетные данные согласно настроенной схеме
+func (s *APIService) authenticate(req *http.Request, body []byte) error {
+	switch s.config.BackendAuthScheme {
+	case config.BackendAuthBearer:
+		req.Header.Set("Authorization", "Bearer "+s.config.BackendAuthToken)
+	case config.BackendAuthHMAC:
+		return signing.Sign(req, body, s.config.BackendHMACKeyID, []byte(s.config.BackendHMACSecret), time.Now())
 	}
+	return nil
 }
 
-// UpdateUserVerification обновляет статус верификации пользователя
-func (s *APIService) UpdateUserVerification(userID int64, isVerified bool) error {
+// UpdateUserVerification обновляет статус верификации пользователя.
+// details передает данные из MRZ и результат проверки правил, если они есть.
+func (s *APIService) UpdateUserVerification(ctx context.Context, userID int64, isVerified bool, details *models.VerificationDetails) error {
 	payload := map[string]interface{}{
 		"userId":        userID,
 		"isVerificated": isVerified,
 	}
+	if details != nil {
+		payload["identity"] = identityPayload(details.Identity)
+		if details.Policy != nil {
+			payload["policy"] = policyPayload(details.Policy)
+		}
+		if details.Screening != nil {
+			payload["screening"] = screeningPayload(details.Screening)
+		}
+	}
 
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
+	_, err := s.doJSON(ctx, http.MethodPost, "/v1/check-verified-passport", payload)
+	return err
+}
+
+// AddBotToChannel добавляет бота в канал
+func (s *APIService) AddBotToChannel(ctx context.Context, registration models.ChannelRegistration) error {
+	ctx, log := s.requestContext(ctx, "/v1/add-bot")
+	log.Info(fmt.Sprintf("AddBotToChannel called with: userID=%d, chatID=%d, type=%s, channelTitle='%s', channelUsername='%s'",
+		registration.UserID, registration.ChatID, registration.ChatType, registration.ChannelTitle, registration.ChannelUsername))
+
+	payload := map[string]interface{}{
+		"user_id":          registration.UserID,
+		"channel_id":       registration.ChatID,
+		"chat_type":        registration.ChatType,
+		"channel_title":    registration.ChannelTitle,
+		"channel_username": registration.ChannelUsername,
+		"description":      registration.Description,
+		"photo_file_id":    registration.PhotoFileID,
+		"photo_unique_id":  registration.PhotoUniqueID,
 	}
 
-	apiURL := strings.TrimRight(s.config.APIBaseURL, "/") + "/v1/check-verified-passport"
-	req, err := http.NewRequest("POST", apiURL, strings.NewReader(string(body)))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+	_, err := s.doJSON(ctx, http.MethodPost, "/v1/add-bot", payload)
+	return err
+}
+
+// UpdateChannel обновляет название, username и метаданные канала
+func (s *APIService) UpdateChannel(ctx context.Context, update models.ChannelUpdate) error {
+	payload := map[string]interface{}{
+		"user_id":          update.UserID,
+		"channel_id":       update.ChatID,
+		"channel_title":    update.ChannelTitle,
+		"channel_username": update.ChannelUsername,
+		"description":      update.Description,
+		"photo_file_id":    update.PhotoFileID,
+		"photo_unique_id":  update.PhotoUniqueID,
+		"updated_at":       update.UpdatedAt.UTC().Format(time.RFC3339),
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	_, err := s.doJSON(ctx, http.MethodPost, "/v1/update-channel", payload)
+	return err
+}
+
+// DeactivateChannel отключает канал: подписки на него приостанавливаются,
+// пока бот снова не станет администратором
+func (s *APIService) DeactivateChannel(ctx context.Context, deactivation models.ChannelDeactivation) error {
+	payload := map[string]interface{}{
+		"user_id":          deactivation.UserID,
+		"channel_id":       deactivation.ChatID,
+		"channel_title":    deactivation.ChannelTitle,
+		"channel_username": deactivation.ChannelUsername,
+		"reason":           deactivation.Reason,
+	}
 
-	s.logger.Info("Sending API request to:", apiURL)
+	_, err := s.doJSON(ctx, http.MethodPost, "/v1/deactivate-channel", payload)
+	return err
+}
 
-	resp, err := s.client.Do(req)
+// ReportChannelPermissions передает результат проверки прав бота в канале
+func (s *APIService) ReportChannelPermissions(ctx context.Context, report models.ChannelPermissionReport) error {
+	missing := report.Missing
+	if missing == nil {
+		missing = []string{}
+	}
+	payload := map[string]interface{}{
+		"user_id":          report.UserID,
+		"channel_id":       report.ChatID,
+		"channel_title":    report.ChannelTitle,
+		"channel_username": report.ChannelUsername,
+		"missing":          missing,
+		"ok":               len(missing) == 0,
+		"checked_at":       report.CheckedAt.UTC().Format(time.RFC3339),
+	}
+
+	_, err := s.doJSON(ctx, http.MethodPost, "/v1/channel-permissions", payload)
+	return err
+}
+
+// ReportInviteLink отправляет статус персональной ссылки подписчика в канал
+func (s *APIService) ReportInviteLink(ctx context.Context, link models.InviteLink) error {
+	payload := map[string]interface{}{
+		"name":          link.Name,
+		"link":          link.Link,
+		"channel_id":    link.ChatID,
+		"subscriber_id": link.SubscriberID,
+		"status":        link.Status(),
+		"created_at":    link.CreatedAt.UTC().Format(time.RFC3339),
+		"expires_at":    link.ExpiresAt.UTC().Format(time.RFC3339),
+	}
+	if link.UsedBy != 0 {
+		payload["used_by"] = link.UsedBy
+		payload["used_at"] = link.UsedAt.UTC().Format(time.RFC3339)
+	}
+
+	_, err := s.doJSON(ctx, http.MethodPost, "/v1/invite-links", payload)
+	return err
+}
+
+// ListExpiredSubscriptions получает истекшие подписки, подписчиков которых нужно удалить из каналов
+func (s *APIService) ListExpiredSubscriptions(ctx context.Context) ([]models.ExpiredSubscription, error) {
+	respBody, err := s.doJSON(ctx, http.MethodGet, "/v1/expired-subscriptions", nil)
 	if err != nil {
-		return fmt.Errorf("API request failed: %w", err)
+		return nil, err
+	}
+
+	var resp struct {
+		Subscriptions []struct {
+			ChannelID    int64     `json:"channel_id"`
+			ChannelTitle string    `json:"channel_title"`
+			SubscriberID int64     `json:"subscriber_id"`
+			ExpiredAt    time.Time `json:"expired_at"`
+			RenewURL     string    `json:"renew_url"`
+		} `json:"subscriptions"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode expired subscriptions: %w", err)
 	}
-	defer resp.Body.Close()
 
-	s.logger.Info("API response status:", resp.StatusCode)
+	subscriptions := make([]models.ExpiredSubscription, 0, len(resp.Subscriptions))
+	for _, subscription := range resp.Subscriptions {
+		subscriptions = append(subscriptions, models.ExpiredSubscription{
+			ChatID:       subscription.ChannelID,
+			ChannelTitle: subscription.ChannelTitle,
+			SubscriberID: subscription.SubscriberID,
+			ExpiredAt:    subscription.ExpiredAt,
+			RenewURL:     subscription.RenewURL,
+		})
+	}
+	return subscriptions, nil
+}
 
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("API returned non-200 status: %d", resp.StatusCode)
+// ReportSubscriptionRemoval сообщает об удалении подписчика с истекшей подпиской из канала
+func (s *APIService) ReportSubscriptionRemoval(ctx context.Context, subscription models.ExpiredSubscription) error {
+	payload := map[string]interface{}{
+		"channel_id":    subscription.ChatID,
+		"subscriber_id": subscription.SubscriberID,
+		"expired_at":    subscription.ExpiredAt.UTC().Format(time.RFC3339),
+		"notified_at":   subscription.NotifiedAt.UTC().Format(time.RFC3339),
+		"removed_at":    subscription.RemovedAt.UTC().Format(time.RFC3339),
 	}
 
-	return nil
+	_, err := s.doJSON(ctx, http.MethodPost, "/v1/subscription-removals", payload)
+	return err
 }
 
-// AddBotToChannel добавляет бота в канал
-func (s *APIService) AddBotToChannel(userID int64, channelTitle, channelUsername string) error {
-	s.logger.Info(fmt.Sprintf("AddBotToChannel called with: userID=%d, channelTitle='%s', channelUsername='%s'", userID, channelTitle, channelUsername))
+// channelResponse канал в ответах API
+type channelResponse struct {
+	ChatID   int64  `json:"chat_id"`
+	UserID   int64  `json:"user_id"`
+	Title    string `json:"title"`
+	Username string `json:"username"`
+	Active   bool   `json:"active"`
+}
+
+// summary переводит канал из ответа API в модель
+func (c channelResponse) summary() models.ChannelSummary {
+	return models.ChannelSummary{
+		ChatID:   c.ChatID,
+		UserID:   c.UserID,
+		Title:    c.Title,
+		Username: c.Username,
+		Active:   c.Active,
+	}
+}
+
+// ListChannels получает список всех подключенных каналов
+func (s *APIService) ListChannels(ctx context.Context) ([]models.ChannelSummary, error) {
+	respBody, err := s.doJSON(ctx, http.MethodGet, "/v1/channels", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Channels []channelResponse `json:"channels"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode channel list: %w", err)
+	}
 
+	channels := make([]models.ChannelSummary, 0, len(resp.Channels))
+	for _, channel := range resp.Channels {
+		channels = append(channels, channel.summary())
+	}
+	return channels, nil
+}
+
+// ReportReconciliation отправляет расхождения, найденные при сверке каналов
+func (s *APIService) ReportReconciliation(ctx context.Context, reconciliation models.ChannelReconciliation) error {
+	discrepancies := make([]map[string]interface{}, 0, len(reconciliation.Discrepancies))
+	for _, d := range reconciliation.Discrepancies {
+		discrepancies = append(discrepancies, map[string]interface{}{
+			"channel_id":       d.ChatID,
+			"user_id":          d.UserID,
+			"channel_title":    d.ChannelTitle,
+			"channel_username": d.ChannelUsername,
+			"kind":             d.Kind,
+			"bot_status":       d.BotStatus,
+		})
+	}
 	payload := map[string]interface{}{
-		"user_id":          userID,
-		"channel_title":    channelTitle,
-		"channel_username": channelUsername,
+		"checked":       reconciliation.Checked,
+		"failed":        reconciliation.Failed,
+		"discrepancies": discrepancies,
+		"checked_at":    reconciliation.CheckedAt.UTC().Format(time.RFC3339),
+	}
+
+	_, err := s.doJSON(ctx, http.MethodPost, "/v1/channel-reconciliation", payload)
+	return err
+}
+
+// userResponse ответ API с профилем пользователя
+type userResponse struct {
+	UserID     int64             `json:"user_id"`
+	IsVerified bool              `json:"is_verified"`
+	IsCreator  bool              `json:"is_creator"`
+	Channels   []channelResponse `json:"channels"`
+}
+
+// GetChannelAccess получает правило вступления в канал и статус подписки пользователя
+func (s *APIService) GetChannelAccess(ctx context.Context, chatID, userID int64) (models.ChannelAccess, error) {
+	path := fmt.Sprintf("/v1/channel-access?channel_id=%d&user_id=%d", chatID, userID)
+	respBody, err := s.doJSON(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return models.ChannelAccess{}, err
+	}
+
+	var resp struct {
+		ChannelID       int64  `json:"channel_id"`
+		UserID          int64  `json:"user_id"`
+		Policy          string `json:"policy"`
+		HasSubscription bool   `json:"has_subscription"`
+		IsVerified      bool   `json:"is_verified"`
+		SubscribeURL    string `json:"subscribe_url"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return models.ChannelAccess{}, fmt.Errorf("failed to decode channel access: %w", err)
+	}
+
+	return models.ChannelAccess{
+		ChatID:          chatID,
+		UserID:          userID,
+		Policy:          resp.Policy,
+		HasSubscription: resp.HasSubscription,
+		IsVerified:      resp.IsVerified,
+		SubscribeURL:    resp.SubscribeURL,
+	}, nil
+}
+
+// GetChannelSettings получает настройки кнопок под постами канала
+func (s *APIService) GetChannelSettings(ctx context.Context, chatID int64) (models.ChannelSettings, error) {
+	respBody, err := s.doJSON(ctx, http.MethodGet, fmt.Sprintf("/v1/channel-settings?channel_id=%d", chatID), nil)
+	if err != nil {
+		return models.ChannelSettings{}, err
 	}
 
-	body, err := json.Marshal(payload)
+	var resp struct {
+		ChannelID   int64 `json:"channel_id"`
+		PostButtons struct {
+			Tip            bool   `json:"tip"`
+			Subscribe      bool   `json:"subscribe"`
+			ExcludeHashtag string `json:"exclude_hashtag"`
+		} `json:"post_buttons"`
+		TipURL string `json:"tip_url"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return models.ChannelSettings{}, fmt.Errorf("failed to decode channel settings: %w", err)
+	}
+
+	return models.ChannelSettings{
+		ChatID:          chatID,
+		TipButton:       resp.PostButtons.Tip,
+		SubscribeButton: resp.PostButtons.Subscribe,
+		ExcludeHashtag:  resp.PostButtons.ExcludeHashtag,
+		TipURL:          resp.TipURL,
+	}, nil
+}
+
+// GetUser получает профиль пользователя
+func (s *APIService) GetUser(ctx context.Context, userID int64) (*models.UserProfile, error) {
+	respBody, err := s.doJSON(ctx, http.MethodGet, fmt.Sprintf("/v1/users/%d", userID), nil)
 	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound && statusErr.Code == "" {
+			return nil, fmt.Errorf("%w: %v", ErrUserNotFound, err)
+		}
+		return nil, err
+	}
+
+	var resp userResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode user profile: %w", err)
+	}
+
+	profile := &models.UserProfile{
+		UserID:     resp.UserID,
+		IsVerified: resp.IsVerified,
+		IsCreator:  resp.IsCreator,
+	}
+	for _, channel := range resp.Channels {
+		summary := channel.summary()
+		if summary.UserID == 0 {
+			summary.UserID = resp.UserID
+		}
+		profile.Channels = append(profile.Channels, summary)
 	}
+	return profile, nil
+}
+
+// doJSON отправляет JSON-запрос к API и возвращает тело успешного ответа.
+// Сетевые ошибки и ответы 5xx/429 повторяются с экспоненциальной задержкой,
+// но неидемпотентные запросы — только при наличии ключа идемпотентности.
+// Ответ API о том, что запрос с этим ключом уже выполнен, считается успехом.
+func (s *APIService) doJSON(ctx context.Context, method, path string, payload interface{}) ([]byte, error) {
+	var body []byte
+	if payload != nil {
+		var err error
+		body, err = json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal payload: %w", err)
+		}
+	}
+
+	apiURL := strings.TrimRight(s.config.APIBaseURL, "/") + path
+	ctx, log := s.requestContext(ctx, path)
+	idempotencyKey := idempotencyKeyFor(ctx, path)
+	requestID := RequestIDFromContext(ctx)
+	retryable := isIdempotentMethod(method) || idempotencyKey != ""
+
+	attempts := 1
+	if retryable {
+		attempts += s.config.BackendMaxRetries
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			delay := s.backoff(attempt, lastErr)
+			log.Warn(fmt.Sprintf("Retrying %s %s in %s (attempt %d/%d): %v", method, path, delay, attempt+1, attempts, lastErr))
+			if err := sleepContext(ctx, delay); err != nil {
+				return nil, fmt.Errorf("API request aborted: %w", lastErr)
+			}
+		}
+
+		// При разомкнутом выключателе не ждем таймаута недоступного API
+		if err := s.breaker.Allow(); err != nil {
+			if lastErr != nil {
+				return nil, fmt.Errorf("%w after: %v", err, lastErr)
+			}
+			return nil, err
+		}
+
+		respBody, err := s.doOnce(ctx, log, method, apiURL, body, idempotencyKey, requestID)
+		s.recordResult(ctx, err)
+		if err == nil {
+			return respBody, nil
+		}
+		if idempotencyKey != "" && errors.Is(err, ErrDuplicateRequest) {
+			// Предыдущая попытка дошла до API, хотя ответ на нее потерялся
+			log.Info(fmt.Sprintf("API reports %s %s as already processed", method, path))
+			return nil, nil
+		}
+		lastErr = err
+
+		if !isRetryableError(ctx, err) {
+			break
+		}
+	}
+	return nil, lastErr
+}
 
-	apiURL := strings.TrimRight(s.config.APIBaseURL, "/") + "/v1/add-bot"
-	s.logger.Info("AddBotToChannel API URL:", apiURL)
-	s.logger.Info("AddBotToChannel payload:", string(body))
+// doOnce выполняет одну попытку запроса с таймаутом BackendTimeout
+func (s *APIService) doOnce(ctx context.Context, log logger.Logger, method, apiURL string, body []byte, idempotencyKey, requestID string) ([]byte, error) {
+	attemptCtx := ctx
+	if s.config.BackendTimeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, s.config.BackendTimeout)
+		defer cancel()
+	}
 
-	req, err := http.NewRequest("POST", apiURL, strings.NewReader(string(body)))
+	req, err := http.NewRequestWithContext(attemptCtx, method, apiURL, bytes.NewReader(body))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-ID", requestID)
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+	// Подпись вычисляется заново для каждой попытки, чтобы метка времени была свежей
+	if err := s.authenticate(req, body); err != nil {
+		return nil, fmt.Errorf("failed to authenticate request: %w", err)
+	}
+
+	log.Info(fmt.Sprintf("Sending API request: %s %s", method, apiURL))
 
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("API request failed: %w", err)
+		return nil, fmt.Errorf("API request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	s.logger.Info("AddBotToChannel response status:", resp.StatusCode)
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API response: %w", err)
+	}
 
-	if resp.StatusCode == 400 {
-		return fmt.Errorf("channel is already added")
+	log.Info(fmt.Sprintf("API response status: %d", resp.StatusCode))
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newStatusError(resp.StatusCode, respBody, parseRetryAfter(resp.Header.Get("Retry-After")))
 	}
+	return respBody, nil
+}
 
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("API returned non-200 status: %d", resp.StatusCode)
+// requestContext добавляет в контекст X-Request-ID, если его еще нет, и возвращает
+// логгер, который пишет ID запроса и ключ идемпотентности в каждую строку
+func (s *APIService) requestContext(ctx context.Context, path string) (context.Context, logger.Logger) {
+	requestID := RequestIDFromContext(ctx)
+	if requestID == "" {
+		requestID = NewRequestID()
+		ctx = WithRequestID(ctx, requestID)
 	}
+	log := s.logger.WithField("request_id", requestID)
+	if key := idempotencyKeyFor(ctx, path); key != "" {
+		log = log.WithField("idempotency_key", key)
+	}
+	return ctx, log
+}
 
-	return nil
+// idempotencyKeyFor возвращает ключ идемпотентности запроса: заданный в контексте
+// или полученный из ID обновления Telegram, вызвавшего запрос. Строка запроса
+// в ключ не входит.
+func idempotencyKeyFor(ctx context.Context, path string) string {
+	if key := IdempotencyKeyFromContext(ctx); key != "" {
+		return key
+	}
+	if updateID, ok := UpdateIDFromContext(ctx); ok {
+		if i := strings.IndexByte(path, '?'); i >= 0 {
+			path = path[:i]
+		}
+		return fmt.Sprintf("tg-update-%d-%s", updateID, strings.ReplaceAll(strings.Trim(path, "/"), "/", "-"))
+	}
+	return ""
+}
+
+// recordResult передает результат попытки выключателю. Ответы 4xx означают,
+// что API работает, а прерванные вызывающим запросы не учитываются.
+func (s *APIService) recordResult(ctx context.Context, err error) {
+	switch {
+	case err == nil:
+		s.breaker.Success()
+	case ctx.Err() != nil:
+		s.breaker.Cancel()
+	case isRetryableError(ctx, err):
+		s.breaker.Failure()
+	default:
+		s.breaker.Success()
+	}
+}
+
+// backoff вычисляет задержку перед повтором: экспоненциальный рост с полным
+// джиттером, но не меньше значения Retry-After, если сервер его прислал
+func (s *APIService) backoff(attempt int, lastErr error) time.Duration {
+	ceiling := s.config.BackendRetryBaseDelay << (attempt - 1)
+	if ceiling <= 0 || ceiling > s.config.BackendRetryMaxDelay {
+		ceiling = s.config.BackendRetryMaxDelay
+	}
+	delay := time.Duration(rand.Int63n(int64(ceiling) + 1))
+
+	var statusErr *StatusError
+	if errors.As(lastErr, &statusErr) && statusErr.RetryAfter > delay {
+		delay = statusErr.RetryAfter
+	}
+	return delay
+}
+
+// isRetryableError определяет, имеет ли смысл повторять запрос
+func isRetryableError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	// Ошибки сети и таймауты отдельной попытки
+	return true
+}
+
+// isIdempotentMethod сообщает, можно ли повторять запрос без ключа идемпотентности
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseRetryAfter разбирает заголовок Retry-After: число секунд или HTTP-дату
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
+// sleepContext ждет delay или отмены контекста
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// identityPayload формирует данные личности для API
+func identityPayload(identity *models.IdentityData) map[string]interface{} {
+	return map[string]interface{}{
+		"surname":         identity.Surname,
+		"given_names":     identity.GivenNames,
+		"nationality":     identity.Nationality,
+		"issuing_country": identity.IssuingCountry,
+		"sex":             identity.Sex,
+		"birth_date":      identity.BirthDate.Format("2006-01-02"),
+		"document_number": identity.DocumentNumber,
+		"expiry_date":     identity.ExpiryDate.Format("2006-01-02"),
+	}
+}
+
+// policyPayload формирует результат проверки правил для API
+func policyPayload(policy *models.PolicyResult) map[string]interface{} {
+	violations := make([]map[string]interface{}, 0, len(policy.Violations))
+	for _, violation := range policy.Violations {
+		violations = append(violations, map[string]interface{}{
+			"rule":     violation.Rule,
+			"required": violation.Required,
+			"actual":   violation.Actual,
+		})
+	}
+	return map[string]interface{}{
+		"passed":              policy.Passed,
+		"age":                 policy.Age,
+		"document_valid_days": policy.DocumentValidDays,
+		"violations":          violations,
+	}
+}
+
+// screeningPayload формирует результат проверки по списку для API
+func screeningPayload(result *models.ScreeningResult) map[string]interface{} {
+	matches := make([]map[string]interface{}, 0, len(result.Matches))
+	for _, match := range result.Matches {
+		matches = append(matches, map[string]interface{}{
+			"entry_id":         match.EntryID,
+			"name":             match.Name,
+			"source":           match.Source,
+			"score":            match.Score,
+			"birth_date_match": match.BirthDateMatch,
+		})
+	}
+	return map[string]interface{}{
+		"matches":     matches,
+		"released_by": result.ReleasedBy,
+	}
 }
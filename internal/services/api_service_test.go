@@ -0,0 +1,328 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"tribute-chatbot/internal/config"
+	"tribute-chatbot/internal/fakebackend"
+	"tribute-chatbot/internal/models"
+	"tribute-chatbot/internal/signing"
+)
+
+const testHMACSecret = "secret"
+
+// newTestBackend запускает фейковый бэкенд с подписью HMAC и возвращает
+// его вместе с настройками, в которых API указывает на него
+func newTestBackend(t *testing.T) (*fakebackend.Server, *config.Config) {
+	t.Helper()
+	backend := fakebackend.New(fakebackend.Options{HMACSecret: testHMACSecret})
+	server := httptest.NewServer(backend)
+	t.Cleanup(server.Close)
+
+	return backend, &config.Config{
+		APIBaseURL:            server.URL,
+		BackendTimeout:        time.Second,
+		BackendMaxRetries:     2,
+		BackendLookupTimeout:  time.Second,
+		BackendRetryBaseDelay: time.Millisecond,
+		BackendRetryMaxDelay:  time.Millisecond,
+		BackendAuthScheme:     config.BackendAuthHMAC,
+		BackendHMACKeyID:      "key-1",
+		BackendHMACSecret:     testHMACSecret,
+		OutboxPollInterval:    time.Hour,
+		OutboxRetryBaseDelay:  time.Millisecond,
+		OutboxRetryMaxDelay:   time.Millisecond,
+		OutboxMaxAttempts:     3,
+	}
+}
+
+func newTestAPIService(t *testing.T, cfg *config.Config) *APIService {
+	t.Helper()
+	api, err := NewAPIService(cfg)
+	if err != nil {
+		t.Fatalf("api service: %v", err)
+	}
+	return api
+}
+
+// testRegistration регистрация канала для тестов
+func testRegistration(chatID int64) models.ChannelRegistration {
+	return models.ChannelRegistration{
+		UserID:       42,
+		ChatID:       chatID,
+		ChatType:     "channel",
+		ChannelTitle: "Test channel",
+	}
+}
+
+func TestUpdateUserVerificationRequest(t *testing.T) {
+	backend, cfg := newTestBackend(t)
+	api := newTestAPIService(t, cfg)
+
+	ctx := WithRequestID(WithIdempotencyKey(context.Background(), "verification-42"), "request-1")
+	details := &models.VerificationDetails{Identity: &models.IdentityData{Surname: "IVANOV", GivenNames: "IVAN"}}
+	if err := api.UpdateUserVerification(ctx, 42, true, details); err != nil {
+		t.Fatalf("update verification: %v", err)
+	}
+
+	requests := backend.RequestsTo("/v1/check-verified-passport")
+	if len(requests) != 1 {
+		t.Fatalf("requests = %d, want 1", len(requests))
+	}
+	req := requests[0]
+	if req.Method != http.MethodPost {
+		t.Errorf("method = %s, want POST", req.Method)
+	}
+	for header, want := range map[string]string{
+		"Content-Type":          "application/json",
+		"Idempotency-Key":       "verification-42",
+		"X-Request-ID":          "request-1",
+		signing.HeaderKeyID:     "key-1",
+		signing.HeaderNonce:     "",
+		signing.HeaderSignature: "",
+	} {
+		got := req.Header.Get(header)
+		if want == "" && got == "" || want != "" && got != want {
+			t.Errorf("header %s = %q, want %q", header, got, want)
+		}
+	}
+
+	var body struct {
+		UserID        int64                  `json:"userId"`
+		IsVerificated bool                   `json:"isVerificated"`
+		Identity      map[string]interface{} `json:"identity"`
+	}
+	if err := req.JSON(&body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if body.UserID != 42 || !body.IsVerificated || body.Identity["surname"] != "IVANOV" {
+		t.Errorf("body = %+v", body)
+	}
+
+	verification, ok := backend.State().Verification(42)
+	if !ok || !verification.IsVerified {
+		t.Errorf("backend verification = %+v, want verified", verification)
+	}
+}
+
+func TestRequestIDAndKeyFromUpdate(t *testing.T) {
+	backend, cfg := newTestBackend(t)
+	api := newTestAPIService(t, cfg)
+	backend.State().AddChannel(fakebackend.Channel{ChatID: -1001, Active: true})
+
+	// Подпись покрывает строку запроса, поэтому GET с параметрами проходит проверку
+	if _, err := api.GetChannelAccess(WithUpdateID(context.Background(), 77), -1001, 42); err != nil {
+		t.Fatalf("get channel access: %v", err)
+	}
+
+	requests := backend.RequestsTo("/v1/channel-access")
+	if len(requests) != 1 {
+		t.Fatalf("requests = %d, want 1", len(requests))
+	}
+	if got := requests[0].Header.Get("Idempotency-Key"); got != "tg-update-77-v1-channel-access" {
+		t.Errorf("Idempotency-Key = %q", got)
+	}
+	if requests[0].Header.Get("X-Request-ID") == "" {
+		t.Error("X-Request-ID is empty")
+	}
+}
+
+func TestBearerAuthentication(t *testing.T) {
+	backend := fakebackend.New(fakebackend.Options{BearerToken: "token"})
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	cfg := &config.Config{
+		APIBaseURL:        server.URL,
+		BackendTimeout:    time.Second,
+		BackendAuthScheme: config.BackendAuthBearer,
+		BackendAuthToken:  "token",
+	}
+	if err := newTestAPIService(t, cfg).AddBotToChannel(context.Background(), testRegistration(-1001)); err != nil {
+		t.Fatalf("add bot: %v", err)
+	}
+
+	cfg.BackendAuthToken = "wrong"
+	err := newTestAPIService(t, cfg).AddBotToChannel(context.Background(), testRegistration(-1002))
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("err = %v, want ErrUnauthorized", err)
+	}
+	if got := backend.RequestsTo("/v1/add-bot")[0].Header.Get("Authorization"); got != "Bearer token" {
+		t.Errorf("Authorization = %q", got)
+	}
+}
+
+func TestRetriesServerErrors(t *testing.T) {
+	backend, cfg := newTestBackend(t)
+	api := newTestAPIService(t, cfg)
+	backend.Inject(fakebackend.Fault{Path: "/v1/add-bot", Times: 2, Status: http.StatusServiceUnavailable, Code: "unavailable"})
+
+	ctx := WithIdempotencyKey(context.Background(), "register-1001")
+	if err := api.AddBotToChannel(ctx, testRegistration(-1001)); err != nil {
+		t.Fatalf("add bot: %v", err)
+	}
+
+	requests := backend.RequestsTo("/v1/add-bot")
+	if len(requests) != 3 {
+		t.Fatalf("requests = %d, want 3", len(requests))
+	}
+	// Повторы отправляются с тем же ключом и ID запроса, но подписываются заново
+	nonces := make(map[string]bool)
+	for _, req := range requests {
+		if req.Header.Get("Idempotency-Key") != "register-1001" {
+			t.Errorf("Idempotency-Key = %q", req.Header.Get("Idempotency-Key"))
+		}
+		if req.Header.Get("X-Request-ID") != requests[0].Header.Get("X-Request-ID") {
+			t.Errorf("X-Request-ID changed between attempts")
+		}
+		nonces[req.Header.Get(signing.HeaderNonce)] = true
+	}
+	if len(nonces) != len(requests) {
+		t.Errorf("signature nonce reused between attempts")
+	}
+	if channels := backend.State().Channels(); len(channels) != 1 {
+		t.Errorf("channels = %d, want 1", len(channels))
+	}
+}
+
+func TestServerErrorWithoutKeyNotRetried(t *testing.T) {
+	backend, cfg := newTestBackend(t)
+	api := newTestAPIService(t, cfg)
+	backend.Inject(fakebackend.Fault{Path: "/v1/add-bot", Times: 1, Status: http.StatusInternalServerError})
+
+	// POST без ключа идемпотентности мог быть выполнен, повторять его нельзя
+	err := api.AddBotToChannel(context.Background(), testRegistration(-1001))
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("err = %v, want status 500", err)
+	}
+	if requests := backend.RequestsTo("/v1/add-bot"); len(requests) != 1 {
+		t.Fatalf("requests = %d, want 1", len(requests))
+	}
+}
+
+func TestTimeoutRetried(t *testing.T) {
+	backend, cfg := newTestBackend(t)
+	cfg.BackendTimeout = 50 * time.Millisecond
+	api := newTestAPIService(t, cfg)
+	backend.Inject(fakebackend.Fault{Path: "/v1/check-verified-passport", Times: 1, Latency: time.Second})
+
+	ctx := WithIdempotencyKey(context.Background(), "verification-42")
+	if err := api.UpdateUserVerification(ctx, 42, true, nil); err != nil {
+		t.Fatalf("update verification: %v", err)
+	}
+	if requests := backend.RequestsTo("/v1/check-verified-passport"); len(requests) != 2 {
+		t.Fatalf("requests = %d, want 2", len(requests))
+	}
+}
+
+func TestTimeoutExhaustsRetries(t *testing.T) {
+	backend, cfg := newTestBackend(t)
+	cfg.BackendTimeout = 20 * time.Millisecond
+	api := newTestAPIService(t, cfg)
+	backend.Inject(fakebackend.Fault{Path: "/v1/channel-settings", Latency: time.Second})
+
+	_, err := api.GetChannelSettings(context.Background(), -1001)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+	if requests := backend.RequestsTo("/v1/channel-settings"); len(requests) != cfg.BackendMaxRetries+1 {
+		t.Fatalf("requests = %d, want %d", len(requests), cfg.BackendMaxRetries+1)
+	}
+}
+
+func TestClientErrorNotRetried(t *testing.T) {
+	backend, cfg := newTestBackend(t)
+	api := newTestAPIService(t, cfg)
+	backend.Inject(fakebackend.Fault{
+		Path:    "/v1/add-bot",
+		Times:   1,
+		Status:  http.StatusUnprocessableEntity,
+		Code:    ErrorCodeValidation,
+		Message: "invalid payload",
+		Details: []fakebackend.ErrorDetail{{Field: "channel_title", Message: "too long"}},
+	})
+
+	err := api.AddBotToChannel(WithIdempotencyKey(context.Background(), "register-1001"), testRegistration(-1001))
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("err = %v, want ErrValidation", err)
+	}
+	if details := ValidationDetails(err); len(details) != 1 {
+		t.Errorf("details = %v, want 1", details)
+	}
+	if requests := backend.RequestsTo("/v1/add-bot"); len(requests) != 1 {
+		t.Fatalf("requests = %d, want 1", len(requests))
+	}
+}
+
+func TestDuplicateRequestReplayed(t *testing.T) {
+	backend, cfg := newTestBackend(t)
+	api := newTestAPIService(t, cfg)
+
+	// Повтор с тем же ключом получает сохраненный ответ и не регистрирует канал второй раз
+	ctx := WithIdempotencyKey(context.Background(), "register-1001")
+	for i := 0; i < 2; i++ {
+		if err := api.AddBotToChannel(ctx, testRegistration(-1001)); err != nil {
+			t.Fatalf("attempt %d: add bot: %v", i+1, err)
+		}
+	}
+	if requests := backend.RequestsTo("/v1/add-bot"); len(requests) != 2 {
+		t.Fatalf("requests = %d, want 2", len(requests))
+	}
+	if channels := backend.State().Channels(); len(channels) != 1 {
+		t.Fatalf("channels = %d, want 1", len(channels))
+	}
+
+	// С новым ключом API отвечает, что канал уже подключен
+	err := api.AddBotToChannel(WithIdempotencyKey(context.Background(), "register-1001-again"), testRegistration(-1001))
+	if !errors.Is(err, ErrChannelAlreadyAdded) {
+		t.Fatalf("err = %v, want ErrChannelAlreadyAdded", err)
+	}
+}
+
+func TestDuplicateRequestErrorIsSuccess(t *testing.T) {
+	backend, cfg := newTestBackend(t)
+	api := newTestAPIService(t, cfg)
+	backend.Inject(fakebackend.Fault{
+		Path:   "/v1/check-verified-passport",
+		Times:  1,
+		Status: http.StatusConflict,
+		Code:   ErrorCodeDuplicateRequest,
+	})
+
+	ctx := WithIdempotencyKey(context.Background(), "verification-42")
+	if err := api.UpdateUserVerification(ctx, 42, true, nil); err != nil {
+		t.Fatalf("err = %v, want nil for an already processed request", err)
+	}
+}
+
+func TestCircuitBreakerStopsRequests(t *testing.T) {
+	backend, cfg := newTestBackend(t)
+	cfg.BackendMaxRetries = 0
+	cfg.BackendBreakerFailureThreshold = 2
+	cfg.BackendBreakerOpenTimeout = time.Hour
+	cfg.BackendBreakerHalfOpenSuccesses = 1
+	api := newTestAPIService(t, cfg)
+	backend.Inject(fakebackend.Fault{Status: http.StatusBadGateway})
+
+	for i := 0; i < 2; i++ {
+		if _, err := api.GetChannelSettings(context.Background(), -1001); err == nil {
+			t.Fatalf("attempt %d: expected error", i+1)
+		}
+	}
+	if api.Available() {
+		t.Fatal("API available after repeated failures")
+	}
+
+	_, err := api.GetChannelSettings(context.Background(), -1001)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+	if requests := backend.Requests(); len(requests) != 2 {
+		t.Fatalf("requests = %d, want 2", len(requests))
+	}
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+	"tribute-chatbot/internal/models"
+)
+
+// BackendClient клиент API бэкенда Tribute, от которого зависят обработчики
+type BackendClient interface {
+	// UpdateUserVerification обновляет статус верификации пользователя
+	UpdateUserVerification(ctx context.Context, userID int64, isVerified bool, details *models.VerificationDetails) error
+	// AddBotToChannel регистрирует канал, в который добавлен бот
+	AddBotToChannel(ctx context.Context, registration models.ChannelRegistration) error
+	// UpdateChannel передает новые название, username и метаданные канала
+	UpdateChannel(ctx context.Context, update models.ChannelUpdate) error
+	// DeactivateChannel отключает канал, в котором бот больше не администратор
+	DeactivateChannel(ctx context.Context, deactivation models.ChannelDeactivation) error
+	// ReportChannelPermissions передает результат проверки прав бота в канале
+	ReportChannelPermissions(ctx context.Context, report models.ChannelPermissionReport) error
+	// ListChannels возвращает все подключенные к Tribute каналы
+	ListChannels(ctx context.Context) ([]models.ChannelSummary, error)
+	// ReportReconciliation передает расхождения, найденные при сверке каналов
+	ReportReconciliation(ctx context.Context, reconciliation models.ChannelReconciliation) error
+	// ReportInviteLink передает выдачу, использование или отзыв персональной ссылки в канал
+	ReportInviteLink(ctx context.Context, link models.InviteLink) error
+	// ListExpiredSubscriptions возвращает истекшие подписки, подписчики которых еще в каналах
+	ListExpiredSubscriptions(ctx context.Context) ([]models.ExpiredSubscription, error)
+	// ReportSubscriptionRemoval сообщает, что подписчик с истекшей подпиской удален из канала
+	ReportSubscriptionRemoval(ctx context.Context, subscription models.ExpiredSubscription) error
+	// GetChannelAccess возвращает правило вступления в канал и статус пользователя
+	// или ErrChannelNotFound, если канал не подключен
+	GetChannelAccess(ctx context.Context, chatID, userID int64) (models.ChannelAccess, error)
+	// GetChannelSettings возвращает настройки канала или ErrChannelNotFound, если канал не подключен
+	GetChannelSettings(ctx context.Context, chatID int64) (models.ChannelSettings, error)
+	// GetUser возвращает профиль пользователя или ErrUserNotFound
+	GetUser(ctx context.Context, userID int64) (*models.UserProfile, error)
+	// Available сообщает, что API не считается недоступным после серии сбоев
+	Available() bool
+}
+
+// contextKey ключ значений, передаваемых в запросы к API через context
+type contextKey string
+
+const (
+	updateIDKey       contextKey = "update_id"
+	idempotencyKeyKey contextKey = "idempotency_key"
+	requestIDKey      contextKey = "request_id"
+)
+
+// WithUpdateID сохраняет в контексте ID обновления Telegram, вызвавшего запрос
+func WithUpdateID(ctx context.Context, updateID int) context.Context {
+	return context.WithValue(ctx, updateIDKey, updateID)
+}
+
+// UpdateIDFromContext возвращает ID обновления Telegram из контекста
+func UpdateIDFromContext(ctx context.Context) (int, bool) {
+	updateID, ok := ctx.Value(updateIDKey).(int)
+	return updateID, ok
+}
+
+// WithIdempotencyKey задает ключ идемпотентности для запроса к API.
+// Без ключа неидемпотентные запросы не повторяются при ошибках.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyKey, key)
+}
+
+// IdempotencyKeyFromContext возвращает ключ идемпотентности из контекста
+func IdempotencyKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyKey).(string)
+	return key
+}
+
+// WithRequestID задает X-Request-ID для запроса к API.
+// Без него APIService генерирует новый ID для каждого вызова.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFromContext возвращает X-Request-ID из контекста
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// NewRequestID генерирует идентификатор запроса для X-Request-ID
+func NewRequestID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("req-%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"errors"
+	"time"
+	"tribute-chatbot/internal/models"
+	"tribute-chatbot/internal/storage"
+)
+
+// ErrLinkNotFound возвращается, если подтверждение привязки канала не ожидается
+var ErrLinkNotFound = errors.New("pending channel link not found")
+
+// ChannelLinkService хранит каналы, ожидающие подтверждения привязки к аккаунту,
+// и отменяет подтверждения по истечении срока. Ожидающие подтверждения сохраняются
+// на диск и восстанавливаются после перезапуска.
+type ChannelLinkService struct {
+	links *timerStore[int64, models.PendingChannelLink]
+}
+
+// NewChannelLinkService создает сервис и загружает сохраненные подтверждения
+func NewChannelLinkService(store *storage.JSONFile) (*ChannelLinkService, error) {
+	links, err := newTimerStore(store, timerStoreOptions[int64, models.PendingChannelLink]{
+		name: "pending channel links",
+		key:  func(link *models.PendingChannelLink) int64 { return link.Channel.ChatID },
+		dueAt: func(link *models.PendingChannelLink) (time.Time, bool) {
+			return link.ExpiresAt, true
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ChannelLinkService{links: links}, nil
+}
+
+// Start задает функцию обработки просроченных подтверждений и планирует загруженные.
+// Просроченные за время простоя подтверждения отменяются сразу.
+func (s *ChannelLinkService) Start(expire func(models.PendingChannelLink)) {
+	s.links.start(expire)
+}
+
+// Request сохраняет канал до подтверждения владельцем. Новый запрос
+// для того же канала заменяет предыдущий.
+func (s *ChannelLinkService) Request(link models.PendingChannelLink) error {
+	return s.links.put(link)
+}
+
+// SetMessageID запоминает сообщение с кнопками подтверждения
+func (s *ChannelLinkService) SetMessageID(chatID int64, messageID int) error {
+	_, err := s.links.update(chatID, func(link *models.PendingChannelLink) {
+		link.MessageID = messageID
+	})
+	if errors.Is(err, errTimerEntryNotFound) {
+		return ErrLinkNotFound
+	}
+	return err
+}
+
+// Pending возвращает копию ожидающего подтверждения канала
+func (s *ChannelLinkService) Pending(chatID int64) (*models.PendingChannelLink, bool) {
+	link, exists := s.links.get(chatID)
+	if !exists {
+		return nil, false
+	}
+	return &link, true
+}
+
+// Resolve завершает ожидание подтверждения и возвращает его данные.
+// Повторный вызов для того же канала, как и вызов во время отмены
+// по истечении срока, возвращает ErrLinkNotFound.
+func (s *ChannelLinkService) Resolve(chatID int64) (*models.PendingChannelLink, error) {
+	link, exists := s.links.remove(chatID)
+	if !exists {
+		return nil, ErrLinkNotFound
+	}
+	return &link, nil
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"tribute-chatbot/internal/models"
+	"tribute-chatbot/internal/storage"
+)
+
+// ChannelService хранит последние отправленные в API данные зарегистрированных
+// каналов, чтобы замечать смену названия, username и метаданных
+type ChannelService struct {
+	store    *storage.JSONFile
+	channels map[int64]models.Channel
+	mutex    sync.Mutex
+}
+
+// NewChannelService создает сервис каналов и загружает сохраненные каналы
+func NewChannelService(store *storage.JSONFile) (*ChannelService, error) {
+	channels := make(map[int64]models.Channel)
+	if err := store.Load(&channels); err != nil {
+		return nil, fmt.Errorf("failed to load channels: %w", err)
+	}
+	return &ChannelService{
+		store:    store,
+		channels: channels,
+	}, nil
+}
+
+// Get возвращает сохраненные данные канала
+func (s *ChannelService) Get(chatID int64) (models.Channel, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	channel, ok := s.channels[chatID]
+	return channel, ok
+}
+
+// Register сохраняет данные канала при его регистрации
+func (s *ChannelService) Register(channel models.Channel) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	channel.UpdatedAt = time.Now()
+	s.channels[channel.ChatID] = channel
+	return s.saveLocked()
+}
+
+// Update сохраняет новые название, username, описание и аватарку известного канала.
+// Возвращает обновленный канал и true, если что-то изменилось.
+func (s *ChannelService) Update(update models.Channel) (models.Channel, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	channel, ok := s.channels[update.ChatID]
+	if !ok {
+		return channel, false, nil
+	}
+	if channel.Title == update.Title && channel.Username == update.Username &&
+		channel.Description == update.Description && channel.PhotoUniqueID == update.PhotoUniqueID {
+		return channel, false, nil
+	}
+	channel.Title = update.Title
+	channel.Username = update.Username
+	channel.Description = update.Description
+	channel.PhotoFileID = update.PhotoFileID
+	channel.PhotoUniqueID = update.PhotoUniqueID
+	channel.UpdatedAt = time.Now()
+	s.channels[update.ChatID] = channel
+	return channel, true, s.saveLocked()
+}
+
+// Remove забывает канал, отключенный в API, чтобы он больше не считался подключенным
+func (s *ChannelService) Remove(chatID int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.channels[chatID]; !ok {
+		return nil
+	}
+	delete(s.channels, chatID)
+	return s.saveLocked()
+}
+
+// saveLocked сохраняет каналы на диск. Вызывается под s.mutex.
+func (s *ChannelService) saveLocked() error {
+	if err := s.store.Save(s.channels); err != nil {
+		return fmt.Errorf("failed to save channels: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+	"tribute-chatbot/internal/models"
+)
+
+// ChannelSettingsService получает настройки каналов из API и кэширует их на время ttl,
+// чтобы не запрашивать API на каждый пост
+type ChannelSettingsService struct {
+	backend       BackendClient
+	lookupTimeout time.Duration
+	// cache хранит nil для каналов, не подключенных в API
+	cache *ttlCache[int64, *models.ChannelSettings]
+}
+
+// NewChannelSettingsService создает сервис настроек каналов. Нулевой ttl отключает кэш.
+func NewChannelSettingsService(backend BackendClient, ttl, lookupTimeout time.Duration) *ChannelSettingsService {
+	return &ChannelSettingsService{
+		backend:       backend,
+		lookupTimeout: lookupTimeout,
+		cache:         newTTLCache[int64, *models.ChannelSettings](ttl),
+	}
+}
+
+// Get возвращает настройки канала из кэша или API. Для канала, не подключенного
+// в API, возвращается ErrChannelNotFound.
+func (s *ChannelSettingsService) Get(ctx context.Context, chatID int64) (models.ChannelSettings, error) {
+	if cached, ok := s.cache.Get(chatID); ok {
+		if cached == nil {
+			return models.ChannelSettings{}, ErrChannelNotFound
+		}
+		return *cached, nil
+	}
+
+	if s.lookupTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.lookupTimeout)
+		defer cancel()
+	}
+
+	settings, err := s.backend.GetChannelSettings(ctx, chatID)
+	if err != nil && !errors.Is(err, ErrChannelNotFound) {
+		return models.ChannelSettings{}, err
+	}
+
+	if err != nil {
+		s.cache.Set(chatID, nil)
+		return models.ChannelSettings{}, err
+	}
+	s.cache.Set(chatID, &settings)
+	return settings, nil
+}
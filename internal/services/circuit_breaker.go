@@ -0,0 +1,179 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen возвращается без обращения к API, пока API считается недоступным
+var ErrCircuitOpen = errors.New("backend circuit is open")
+
+// CircuitState состояние автоматического выключателя
+type CircuitState string
+
+// Состояния автоматического выключателя
+const (
+	CircuitClosed   CircuitState = "closed"
+	CircuitOpen     CircuitState = "open"
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+// CircuitBreaker автоматический выключатель запросов к API. После серии сбоев
+// подряд запросы отклоняются сразу; по истечении паузы пропускается пробный
+// запрос, и выключатель замыкается после нужного числа успешных проб.
+type CircuitBreaker struct {
+	failureThreshold  int
+	openTimeout       time.Duration
+	halfOpenSuccesses int
+
+	state     CircuitState
+	failures  int
+	successes int
+	probing   bool // в состоянии half-open одновременно выполняется один пробный запрос
+	openedAt  time.Time
+	listeners []func(from, to CircuitState)
+	mutex     sync.Mutex
+}
+
+// NewCircuitBreaker создает выключатель. Нулевой failureThreshold выключает его.
+func NewCircuitBreaker(failureThreshold int, openTimeout time.Duration, halfOpenSuccesses int) *CircuitBreaker {
+	if halfOpenSuccesses < 1 {
+		halfOpenSuccesses = 1
+	}
+	return &CircuitBreaker{
+		failureThreshold:  failureThreshold,
+		openTimeout:       openTimeout,
+		halfOpenSuccesses: halfOpenSuccesses,
+		state:             CircuitClosed,
+	}
+}
+
+// OnStateChange добавляет обработчик смены состояния
+func (b *CircuitBreaker) OnStateChange(fn func(from, to CircuitState)) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.listeners = append(b.listeners, fn)
+}
+
+// State возвращает текущее состояние. Разомкнутый выключатель, пауза которого
+// истекла, считается полуоткрытым.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= b.openTimeout {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+// Allow сообщает, можно ли выполнить запрос. Разрешенный запрос должен
+// завершиться вызовом Success, Failure или Cancel.
+func (b *CircuitBreaker) Allow() error {
+	if b.failureThreshold <= 0 {
+		return nil
+	}
+
+	b.mutex.Lock()
+	var from CircuitState
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.openTimeout {
+			b.mutex.Unlock()
+			return ErrCircuitOpen
+		}
+		from = b.setStateLocked(CircuitHalfOpen)
+		b.probing = true
+	case CircuitHalfOpen:
+		if b.probing {
+			b.mutex.Unlock()
+			return ErrCircuitOpen
+		}
+		b.probing = true
+	}
+	listeners := b.listeners
+	b.mutex.Unlock()
+
+	if from != "" {
+		notifyCircuitListeners(listeners, from, CircuitHalfOpen)
+	}
+	return nil
+}
+
+// Success фиксирует успешный запрос
+func (b *CircuitBreaker) Success() {
+	b.record(func() CircuitState {
+		switch b.state {
+		case CircuitClosed:
+			b.failures = 0
+		case CircuitHalfOpen:
+			b.probing = false
+			b.successes++
+			if b.successes >= b.halfOpenSuccesses {
+				return b.setStateLocked(CircuitClosed)
+			}
+		}
+		return ""
+	})
+}
+
+// Failure фиксирует сбой запроса
+func (b *CircuitBreaker) Failure() {
+	b.record(func() CircuitState {
+		switch b.state {
+		case CircuitClosed:
+			b.failures++
+			if b.failures >= b.failureThreshold {
+				return b.setStateLocked(CircuitOpen)
+			}
+		case CircuitHalfOpen:
+			return b.setStateLocked(CircuitOpen)
+		}
+		return ""
+	})
+}
+
+// Cancel освобождает разрешение запроса, прерванного вызывающим без ответа API
+func (b *CircuitBreaker) Cancel() {
+	b.record(func() CircuitState {
+		b.probing = false
+		return ""
+	})
+}
+
+// record применяет результат запроса и оповещает о смене состояния
+func (b *CircuitBreaker) record(apply func() CircuitState) {
+	if b.failureThreshold <= 0 {
+		return
+	}
+
+	b.mutex.Lock()
+	from := apply()
+	to := b.state
+	listeners := b.listeners
+	b.mutex.Unlock()
+
+	if from != "" {
+		notifyCircuitListeners(listeners, from, to)
+	}
+}
+
+// setStateLocked переключает состояние и возвращает предыдущее
+func (b *CircuitBreaker) setStateLocked(to CircuitState) CircuitState {
+	from := b.state
+	b.state = to
+	b.failures = 0
+	b.successes = 0
+	b.probing = false
+	if to == CircuitOpen {
+		b.openedAt = time.Now()
+	}
+	return from
+}
+
+// notify вызывает обработчики смены состояния
+func notifyCircuitListeners(listeners []func(from, to CircuitState), from, to CircuitState) {
+	for _, fn := range listeners {
+		fn(from, to)
+	}
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"tribute-chatbot/internal/logger"
+	"tribute-chatbot/internal/models"
+	"tribute-chatbot/internal/storage"
+)
+
+// ErrDecisionNotFound возвращается, если отложенного решения нет или оно уже применяется
+var ErrDecisionNotFound = errors.New("pending decision not found")
+
+// DecisionService хранит решения по заявкам на время окна отмены
+// и применяет их по истечении окна. Отложенные решения сохраняются на диск
+// и восстанавливаются после перезапуска.
+type DecisionService struct {
+	decisions *timerStore[int64, models.PendingDecision]
+	logger    logger.Logger
+}
+
+// NewDecisionService создает сервис и загружает сохраненные решения
+func NewDecisionService(store *storage.JSONFile) (*DecisionService, error) {
+	decisions, err := newTimerStore(store, timerStoreOptions[int64, models.PendingDecision]{
+		name: "pending decisions",
+		key:  func(decision *models.PendingDecision) int64 { return decision.UserID },
+		dueAt: func(decision *models.PendingDecision) (time.Time, bool) {
+			return decision.ExecuteAt, true
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &DecisionService{
+		decisions: decisions,
+		logger:    logger.New(),
+	}, nil
+}
+
+// Start задает функцию применения решений и планирует загруженные решения.
+// Просроченные за время простоя решения применяются сразу. Решение удаляется
+// с диска только после применения, чтобы пережить падение.
+func (s *DecisionService) Start(execute func(models.PendingDecision)) {
+	for _, decision := range s.decisions.values() {
+		s.logger.Info(fmt.Sprintf("Restoring pending decision: user_id=%d, execute_at=%s", decision.UserID, decision.ExecuteAt.Format(time.RFC3339)))
+	}
+	s.decisions.start(execute)
+}
+
+// Schedule сохраняет решение и планирует его применение на decision.ExecuteAt
+func (s *DecisionService) Schedule(decision models.PendingDecision) error {
+	err := s.decisions.add(decision)
+	if errors.Is(err, errTimerEntryExists) {
+		return fmt.Errorf("decision for user %d is already pending", decision.UserID)
+	}
+	return err
+}
+
+// Cancel отменяет отложенное решение, если оно еще не начало применяться
+func (s *DecisionService) Cancel(userID int64) (*models.PendingDecision, error) {
+	decision, exists := s.decisions.remove(userID)
+	if !exists {
+		return nil, ErrDecisionNotFound
+	}
+	return &decision, nil
+}
+
+// Pending возвращает копию отложенного решения пользователя
+func (s *DecisionService) Pending(userID int64) (*models.PendingDecision, bool) {
+	decision, exists := s.decisions.get(userID)
+	if !exists {
+		return nil, false
+	}
+	return &decision, true
+}
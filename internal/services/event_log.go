@@ -0,0 +1,80 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"tribute-chatbot/internal/storage"
+)
+
+// ErrEventInProgress возвращается, если событие с тем же ID сейчас обрабатывается
+var ErrEventInProgress = errors.New("event is already being processed")
+
+// eventRetention время хранения ID обработанных событий
+const eventRetention = 7 * 24 * time.Hour
+
+// EventLog запоминает ID обработанных событий бэкенда, чтобы повторная
+// доставка события не отправляла сообщение пользователю второй раз
+type EventLog struct {
+	store      *storage.JSONFile
+	processed  map[string]time.Time
+	inProgress map[string]bool
+	mutex      sync.Mutex
+}
+
+// NewEventLog создает журнал событий и загружает сохраненные ID
+func NewEventLog(store *storage.JSONFile) (*EventLog, error) {
+	processed := make(map[string]time.Time)
+	if err := store.Load(&processed); err != nil {
+		return nil, fmt.Errorf("failed to load event log: %w", err)
+	}
+	return &EventLog{
+		store:      store,
+		processed:  processed,
+		inProgress: make(map[string]bool),
+	}, nil
+}
+
+// Begin начинает обработку события. Возвращает false, если событие уже обработано,
+// и ErrEventInProgress, если его параллельно обрабатывает другой запрос.
+// После true обработка завершается вызовом Complete или Abort.
+func (l *EventLog) Begin(id string) (bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, done := l.processed[id]; done {
+		return false, nil
+	}
+	if l.inProgress[id] {
+		return false, ErrEventInProgress
+	}
+	l.inProgress[id] = true
+	return true, nil
+}
+
+// Complete отмечает событие обработанным
+func (l *EventLog) Complete(id string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	delete(l.inProgress, id)
+	now := time.Now()
+	l.processed[id] = now
+	for eventID, at := range l.processed {
+		if now.Sub(at) > eventRetention {
+			delete(l.processed, eventID)
+		}
+	}
+	if err := l.store.Save(l.processed); err != nil {
+		return fmt.Errorf("failed to save event log: %w", err)
+	}
+	return nil
+}
+
+// Abort прерывает обработку, чтобы событие можно было доставить повторно
+func (l *EventLog) Abort(id string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	delete(l.inProgress, id)
+}
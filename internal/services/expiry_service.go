@@ -0,0 +1,120 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"tribute-chatbot/internal/models"
+	"tribute-chatbot/internal/storage"
+)
+
+// ExpiryService хранит истекшие подписки, подписчики которых предупреждены
+// о продлении и ждут удаления из канала после льготного периода
+type ExpiryService struct {
+	store   *storage.JSONFile
+	pending map[string]*models.ExpiredSubscription
+	mutex   sync.Mutex
+}
+
+// NewExpiryService создает сервис и загружает сохраненные подписки
+func NewExpiryService(store *storage.JSONFile) (*ExpiryService, error) {
+	var saved []*models.ExpiredSubscription
+	if err := store.Load(&saved); err != nil {
+		return nil, fmt.Errorf("failed to load expired subscriptions: %w", err)
+	}
+
+	pending := make(map[string]*models.ExpiredSubscription, len(saved))
+	for _, subscription := range saved {
+		pending[expiryKey(subscription.ChatID, subscription.SubscriberID)] = subscription
+	}
+	return &ExpiryService{
+		store:   store,
+		pending: pending,
+	}, nil
+}
+
+// Sync сверяет отслеживаемые подписки с актуальным списком истекших из API.
+// Возвращает новые подписки, подписчиков которых нужно предупредить, и подписки,
+// которые пропали из списка, то есть были продлены. Новые подписки начинают
+// отслеживаться с удалением через grace после now.
+func (s *ExpiryService) Sync(expired []models.ExpiredSubscription, now time.Time, grace time.Duration) (added, renewed []models.ExpiredSubscription, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	current := make(map[string]bool, len(expired))
+	for _, subscription := range expired {
+		key := expiryKey(subscription.ChatID, subscription.SubscriberID)
+		current[key] = true
+		if _, tracked := s.pending[key]; tracked {
+			continue
+		}
+		subscription.NotifiedAt = now
+		subscription.RemoveAt = now.Add(grace)
+		s.pending[key] = &subscription
+		added = append(added, subscription)
+	}
+
+	changed := len(added) > 0
+	for key, subscription := range s.pending {
+		if current[key] {
+			continue
+		}
+		// Удаленный подписчик пропадает из списка, когда API учтет удаление
+		if subscription.RemovedAt.IsZero() {
+			renewed = append(renewed, *subscription)
+		}
+		delete(s.pending, key)
+		changed = true
+	}
+
+	if !changed {
+		return nil, nil, nil
+	}
+	return added, renewed, s.saveLocked()
+}
+
+// Due возвращает подписки, льготный период которых закончился
+func (s *ExpiryService) Due(now time.Time) []models.ExpiredSubscription {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var due []models.ExpiredSubscription
+	for _, subscription := range s.pending {
+		if subscription.RemovedAt.IsZero() && !now.Before(subscription.RemoveAt) {
+			due = append(due, *subscription)
+		}
+	}
+	return due
+}
+
+// Complete отмечает подписчика удаленным из канала. Подписка перестает
+// отслеживаться, когда пропадет из списка истекших в API, чтобы запоздавший
+// список не вызвал повторное напоминание и удаление.
+func (s *ExpiryService) Complete(chatID, subscriberID int64, removedAt time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	subscription, tracked := s.pending[expiryKey(chatID, subscriberID)]
+	if !tracked {
+		return nil
+	}
+	subscription.RemovedAt = removedAt
+	return s.saveLocked()
+}
+
+// saveLocked сохраняет отслеживаемые подписки на диск
+func (s *ExpiryService) saveLocked() error {
+	subscriptions := make([]*models.ExpiredSubscription, 0, len(s.pending))
+	for _, subscription := range s.pending {
+		subscriptions = append(subscriptions, subscription)
+	}
+	if err := s.store.Save(subscriptions); err != nil {
+		return fmt.Errorf("failed to save expired subscriptions: %w", err)
+	}
+	return nil
+}
+
+// expiryKey идентифицирует подписку подписчика на канал
+func expiryKey(chatID, subscriberID int64) string {
+	return fmt.Sprintf("%d:%d", chatID, subscriberID)
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"errors"
+	"time"
+	"tribute-chatbot/internal/models"
+	"tribute-chatbot/internal/storage"
+)
+
+// ErrInviteNotFound возвращается, если ссылка выдана не ботом или уже удалена
+var ErrInviteNotFound = errors.New("invite link not found")
+
+// inviteRetention время хранения отозванных и использованных ссылок
+const inviteRetention = 30 * 24 * time.Hour
+
+// InviteService хранит персональные пригласительные ссылки подписчиков
+// и отзывает неиспользованные по истечении срока. Ссылки сохраняются на диск,
+// а запланированный отзыв восстанавливается после перезапуска.
+type InviteService struct {
+	links *timerStore[string, models.InviteLink]
+}
+
+// NewInviteService создает сервис и загружает сохраненные ссылки
+func NewInviteService(store *storage.JSONFile) (*InviteService, error) {
+	links, err := newTimerStore(store, timerStoreOptions[string, models.InviteLink]{
+		name: "invite links",
+		key:  func(link *models.InviteLink) string { return link.Name },
+		// Отзывать нужно только неиспользованные ссылки
+		dueAt: func(link *models.InviteLink) (time.Time, bool) {
+			return link.ExpiresAt, link.Status() == models.InviteStatusIssued
+		},
+		// Отозванная ссылка хранится, чтобы показать ее статус
+		done: func(link *models.InviteLink) bool {
+			link.Revoked = true
+			return false
+		},
+		// Давно отозванные и использованные ссылки удаляются
+		keep: func(link *models.InviteLink) bool {
+			now := time.Now()
+			expired := link.Revoked && now.Sub(link.ExpiresAt) > inviteRetention
+			used := !link.UsedAt.IsZero() && now.Sub(link.UsedAt) > inviteRetention
+			return !expired && !used
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &InviteService{links: links}, nil
+}
+
+// Start задает функцию отзыва просроченных ссылок и планирует отзыв загруженных.
+// Просроченные за время простоя ссылки отзываются сразу.
+func (s *InviteService) Start(expire func(models.InviteLink)) {
+	s.links.start(expire)
+}
+
+// Add сохраняет выданную ссылку и планирует ее отзыв
+func (s *InviteService) Add(link models.InviteLink) error {
+	return s.links.put(link)
+}
+
+// Active возвращает действующую неиспользованную ссылку подписчика в канал, если она есть
+func (s *InviteService) Active(chatID, subscriberID int64) (*models.InviteLink, bool) {
+	now := time.Now()
+	link, exists := s.links.find(func(link *models.InviteLink) bool {
+		return link.ChatID == chatID && link.SubscriberID == subscriberID &&
+			link.Status() == models.InviteStatusIssued && now.Before(link.ExpiresAt)
+	})
+	if !exists {
+		return nil, false
+	}
+	return &link, true
+}
+
+// MarkUsed запоминает пользователя, вступившего в канал по ссылке, и отменяет ее отзыв.
+// Ссылка ищется по имени, а если его нет — по самой ссылке.
+func (s *InviteService) MarkUsed(chatID int64, name, inviteLink string, userID int64) (*models.InviteLink, error) {
+	found, exists := s.links.find(func(link *models.InviteLink) bool {
+		return link.ChatID == chatID && (link.Name == name || link.Link == inviteLink)
+	})
+	if !exists {
+		return nil, ErrInviteNotFound
+	}
+
+	link, err := s.links.update(found.Name, func(link *models.InviteLink) {
+		link.UsedBy = userID
+		link.UsedAt = time.Now()
+	})
+	if errors.Is(err, errTimerEntryNotFound) {
+		return nil, ErrInviteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
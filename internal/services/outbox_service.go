@@ -0,0 +1,477 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+	"tribute-chatbot/internal/config"
+	"tribute-chatbot/internal/logger"
+	"tribute-chatbot/internal/models"
+	"tribute-chatbot/internal/storage"
+)
+
+var (
+	// ErrOutboxItemNotFound возвращается, если элемента нет в outbox
+	ErrOutboxItemNotFound = errors.New("outbox item not found")
+	// ErrOutboxItemDiscarded передается обработчику результата при ручном удалении элемента
+	ErrOutboxItemDiscarded = errors.New("outbox item discarded")
+	// ErrOutboxItemInFlight возвращается при удалении элемента, который сейчас доставляется
+	ErrOutboxItemInFlight = errors.New("outbox item delivery in progress")
+)
+
+// OutboxResultHandler получает окончательный результат элемента: nil при доставке,
+// ошибку API при отказе или ErrOutboxItemDiscarded при удалении администратором
+type OutboxResultHandler func(item models.OutboxItem, err error)
+
+// OutboxService сохраняет изменяющие вызовы API на диск перед отправкой
+// и доставляет их фоновым обработчиком с повторами, пока API не примет вызов
+type OutboxService struct {
+	store    *storage.JSONFile
+	backend  BackendClient
+	config   *config.Config
+	items    map[string]*models.OutboxItem
+	inFlight map[string]bool
+	handlers map[string]OutboxResultHandler
+	wake     chan struct{}
+	stop     chan struct{}
+	mutex    sync.Mutex
+	logger   logger.Logger
+}
+
+// NewOutboxService создает outbox и загружает недоставленные элементы
+func NewOutboxService(store *storage.JSONFile, backend BackendClient, cfg *config.Config) (*OutboxService, error) {
+	var saved []*models.OutboxItem
+	if err := store.Load(&saved); err != nil {
+		return nil, fmt.Errorf("failed to load outbox: %w", err)
+	}
+
+	items := make(map[string]*models.OutboxItem, len(saved))
+	for _, item := range saved {
+		items[item.ID] = item
+	}
+
+	return &OutboxService{
+		store:    store,
+		backend:  backend,
+		config:   cfg,
+		items:    items,
+		inFlight: make(map[string]bool),
+		handlers: make(map[string]OutboxResultHandler),
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		logger:   logger.New(),
+	}, nil
+}
+
+// OnResult задает обработчик результата для элементов указанного типа
+func (s *OutboxService) OnResult(kind string, handler OutboxResultHandler) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.handlers[kind] = handler
+}
+
+// EnqueueVerification сохраняет решение по верификации для отправки в API.
+// idempotencyKey должен однозначно определять заявку и решение по ней.
+func (s *OutboxService) EnqueueVerification(decision models.PendingDecision, idempotencyKey string) (*models.OutboxItem, error) {
+	return s.enqueue(models.OutboxKindVerification, idempotencyKey, decision)
+}
+
+// EnqueueChannelRegistration сохраняет регистрацию канала для отправки в API.
+// idempotencyKey должен однозначно определять событие Telegram, вызвавшее регистрацию.
+func (s *OutboxService) EnqueueChannelRegistration(registration models.ChannelRegistration, idempotencyKey string) (*models.OutboxItem, error) {
+	return s.enqueue(models.OutboxKindChannelRegistration, idempotencyKey, registration)
+}
+
+// EnqueueChannelUpdate сохраняет обновление данных канала для отправки в API
+func (s *OutboxService) EnqueueChannelUpdate(update models.ChannelUpdate, idempotencyKey string) (*models.OutboxItem, error) {
+	return s.enqueue(models.OutboxKindChannelUpdate, idempotencyKey, update)
+}
+
+// EnqueueReconciliation сохраняет результат сверки каналов для отправки в API
+func (s *OutboxService) EnqueueReconciliation(reconciliation models.ChannelReconciliation, idempotencyKey string) (*models.OutboxItem, error) {
+	return s.enqueue(models.OutboxKindReconciliation, idempotencyKey, reconciliation)
+}
+
+// EnqueueInviteLink сохраняет статус персональной ссылки для отправки в API
+func (s *OutboxService) EnqueueInviteLink(link models.InviteLink, idempotencyKey string) (*models.OutboxItem, error) {
+	return s.enqueue(models.OutboxKindInviteLink, idempotencyKey, link)
+}
+
+// EnqueueSubscriptionRemoval сохраняет удаление подписчика из канала для отправки в API
+func (s *OutboxService) EnqueueSubscriptionRemoval(subscription models.ExpiredSubscription, idempotencyKey string) (*models.OutboxItem, error) {
+	return s.enqueue(models.OutboxKindSubscriptionRemoval, idempotencyKey, subscription)
+}
+
+// EnqueueChannelDeactivation сохраняет отключение канала для отправки в API
+func (s *OutboxService) EnqueueChannelDeactivation(deactivation models.ChannelDeactivation, idempotencyKey string) (*models.OutboxItem, error) {
+	return s.enqueue(models.OutboxKindChannelDeactivation, idempotencyKey, deactivation)
+}
+
+// EnqueueChannelPermissions сохраняет результат проверки прав бота для отправки в API
+func (s *OutboxService) EnqueueChannelPermissions(report models.ChannelPermissionReport, idempotencyKey string) (*models.OutboxItem, error) {
+	return s.enqueue(models.OutboxKindChannelPermissions, idempotencyKey, report)
+}
+
+// Start запускает фоновую доставку элементов
+func (s *OutboxService) Start() {
+	go func() {
+		ticker := time.NewTicker(s.config.OutboxPollInterval)
+		defer ticker.Stop()
+		for {
+			s.deliverDue()
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			case <-s.wake:
+			}
+		}
+	}()
+}
+
+// Stop останавливает фоновую доставку
+func (s *OutboxService) Stop() {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+}
+
+// Items возвращает копии всех недоставленных элементов, старые первыми
+func (s *OutboxService) Items() []models.OutboxItem {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	items := make([]models.OutboxItem, 0, len(s.items))
+	for _, item := range s.items {
+		items = append(items, *item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].CreatedAt.Before(items[j].CreatedAt) })
+	return items
+}
+
+// Retry возвращает элемент в очередь и запускает доставку немедленно
+func (s *OutboxService) Retry(id string) error {
+	s.mutex.Lock()
+	item, exists := s.items[id]
+	if !exists {
+		s.mutex.Unlock()
+		return ErrOutboxItemNotFound
+	}
+	item.Stuck = false
+	item.NextAttemptAt = time.Now()
+	err := s.saveLocked()
+	s.mutex.Unlock()
+
+	s.notify()
+	return err
+}
+
+// Discard удаляет элемент без доставки. Элемент, который сейчас доставляется,
+// удалить нельзя: API может принять вызов, и обработчик получит оба результата.
+func (s *OutboxService) Discard(id string) error {
+	s.mutex.Lock()
+	item, exists := s.items[id]
+	if !exists {
+		s.mutex.Unlock()
+		return ErrOutboxItemNotFound
+	}
+	if s.inFlight[id] {
+		s.mutex.Unlock()
+		return ErrOutboxItemInFlight
+	}
+	delete(s.items, id)
+	err := s.saveLocked()
+	handler := s.handlers[item.Kind]
+	discarded := *item
+	s.mutex.Unlock()
+
+	s.logger.Warn(fmt.Sprintf("Outbox item discarded: id=%s, kind=%s", discarded.ID, discarded.Kind))
+	if handler != nil {
+		handler(discarded, ErrOutboxItemDiscarded)
+	}
+	return err
+}
+
+// enqueue сохраняет элемент на диск и будит обработчик. Если элемент с тем же
+// ключом идемпотентности уже ждет доставки, новый не создается. Поиск
+// и добавление выполняются под одной блокировкой, чтобы одновременные вызовы
+// с одним ключом не создали два элемента.
+// Без ключа элемент получает собственный ключ на основе ID.
+func (s *OutboxService) enqueue(kind, idempotencyKey string, payload interface{}) (*models.OutboxItem, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal outbox payload: %w", err)
+	}
+	id, err := newOutboxID()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	if idempotencyKey != "" {
+		if existing := s.findByKeyLocked(idempotencyKey); existing != nil {
+			s.mutex.Unlock()
+			s.logger.WithField("idempotency_key", idempotencyKey).Info(
+				fmt.Sprintf("Outbox item already enqueued: id=%s, kind=%s", existing.ID, existing.Kind))
+			return existing, nil
+		}
+	} else {
+		idempotencyKey = "outbox-" + id
+	}
+
+	now := time.Now()
+	item := &models.OutboxItem{
+		ID:             id,
+		Kind:           kind,
+		Payload:        data,
+		IdempotencyKey: idempotencyKey,
+		CreatedAt:      now,
+		NextAttemptAt:  now,
+	}
+	s.items[id] = item
+	if err := s.saveLocked(); err != nil {
+		delete(s.items, id)
+		s.mutex.Unlock()
+		return nil, err
+	}
+	result := *item
+	s.mutex.Unlock()
+
+	s.logger.WithField("idempotency_key", idempotencyKey).Info(fmt.Sprintf("Outbox item enqueued: id=%s, kind=%s", id, kind))
+	s.notify()
+	return &result, nil
+}
+
+// findByKeyLocked возвращает копию элемента с указанным ключом идемпотентности
+func (s *OutboxService) findByKeyLocked(idempotencyKey string) *models.OutboxItem {
+	for _, item := range s.items {
+		if item.IdempotencyKey == idempotencyKey {
+			result := *item
+			return &result
+		}
+	}
+	return nil
+}
+
+// deliverDue доставляет все элементы, срок попытки которых наступил
+func (s *OutboxService) deliverDue() {
+	now := time.Now()
+
+	s.mutex.Lock()
+	var due []models.OutboxItem
+	for _, item := range s.items {
+		if !item.Stuck && !item.NextAttemptAt.After(now) {
+			due = append(due, *item)
+		}
+	}
+	s.mutex.Unlock()
+
+	sort.Slice(due, func(i, j int) bool { return due[i].CreatedAt.Before(due[j].CreatedAt) })
+	for _, item := range due {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		if err := s.deliverItem(item); errors.Is(err, ErrCircuitOpen) {
+			// API недоступно: оставляем элементы до следующей проверки, не тратя попытки
+			return
+		}
+	}
+}
+
+// BackendAvailable сообщает, доставляются ли сейчас вызовы в API
+func (s *OutboxService) BackendAvailable() bool {
+	return s.backend.Available()
+}
+
+// deliverItem выполняет одну попытку доставки элемента и возвращает ее ошибку
+func (s *OutboxService) deliverItem(item models.OutboxItem) error {
+	// Элемент помечается доставляемым под блокировкой, чтобы его нельзя было
+	// удалить, пока вызов в API не завершился
+	s.mutex.Lock()
+	if _, exists := s.items[item.ID]; !exists || s.inFlight[item.ID] {
+		// Элемент удалили после выборки или он уже доставляется
+		s.mutex.Unlock()
+		return nil
+	}
+	s.inFlight[item.ID] = true
+	s.mutex.Unlock()
+
+	requestID := NewRequestID()
+	ctx := WithRequestID(WithIdempotencyKey(context.Background(), item.IdempotencyKey), requestID)
+	log := s.logger.WithField("request_id", requestID).WithField("idempotency_key", item.IdempotencyKey)
+	err := s.deliver(ctx, item)
+
+	s.mutex.Lock()
+	delete(s.inFlight, item.ID)
+	if errors.Is(err, ErrCircuitOpen) {
+		s.mutex.Unlock()
+		return err
+	}
+	stored := s.items[item.ID]
+
+	if err != nil && !isPermanentBackendError(err) {
+		stored.Attempts++
+		stored.LastError = err.Error()
+		stored.NextAttemptAt = time.Now().Add(s.retryDelay(stored.Attempts))
+		if stored.Attempts >= s.config.OutboxMaxAttempts {
+			stored.Stuck = true
+		}
+		attempts, stuck := stored.Attempts, stored.Stuck
+		if saveErr := s.saveLocked(); saveErr != nil {
+			log.Error("Failed to persist outbox:", saveErr)
+		}
+		s.mutex.Unlock()
+
+		if stuck {
+			log.Error(fmt.Sprintf("Outbox item stuck: id=%s, kind=%s, attempts=%d: %v", item.ID, item.Kind, attempts, err))
+		} else {
+			log.Warn(fmt.Sprintf("Outbox delivery failed: id=%s, kind=%s, attempt=%d: %v", item.ID, item.Kind, attempts, err))
+		}
+		return err
+	}
+
+	// Элемент доставлен или окончательно отклонен API
+	delete(s.items, item.ID)
+	if saveErr := s.saveLocked(); saveErr != nil {
+		log.Error("Failed to persist outbox:", saveErr)
+	}
+	handler := s.handlers[item.Kind]
+	final := *stored
+	s.mutex.Unlock()
+
+	if err != nil {
+		log.Error(fmt.Sprintf("Outbox item rejected by API: id=%s, kind=%s: %v", item.ID, item.Kind, err))
+	} else {
+		log.Info(fmt.Sprintf("Outbox item delivered: id=%s, kind=%s", item.ID, item.Kind))
+	}
+	if handler != nil {
+		handler(final, err)
+	}
+	return err
+}
+
+// deliver отправляет элемент в API согласно его типу
+func (s *OutboxService) deliver(ctx context.Context, item models.OutboxItem) error {
+	switch item.Kind {
+	case models.OutboxKindVerification:
+		var decision models.PendingDecision
+		if err := json.Unmarshal(item.Payload, &decision); err != nil {
+			return fmt.Errorf("failed to decode outbox payload: %w", err)
+		}
+		return s.backend.UpdateUserVerification(ctx, decision.UserID, decision.IsVerified, decision.State.Details())
+
+	case models.OutboxKindChannelRegistration:
+		var registration models.ChannelRegistration
+		if err := json.Unmarshal(item.Payload, &registration); err != nil {
+			return fmt.Errorf("failed to decode outbox payload: %w", err)
+		}
+		return s.backend.AddBotToChannel(ctx, registration)
+
+	case models.OutboxKindChannelUpdate:
+		var update models.ChannelUpdate
+		if err := json.Unmarshal(item.Payload, &update); err != nil {
+			return fmt.Errorf("failed to decode outbox payload: %w", err)
+		}
+		return s.backend.UpdateChannel(ctx, update)
+
+	case models.OutboxKindChannelDeactivation:
+		var deactivation models.ChannelDeactivation
+		if err := json.Unmarshal(item.Payload, &deactivation); err != nil {
+			return fmt.Errorf("failed to decode outbox payload: %w", err)
+		}
+		return s.backend.DeactivateChannel(ctx, deactivation)
+
+	case models.OutboxKindChannelPermissions:
+		var report models.ChannelPermissionReport
+		if err := json.Unmarshal(item.Payload, &report); err != nil {
+			return fmt.Errorf("failed to decode outbox payload: %w", err)
+		}
+		return s.backend.ReportChannelPermissions(ctx, report)
+
+	case models.OutboxKindReconciliation:
+		var reconciliation models.ChannelReconciliation
+		if err := json.Unmarshal(item.Payload, &reconciliation); err != nil {
+			return fmt.Errorf("failed to decode outbox payload: %w", err)
+		}
+		return s.backend.ReportReconciliation(ctx, reconciliation)
+
+	case models.OutboxKindInviteLink:
+		var link models.InviteLink
+		if err := json.Unmarshal(item.Payload, &link); err != nil {
+			return fmt.Errorf("failed to decode outbox payload: %w", err)
+		}
+		return s.backend.ReportInviteLink(ctx, link)
+
+	case models.OutboxKindSubscriptionRemoval:
+		var subscription models.ExpiredSubscription
+		if err := json.Unmarshal(item.Payload, &subscription); err != nil {
+			return fmt.Errorf("failed to decode outbox payload: %w", err)
+		}
+		return s.backend.ReportSubscriptionRemoval(ctx, subscription)
+
+	default:
+		return fmt.Errorf("unknown outbox item kind: %s", item.Kind)
+	}
+}
+
+// retryDelay вычисляет задержку перед следующей попыткой
+func (s *OutboxService) retryDelay(attempts int) time.Duration {
+	delay := s.config.OutboxRetryBaseDelay
+	for i := 1; i < attempts && delay < s.config.OutboxRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > s.config.OutboxRetryMaxDelay {
+		delay = s.config.OutboxRetryMaxDelay
+	}
+	return delay
+}
+
+// notify будит фоновый обработчик
+func (s *OutboxService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// saveLocked сохраняет outbox на диск
+func (s *OutboxService) saveLocked() error {
+	items := make([]*models.OutboxItem, 0, len(s.items))
+	for _, item := range s.items {
+		items = append(items, item)
+	}
+	if err := s.store.Save(items); err != nil {
+		return fmt.Errorf("failed to save outbox: %w", err)
+	}
+	return nil
+}
+
+// isPermanentBackendError сообщает, что API отклонило запрос и повтор не поможет
+func isPermanentBackendError(err error) bool {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	code := statusErr.StatusCode
+	return code >= 400 && code < 500 && code != http.StatusRequestTimeout && code != http.StatusTooManyRequests
+}
+
+// newOutboxID генерирует идентификатор элемента
+func newOutboxID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate outbox id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,358 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+	"tribute-chatbot/internal/config"
+	"tribute-chatbot/internal/fakebackend"
+	"tribute-chatbot/internal/models"
+	"tribute-chatbot/internal/storage"
+)
+
+// outboxResult результат элемента, переданный обработчику
+type outboxResult struct {
+	item models.OutboxItem
+	err  error
+}
+
+// newTestOutbox создает outbox поверх фейкового бэкенда. Фоновый обработчик
+// не запускается: тесты доставляют элементы вызовом deliverDue.
+func newTestOutbox(t *testing.T, cfg *config.Config) (*OutboxService, *storage.JSONFile) {
+	t.Helper()
+	store := storage.NewJSONFile(filepath.Join(t.TempDir(), "outbox.json"))
+	outbox, err := NewOutboxService(store, newTestAPIService(t, cfg), cfg)
+	if err != nil {
+		t.Fatalf("outbox: %v", err)
+	}
+	return outbox, store
+}
+
+// collectResults записывает результаты элементов указанного типа
+func collectResults(outbox *OutboxService, kind string) func() []outboxResult {
+	var mu sync.Mutex
+	var results []outboxResult
+	outbox.OnResult(kind, func(item models.OutboxItem, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		results = append(results, outboxResult{item: item, err: err})
+	})
+	return func() []outboxResult {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]outboxResult(nil), results...)
+	}
+}
+
+// waitRetry ждет, пока наступит срок повторной доставки элементов
+func waitRetry(cfg *config.Config) {
+	time.Sleep(2 * cfg.OutboxRetryMaxDelay)
+}
+
+func TestOutboxDeliversWithItemKey(t *testing.T) {
+	backend, cfg := newTestBackend(t)
+	outbox, _ := newTestOutbox(t, cfg)
+	results := collectResults(outbox, models.OutboxKindChannelRegistration)
+
+	item, err := outbox.EnqueueChannelRegistration(testRegistration(-1001), "register-1001")
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	outbox.deliverDue()
+
+	requests := backend.RequestsTo("/v1/add-bot")
+	if len(requests) != 1 {
+		t.Fatalf("requests = %d, want 1", len(requests))
+	}
+	if got := requests[0].Header.Get("Idempotency-Key"); got != "register-1001" {
+		t.Errorf("Idempotency-Key = %q, want register-1001", got)
+	}
+	if requests[0].Header.Get("X-Request-ID") == "" {
+		t.Error("X-Request-ID is empty")
+	}
+	var body struct {
+		UserID    int64 `json:"user_id"`
+		ChannelID int64 `json:"channel_id"`
+	}
+	if err := requests[0].JSON(&body); err != nil || body.UserID != 42 || body.ChannelID != -1001 {
+		t.Errorf("body = %+v, err = %v", body, err)
+	}
+
+	if got := results(); len(got) != 1 || got[0].err != nil || got[0].item.ID != item.ID {
+		t.Fatalf("results = %+v, want one successful delivery of %s", got, item.ID)
+	}
+	if items := outbox.Items(); len(items) != 0 {
+		t.Fatalf("items left: %+v", items)
+	}
+}
+
+func TestOutboxRetriesServerErrors(t *testing.T) {
+	backend, cfg := newTestBackend(t)
+	cfg.BackendMaxRetries = 0
+	outbox, store := newTestOutbox(t, cfg)
+	results := collectResults(outbox, models.OutboxKindVerification)
+	backend.Inject(fakebackend.Fault{Path: "/v1/check-verified-passport", Times: 1, Status: http.StatusInternalServerError})
+
+	if _, err := outbox.EnqueueVerification(models.PendingDecision{UserID: 42, IsVerified: true}, "verification-42"); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	outbox.deliverDue()
+
+	items := outbox.Items()
+	if len(items) != 1 || items[0].Attempts != 1 || items[0].LastError == "" {
+		t.Fatalf("items = %+v, want one failed attempt", items)
+	}
+	if len(results()) != 0 {
+		t.Fatal("result reported before delivery")
+	}
+
+	// Неудачная попытка сохранена на диск и переживает перезапуск
+	restarted, err := NewOutboxService(store, newTestAPIService(t, cfg), cfg)
+	if err != nil {
+		t.Fatalf("reload outbox: %v", err)
+	}
+	if reloaded := restarted.Items(); len(reloaded) != 1 || reloaded[0].Attempts != 1 {
+		t.Fatalf("reloaded items = %+v", reloaded)
+	}
+
+	waitRetry(cfg)
+	outbox.deliverDue()
+
+	requests := backend.RequestsTo("/v1/check-verified-passport")
+	if len(requests) != 2 {
+		t.Fatalf("requests = %d, want 2", len(requests))
+	}
+	// Каждая попытка доставки получает свой ID запроса, а ключ остается прежним
+	if requests[0].Header.Get("Idempotency-Key") != requests[1].Header.Get("Idempotency-Key") {
+		t.Error("Idempotency-Key changed between deliveries")
+	}
+	if requests[0].Header.Get("X-Request-ID") == requests[1].Header.Get("X-Request-ID") {
+		t.Error("X-Request-ID reused between deliveries")
+	}
+	if got := results(); len(got) != 1 || got[0].err != nil {
+		t.Fatalf("results = %+v, want one successful delivery", got)
+	}
+	if verification, ok := backend.State().Verification(42); !ok || !verification.IsVerified {
+		t.Fatalf("backend verification = %+v, want verified", verification)
+	}
+}
+
+func TestOutboxRetriesTimeouts(t *testing.T) {
+	backend, cfg := newTestBackend(t)
+	cfg.BackendMaxRetries = 0
+	cfg.BackendTimeout = 50 * time.Millisecond
+	outbox, _ := newTestOutbox(t, cfg)
+	results := collectResults(outbox, models.OutboxKindChannelRegistration)
+	backend.Inject(fakebackend.Fault{Path: "/v1/add-bot", Times: 1, Latency: time.Second})
+
+	if _, err := outbox.EnqueueChannelRegistration(testRegistration(-1001), "register-1001"); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	outbox.deliverDue()
+	if items := outbox.Items(); len(items) != 1 || items[0].Attempts != 1 {
+		t.Fatalf("items = %+v, want one timed out attempt", items)
+	}
+
+	waitRetry(cfg)
+	outbox.deliverDue()
+
+	if got := results(); len(got) != 1 || got[0].err != nil {
+		t.Fatalf("results = %+v, want one successful delivery", got)
+	}
+	if channels := backend.State().Channels(); len(channels) != 1 {
+		t.Fatalf("channels = %d, want 1", len(channels))
+	}
+}
+
+func TestOutboxStopsAfterMaxAttempts(t *testing.T) {
+	backend, cfg := newTestBackend(t)
+	cfg.BackendMaxRetries = 0
+	outbox, _ := newTestOutbox(t, cfg)
+	results := collectResults(outbox, models.OutboxKindChannelRegistration)
+	backend.Inject(fakebackend.Fault{Path: "/v1/add-bot", Status: http.StatusServiceUnavailable})
+
+	if _, err := outbox.EnqueueChannelRegistration(testRegistration(-1001), "register-1001"); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	for i := 0; i < cfg.OutboxMaxAttempts+1; i++ {
+		outbox.deliverDue()
+		waitRetry(cfg)
+	}
+
+	items := outbox.Items()
+	if len(items) != 1 || !items[0].Stuck || items[0].Attempts != cfg.OutboxMaxAttempts {
+		t.Fatalf("items = %+v, want one stuck item", items)
+	}
+	if requests := backend.RequestsTo("/v1/add-bot"); len(requests) != cfg.OutboxMaxAttempts {
+		t.Fatalf("requests = %d, want %d", len(requests), cfg.OutboxMaxAttempts)
+	}
+	if len(results()) != 0 {
+		t.Fatal("stuck item reported as final")
+	}
+
+	// Ручной повтор доставляет элемент, когда API снова работает
+	backend.Reset()
+	if err := outbox.Retry(items[0].ID); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	outbox.deliverDue()
+	if got := results(); len(got) != 1 || got[0].err != nil {
+		t.Fatalf("results = %+v, want one successful delivery", got)
+	}
+}
+
+func TestOutboxPermanentErrorIsFinal(t *testing.T) {
+	backend, cfg := newTestBackend(t)
+	outbox, _ := newTestOutbox(t, cfg)
+	results := collectResults(outbox, models.OutboxKindChannelRegistration)
+	backend.Inject(fakebackend.Fault{
+		Path:    "/v1/add-bot",
+		Times:   1,
+		Status:  http.StatusUnprocessableEntity,
+		Code:    ErrorCodeValidation,
+		Message: "invalid payload",
+	})
+
+	if _, err := outbox.EnqueueChannelRegistration(testRegistration(-1001), "register-1001"); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	outbox.deliverDue()
+
+	got := results()
+	if len(got) != 1 || !errors.Is(got[0].err, ErrValidation) {
+		t.Fatalf("results = %+v, want validation error", got)
+	}
+	if items := outbox.Items(); len(items) != 0 {
+		t.Fatalf("items left: %+v", items)
+	}
+	if requests := backend.RequestsTo("/v1/add-bot"); len(requests) != 1 {
+		t.Fatalf("requests = %d, want 1", len(requests))
+	}
+}
+
+func TestOutboxDeduplicatesConcurrentEnqueue(t *testing.T) {
+	backend, cfg := newTestBackend(t)
+	outbox, _ := newTestOutbox(t, cfg)
+
+	ids := make(chan string, concurrency)
+	runConcurrently(concurrency, func(i int) {
+		item, err := outbox.EnqueueChannelRegistration(testRegistration(-1001), "register-1001")
+		if err != nil {
+			t.Errorf("enqueue: %v", err)
+			return
+		}
+		ids <- item.ID
+	})
+	close(ids)
+
+	unique := make(map[string]bool)
+	for id := range ids {
+		unique[id] = true
+	}
+	if len(unique) != 1 {
+		t.Fatalf("enqueued items = %d, want 1", len(unique))
+	}
+
+	outbox.deliverDue()
+	if requests := backend.RequestsTo("/v1/add-bot"); len(requests) != 1 {
+		t.Fatalf("requests = %d, want 1", len(requests))
+	}
+}
+
+func TestOutboxDuplicateDeliveryReplayed(t *testing.T) {
+	backend, cfg := newTestBackend(t)
+	outbox, _ := newTestOutbox(t, cfg)
+	results := collectResults(outbox, models.OutboxKindChannelRegistration)
+
+	// Тот же вызов, поставленный в очередь после доставки, например после перезапуска
+	// до сохранения результата, API повторяет по ключу и не выполняет второй раз
+	for i := 0; i < 2; i++ {
+		if _, err := outbox.EnqueueChannelRegistration(testRegistration(-1001), "register-1001"); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+		outbox.deliverDue()
+	}
+
+	got := results()
+	if len(got) != 2 || got[0].err != nil || got[1].err != nil {
+		t.Fatalf("results = %+v, want two successful deliveries", got)
+	}
+	if requests := backend.RequestsTo("/v1/add-bot"); len(requests) != 2 {
+		t.Fatalf("requests = %d, want 2", len(requests))
+	}
+	if channels := backend.State().Channels(); len(channels) != 1 {
+		t.Fatalf("channels = %d, want 1", len(channels))
+	}
+}
+
+func TestOutboxKeepsAttemptsWhileCircuitOpen(t *testing.T) {
+	backend, cfg := newTestBackend(t)
+	cfg.BackendMaxRetries = 0
+	cfg.BackendBreakerFailureThreshold = 1
+	cfg.BackendBreakerOpenTimeout = time.Hour
+	cfg.BackendBreakerHalfOpenSuccesses = 1
+	outbox, _ := newTestOutbox(t, cfg)
+	backend.Inject(fakebackend.Fault{Path: "/v1/add-bot", Status: http.StatusBadGateway})
+
+	if _, err := outbox.EnqueueChannelRegistration(testRegistration(-1001), "register-1001"); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		outbox.deliverDue()
+		waitRetry(cfg)
+	}
+
+	// После размыкания выключателя попытки не тратятся и запросы не отправляются
+	items := outbox.Items()
+	if len(items) != 1 || items[0].Attempts != 1 || items[0].Stuck {
+		t.Fatalf("items = %+v, want one attempt", items)
+	}
+	if outbox.BackendAvailable() {
+		t.Fatal("backend reported available with open circuit")
+	}
+	if requests := backend.RequestsTo("/v1/add-bot"); len(requests) != 1 {
+		t.Fatalf("requests = %d, want 1", len(requests))
+	}
+}
+
+func TestOutboxRefusesDiscardDuringDelivery(t *testing.T) {
+	backend, cfg := newTestBackend(t)
+	outbox, _ := newTestOutbox(t, cfg)
+	results := collectResults(outbox, models.OutboxKindChannelRegistration)
+	backend.Inject(fakebackend.Fault{Path: "/v1/add-bot", Times: 1, Latency: 200 * time.Millisecond})
+
+	item, err := outbox.EnqueueChannelRegistration(testRegistration(-1001), "register-1001")
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	delivered := make(chan struct{})
+	go func() {
+		defer close(delivered)
+		outbox.deliverDue()
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(backend.RequestsTo("/v1/add-bot")) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("delivery did not start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Вызов уже отправлен в API: удаление отклоняется, а не теряет результат
+	if err := outbox.Discard(item.ID); !errors.Is(err, ErrOutboxItemInFlight) {
+		t.Fatalf("discard in flight: err = %v, want %v", err, ErrOutboxItemInFlight)
+	}
+	<-delivered
+
+	got := results()
+	if len(got) != 1 || got[0].err != nil {
+		t.Fatalf("results = %+v, want one delivery", got)
+	}
+	if err := outbox.Discard(item.ID); !errors.Is(err, ErrOutboxItemNotFound) {
+		t.Fatalf("discard delivered: err = %v, want %v", err, ErrOutboxItemNotFound)
+	}
+}
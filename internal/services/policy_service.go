@@ -0,0 +1,57 @@
+package services
+
+import (
+	"time"
+	"tribute-chatbot/internal/config"
+	"tribute-chatbot/internal/models"
+)
+
+// PolicyService проверяет данные личности по настроенным правилам:
+// минимальный возраст и оставшийся срок действия документа
+type PolicyService struct {
+	config *config.Config
+}
+
+// NewPolicyService создает сервис проверки правил
+func NewPolicyService(cfg *config.Config) *PolicyService {
+	return &PolicyService{config: cfg}
+}
+
+// Evaluate проверяет данные личности на момент now
+func (s *PolicyService) Evaluate(identity models.IdentityData, now time.Time) models.PolicyResult {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	result := models.PolicyResult{
+		Age:               ageAt(identity.BirthDate, today),
+		DocumentValidDays: int(identity.ExpiryDate.Sub(today).Hours() / 24),
+	}
+
+	if s.config.PolicyMinAge > 0 && result.Age < s.config.PolicyMinAge {
+		result.Violations = append(result.Violations, models.PolicyViolation{
+			Rule:     models.PolicyRuleMinAge,
+			Required: s.config.PolicyMinAge,
+			Actual:   result.Age,
+		})
+	}
+
+	// Документ с истекшим сроком недействителен при любой настройке
+	if result.DocumentValidDays < s.config.PolicyMinDocumentValidityDays || result.DocumentValidDays < 0 {
+		result.Violations = append(result.Violations, models.PolicyViolation{
+			Rule:     models.PolicyRuleDocumentValidity,
+			Required: s.config.PolicyMinDocumentValidityDays,
+			Actual:   result.DocumentValidDays,
+		})
+	}
+
+	result.Passed = len(result.Violations) == 0
+	return result
+}
+
+// ageAt возвращает полное число лет на дату
+func ageAt(birthDate, date time.Time) int {
+	age := date.Year() - birthDate.Year()
+	if date.Month() < birthDate.Month() || (date.Month() == birthDate.Month() && date.Day() < birthDate.Day()) {
+		age--
+	}
+	return age
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+	"tribute-chatbot/internal/config"
+	"tribute-chatbot/internal/logger"
+	"tribute-chatbot/internal/models"
+	"tribute-chatbot/internal/screening"
+)
+
+// maxScreeningMatches ограничивает число совпадений в результате
+const maxScreeningMatches = 3
+
+// ScreeningService проверяет имена и даты рождения по локальному списку.
+// Файл списка перечитывается при изменении без перезапуска бота.
+type ScreeningService struct {
+	config  *config.Config
+	entries []screening.Entry
+	modTime time.Time
+	stop    chan struct{}
+	mutex   sync.RWMutex
+	logger  logger.Logger
+}
+
+// NewScreeningService создает сервис и загружает список, если путь к нему задан
+func NewScreeningService(cfg *config.Config) (*ScreeningService, error) {
+	s := &ScreeningService{
+		config: cfg,
+		stop:   make(chan struct{}),
+		logger: logger.New(),
+	}
+	if !s.Enabled() {
+		return s, nil
+	}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Enabled сообщает, настроена ли проверка по списку
+func (s *ScreeningService) Enabled() bool {
+	return s.config.ScreeningListPath != ""
+}
+
+// Reload перечитывает файл списка. При ошибке прежний список сохраняется.
+func (s *ScreeningService) Reload() error {
+	info, err := os.Stat(s.config.ScreeningListPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat screening list: %w", err)
+	}
+
+	entries, err := screening.LoadFile(s.config.ScreeningListPath)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	s.entries = entries
+	s.modTime = info.ModTime()
+	s.mutex.Unlock()
+
+	s.logger.Info(fmt.Sprintf("Screening list loaded: %d entries from %s", len(entries), s.config.ScreeningListPath))
+	return nil
+}
+
+// StartWatching периодически проверяет файл списка и перечитывает его при изменении
+func (s *ScreeningService) StartWatching() {
+	if !s.Enabled() || s.config.ScreeningReloadInterval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.config.ScreeningReloadInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.reloadIfChanged()
+			}
+		}
+	}()
+}
+
+// Stop останавливает отслеживание файла списка
+func (s *ScreeningService) Stop() {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+}
+
+// Screen проверяет данные личности по списку
+func (s *ScreeningService) Screen(identity models.IdentityData) models.ScreeningResult {
+	s.mutex.RLock()
+	entries := s.entries
+	s.mutex.RUnlock()
+
+	name := identity.Surname + " " + identity.GivenNames
+	found := screening.Screen(entries, name, identity.BirthDate, s.config.ScreeningThreshold)
+	if len(found) > maxScreeningMatches {
+		found = found[:maxScreeningMatches]
+	}
+
+	result := models.ScreeningResult{}
+	for _, match := range found {
+		result.Matches = append(result.Matches, models.ScreeningMatch{
+			EntryID:        match.Entry.ID,
+			Name:           match.Entry.Name,
+			Source:         match.Entry.Source,
+			Score:          match.Score,
+			BirthDateMatch: match.BirthDateMatch,
+		})
+	}
+	result.Held = len(result.Matches) > 0 && s.config.ScreeningAction == config.ScreeningActionHold
+	return result
+}
+
+// reloadIfChanged перечитывает список, если файл изменился
+func (s *ScreeningService) reloadIfChanged() {
+	info, err := os.Stat(s.config.ScreeningListPath)
+	if err != nil {
+		s.logger.Error("Failed to stat screening list:", err)
+		return
+	}
+
+	s.mutex.RLock()
+	changed := !info.ModTime().Equal(s.modTime)
+	s.mutex.RUnlock()
+
+	if changed {
+		if err := s.Reload(); err != nil {
+			s.logger.Error("Failed to reload screening list, keeping previous version:", err)
+		}
+	}
+}
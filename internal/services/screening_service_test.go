@@ -0,0 +1,48 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"tribute-chatbot/internal/config"
+	"tribute-chatbot/internal/models"
+)
+
+func TestScreeningServiceHold(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list.csv")
+	list := "id,name,aliases,birth_date,source\n1,Ivan Pavlov,,1980-05-01,test\n2,Oleg Sidorov,Иван Петров,,test\n"
+	if err := os.WriteFile(path, []byte(list), 0o600); err != nil {
+		t.Fatalf("write list: %v", err)
+	}
+	birthDate := time.Date(1980, time.May, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name     string
+		action   string
+		identity models.IdentityData
+		matches  int
+		held     bool
+	}{
+		{"transliterated alias held", config.ScreeningActionHold, models.IdentityData{Surname: "PETROV", GivenNames: "IVAN"}, 1, true},
+		{"transliterated alias flagged", config.ScreeningActionFlag, models.IdentityData{Surname: "PETROV", GivenNames: "IVAN"}, 1, false},
+		{"below threshold", config.ScreeningActionHold, models.IdentityData{Surname: "PETROVSKY", GivenNames: "IGOR"}, 0, false},
+		{"birth date lifts name above threshold", config.ScreeningActionHold, models.IdentityData{Surname: "PETROV", GivenNames: "IVAN", BirthDate: birthDate}, 2, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := NewScreeningService(&config.Config{
+				ScreeningListPath:  path,
+				ScreeningThreshold: 0.88,
+				ScreeningAction:    tc.action,
+			})
+			if err != nil {
+				t.Fatalf("screening service: %v", err)
+			}
+
+			result := s.Screen(tc.identity)
+			if len(result.Matches) != tc.matches || result.Held != tc.held {
+				t.Fatalf("result = %+v, want %d matches, held %t", result, tc.matches, tc.held)
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"tribute-chatbot/internal/logger"
+	"tribute-chatbot/internal/storage"
+)
+
+var (
+	// errTimerEntryNotFound возвращается, если записи нет или она уже обрабатывается
+	errTimerEntryNotFound = errors.New("timer entry not found")
+	// errTimerEntryExists возвращается при добавлении записи с занятым ключом
+	errTimerEntryExists = errors.New("timer entry already exists")
+)
+
+// timerStoreOptions описывает записи, которые хранит timerStore
+type timerStoreOptions[K comparable, V any] struct {
+	// name название записей в сообщениях об ошибках
+	name string
+	// key возвращает ключ записи
+	key func(*V) K
+	// dueAt возвращает время срабатывания записи; false — таймер не нужен
+	dueAt func(*V) (time.Time, bool)
+	// done вызывается после обработчика и сообщает, удалить ли запись; nil — удалить
+	done func(*V) bool
+	// keep отбирает записи при сохранении, остальные удаляются; nil — хранить все
+	keep func(*V) bool
+}
+
+// timerStore хранит записи на диске и вызывает обработчик, когда наступает
+// их время. Запись остается на диске, пока обработчик не завершится, поэтому
+// после падения она будет обработана при следующем запуске.
+type timerStore[K comparable, V any] struct {
+	timerStoreOptions[K, V]
+	store   *storage.JSONFile
+	entries map[K]*V
+	timers  map[K]*time.Timer
+	firing  map[K]*V
+	handle  func(V)
+	mutex   sync.Mutex
+	logger  logger.Logger
+}
+
+// newTimerStore создает хранилище и загружает сохраненные записи
+func newTimerStore[K comparable, V any](store *storage.JSONFile, options timerStoreOptions[K, V]) (*timerStore[K, V], error) {
+	var saved []*V
+	if err := store.Load(&saved); err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", options.name, err)
+	}
+
+	entries := make(map[K]*V, len(saved))
+	for _, entry := range saved {
+		entries[options.key(entry)] = entry
+	}
+
+	return &timerStore[K, V]{
+		timerStoreOptions: options,
+		store:             store,
+		entries:           entries,
+		timers:            make(map[K]*time.Timer),
+		firing:            make(map[K]*V),
+		logger:            logger.New(),
+	}, nil
+}
+
+// start задает обработчик и планирует загруженные записи.
+// Просроченные за время простоя записи обрабатываются сразу.
+func (s *timerStore[K, V]) start(handle func(V)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.handle = handle
+	for _, entry := range s.entries {
+		s.scheduleLocked(entry)
+	}
+}
+
+// get возвращает копию записи
+func (s *timerStore[K, V]) get(key K) (V, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entry, exists := s.entries[key]
+	if !exists {
+		var zero V
+		return zero, false
+	}
+	return *entry, true
+}
+
+// values возвращает копии всех записей
+func (s *timerStore[K, V]) values() []V {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	values := make([]V, 0, len(s.entries))
+	for _, entry := range s.entries {
+		values = append(values, *entry)
+	}
+	return values
+}
+
+// find возвращает копию первой записи, для которой match вернула true
+func (s *timerStore[K, V]) find(match func(*V) bool) (V, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, entry := range s.entries {
+		if match(entry) {
+			return *entry, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// add сохраняет новую запись и планирует ее; занятый ключ — errTimerEntryExists
+func (s *timerStore[K, V]) add(value V) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.entries[s.key(&value)]; exists {
+		return errTimerEntryExists
+	}
+	return s.putLocked(&value)
+}
+
+// put сохраняет запись, заменяя прежнюю с тем же ключом, и планирует ее.
+// Если сохранить не удалось, прежняя запись восстанавливается.
+func (s *timerStore[K, V]) put(value V) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.putLocked(&value)
+}
+
+// update изменяет запись, сохраняет ее и перепланирует таймер
+func (s *timerStore[K, V]) update(key K, fn func(*V)) (V, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entry, exists := s.entries[key]
+	if !exists {
+		var zero V
+		return zero, errTimerEntryNotFound
+	}
+	fn(entry)
+	s.stopLocked(key)
+	s.scheduleLocked(entry)
+	return *entry, s.saveLocked()
+}
+
+// remove удаляет запись и отменяет ее таймер. Запись, которая уже
+// обрабатывается, не удаляется.
+func (s *timerStore[K, V]) remove(key K) (V, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entry, exists := s.entries[key]
+	if !exists || s.firing[key] == entry {
+		var zero V
+		return zero, false
+	}
+
+	s.stopLocked(key)
+	delete(s.entries, key)
+	if err := s.saveLocked(); err != nil {
+		s.logger.Error(fmt.Sprintf("Failed to persist %s:", s.name), err)
+	}
+	return *entry, true
+}
+
+// putLocked сохраняет запись и планирует ее, при ошибке возвращает прежнюю
+func (s *timerStore[K, V]) putLocked(entry *V) error {
+	key := s.key(entry)
+	previous := s.entries[key]
+	s.stopLocked(key)
+
+	s.entries[key] = entry
+	if err := s.saveLocked(); err != nil {
+		if previous != nil {
+			s.entries[key] = previous
+			s.scheduleLocked(previous)
+		} else {
+			delete(s.entries, key)
+		}
+		return err
+	}
+
+	s.scheduleLocked(entry)
+	return nil
+}
+
+// scheduleLocked запускает таймер записи
+func (s *timerStore[K, V]) scheduleLocked(entry *V) {
+	if s.handle == nil {
+		// Запись будет запланирована при вызове start
+		return
+	}
+	dueAt, ok := s.dueAt(entry)
+	if !ok {
+		return
+	}
+
+	key := s.key(entry)
+	delay := time.Until(dueAt)
+	if delay < 0 {
+		delay = 0
+	}
+	s.timers[key] = time.AfterFunc(delay, func() { s.fire(key) })
+}
+
+// stopLocked останавливает таймер записи
+func (s *timerStore[K, V]) stopLocked(key K) {
+	if timer := s.timers[key]; timer != nil {
+		timer.Stop()
+		delete(s.timers, key)
+	}
+}
+
+// fire обрабатывает запись, время которой наступило
+func (s *timerStore[K, V]) fire(key K) {
+	s.mutex.Lock()
+	entry, exists := s.entries[key]
+	if !exists || s.firing[key] == entry {
+		// Запись уже удалена или обрабатывается
+		s.mutex.Unlock()
+		return
+	}
+	dueAt, ok := s.dueAt(entry)
+	if !ok {
+		s.mutex.Unlock()
+		return
+	}
+	if time.Now().Before(dueAt) {
+		// Запись заменена более поздней или часы сдвинулись назад
+		s.stopLocked(key)
+		s.scheduleLocked(entry)
+		s.mutex.Unlock()
+		return
+	}
+	s.firing[key] = entry
+	delete(s.timers, key)
+	handle := s.handle
+	snapshot := *entry
+	s.mutex.Unlock()
+
+	handle(snapshot)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.firing, key)
+	if s.entries[key] != entry {
+		// Пока обработчик работал, запись заменили новой
+		return
+	}
+	if s.done == nil || s.done(entry) {
+		delete(s.entries, key)
+	}
+	if err := s.saveLocked(); err != nil {
+		s.logger.Error(fmt.Sprintf("Failed to persist %s:", s.name), err)
+	}
+}
+
+// saveLocked сохраняет записи на диск, удаляя те, которые не нужно хранить
+func (s *timerStore[K, V]) saveLocked() error {
+	entries := make([]*V, 0, len(s.entries))
+	for key, entry := range s.entries {
+		if s.keep != nil && !s.keep(entry) {
+			delete(s.entries, key)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := s.store.Save(entries); err != nil {
+		return fmt.Errorf("failed to save %s: %w", s.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+	"tribute-chatbot/internal/storage"
+)
+
+// timerEntry запись для проверки timerStore
+type timerEntry struct {
+	ID    int64     `json:"id"`
+	DueAt time.Time `json:"due_at"`
+	Fired bool      `json:"fired"`
+}
+
+// newTestTimerStore создает хранилище записей в файле path
+func newTestTimerStore(t *testing.T, path string, done func(*timerEntry) bool) *timerStore[int64, timerEntry] {
+	t.Helper()
+	store, err := newTimerStore(storage.NewJSONFile(path), timerStoreOptions[int64, timerEntry]{
+		name:  "test entries",
+		key:   func(entry *timerEntry) int64 { return entry.ID },
+		dueAt: func(entry *timerEntry) (time.Time, bool) { return entry.DueAt, !entry.Fired },
+		done:  done,
+	})
+	if err != nil {
+		t.Fatalf("timer store: %v", err)
+	}
+	return store
+}
+
+// waitFired ждет срабатывания обработчика
+func waitFired(t *testing.T, fired <-chan timerEntry) timerEntry {
+	t.Helper()
+	select {
+	case entry := <-fired:
+		return entry
+	case <-time.After(5 * time.Second):
+		t.Fatal("timer did not fire")
+		return timerEntry{}
+	}
+}
+
+func TestTimerStoreRestoresAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "entries.json")
+	before := newTestTimerStore(t, path, nil)
+	if err := before.put(timerEntry{ID: 1, DueAt: time.Now().Add(20 * time.Millisecond)}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if err := before.put(timerEntry{ID: 2, DueAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	// Процесс перезапущен без вызова start: просроченная запись срабатывает сразу
+	time.Sleep(30 * time.Millisecond)
+	after := newTestTimerStore(t, path, nil)
+	fired := make(chan timerEntry, 2)
+	after.start(func(entry timerEntry) { fired <- entry })
+
+	if entry := waitFired(t, fired); entry.ID != 1 {
+		t.Fatalf("fired entry = %d, want 1", entry.ID)
+	}
+	if _, exists := after.remove(2); !exists {
+		t.Fatal("pending entry not restored")
+	}
+
+	// После обработки и отмены на диске ничего не осталось
+	reloaded := newTestTimerStore(t, path, nil)
+	if values := reloaded.values(); len(values) != 0 {
+		t.Fatalf("saved entries = %+v, want none", values)
+	}
+	select {
+	case entry := <-fired:
+		t.Fatalf("cancelled entry %d fired", entry.ID)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestTimerStoreKeepsEntryWhileFiring(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "entries.json")
+	store := newTestTimerStore(t, path, nil)
+	started := make(chan timerEntry)
+	release := make(chan struct{})
+	store.start(func(entry timerEntry) {
+		started <- entry
+		<-release
+	})
+	if err := store.add(timerEntry{ID: 1, DueAt: time.Now()}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	waitFired(t, started)
+
+	// Пока обработчик работает, запись нельзя отменить или добавить заново,
+	// а на диске она сохраняется на случай падения
+	if _, removed := store.remove(1); removed {
+		t.Fatal("firing entry removed")
+	}
+	if err := store.add(timerEntry{ID: 1, DueAt: time.Now()}); err != errTimerEntryExists {
+		t.Fatalf("add firing entry: err = %v, want %v", err, errTimerEntryExists)
+	}
+	if values := newTestTimerStore(t, path, nil).values(); len(values) != 1 {
+		t.Fatalf("saved entries = %d, want 1", len(values))
+	}
+
+	close(release)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(store.values()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("entry not removed after firing")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestTimerStoreDoneKeepsEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "entries.json")
+	fired := make(chan timerEntry, 1)
+	store := newTestTimerStore(t, path, func(entry *timerEntry) bool {
+		entry.Fired = true
+		return false
+	})
+	store.start(func(entry timerEntry) { fired <- entry })
+	if err := store.put(timerEntry{ID: 1, DueAt: time.Now()}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	waitFired(t, fired)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if entry, _ := store.get(1); entry.Fired {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("entry not marked after firing")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Отмеченная запись не планируется снова после перезапуска
+	restarted := newTestTimerStore(t, path, nil)
+	restarted.start(func(entry timerEntry) { fired <- entry })
+	select {
+	case entry := <-fired:
+		t.Fatalf("entry %d fired again", entry.ID)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
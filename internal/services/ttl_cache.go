@@ -0,0 +1,116 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// ttlEntry значение в кэше и время его устаревания. Запись без значения
+// только помнит время последнего сброса ключа.
+type ttlEntry[V any] struct {
+	value         V
+	cached        bool
+	expiresAt     time.Time
+	invalidatedAt time.Time
+}
+
+// ttlCache кэш значений на время ttl. Устаревшие записи удаляются не реже
+// одного раза за ttl, поэтому кэш не растет с каждым новым ключом.
+// Нулевой ttl отключает кэш.
+type ttlCache[K comparable, V any] struct {
+	ttl      time.Duration
+	entries  map[K]ttlEntry[V]
+	prunedAt time.Time
+	mutex    sync.Mutex
+}
+
+// newTTLCache создает кэш со временем хранения ttl
+func newTTLCache[K comparable, V any](ttl time.Duration) *ttlCache[K, V] {
+	return &ttlCache[K, V]{
+		ttl:      ttl,
+		entries:  make(map[K]ttlEntry[V]),
+		prunedAt: time.Now(),
+	}
+}
+
+// Get возвращает значение, если оно есть в кэше и не устарело
+func (c *ttlCache[K, V]) Get(key K) (V, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !entry.cached || !time.Now().Before(entry.expiresAt) {
+		var zero V
+		return zero, false
+	}
+	return entry.value, true
+}
+
+// Set сохраняет значение и удаляет устаревшие записи, если с прошлой очистки прошло ttl
+func (c *ttlCache[K, V]) Set(key K, value V) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.setLocked(key, value, time.Now())
+}
+
+// SetIfNewer сохраняет значение, полученное запросом, начатым в startedAt.
+// Результат отбрасывается, если ключ сброшен после начала запроса или запрос
+// длился не меньше ttl: время такого сброса кэш мог уже забыть.
+func (c *ttlCache[K, V]) SetIfNewer(key K, value V, startedAt time.Time) bool {
+	if c.ttl <= 0 {
+		return false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(startedAt) >= c.ttl || !startedAt.After(c.entries[key].invalidatedAt) {
+		return false
+	}
+	c.setLocked(key, value, now)
+	return true
+}
+
+// Delete удаляет значение из кэша и запоминает время сброса на ttl
+func (c *ttlCache[K, V]) Delete(key K) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	c.pruneLocked(now)
+	c.entries[key] = ttlEntry[V]{expiresAt: now.Add(c.ttl), invalidatedAt: now}
+}
+
+// setLocked сохраняет значение, сохраняя время последнего сброса ключа
+func (c *ttlCache[K, V]) setLocked(key K, value V, now time.Time) {
+	c.pruneLocked(now)
+	c.entries[key] = ttlEntry[V]{
+		value:         value,
+		cached:        true,
+		expiresAt:     now.Add(c.ttl),
+		invalidatedAt: c.entries[key].invalidatedAt,
+	}
+}
+
+// pruneLocked удаляет устаревшие записи, если с прошлой очистки прошло ttl
+func (c *ttlCache[K, V]) pruneLocked(now time.Time) {
+	if now.Sub(c.prunedAt) < c.ttl {
+		return
+	}
+	for k, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+	c.prunedAt = now
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+	"tribute-chatbot/internal/models"
+)
+
+// UserService получает профили пользователей из API и кэширует их на время ttl.
+// Бот сбрасывает кэш пользователя сам, когда меняет его данные в бэкенде.
+type UserService struct {
+	backend       BackendClient
+	lookupTimeout time.Duration
+	// cache хранит nil для пользователей, которых нет в бэкенде
+	cache *ttlCache[int64, *models.UserProfile]
+}
+
+// NewUserService создает сервис профилей. Нулевой ttl отключает кэш.
+func NewUserService(backend BackendClient, ttl, lookupTimeout time.Duration) *UserService {
+	return &UserService{
+		backend:       backend,
+		lookupTimeout: lookupTimeout,
+		cache:         newTTLCache[int64, *models.UserProfile](ttl),
+	}
+}
+
+// Get возвращает профиль пользователя из кэша или API. Для пользователя,
+// которого нет в бэкенде, возвращается ErrUserNotFound. Запрос вместе
+// с повторами ограничен lookupTimeout, чтобы обработчики не ждали недоступное API.
+// Ответ, полученный на запрос, начатый до Invalidate, в кэш не попадает.
+func (s *UserService) Get(ctx context.Context, userID int64) (*models.UserProfile, error) {
+	if cached, ok := s.cache.Get(userID); ok {
+		if cached == nil {
+			return nil, ErrUserNotFound
+		}
+		return cloneProfile(cached), nil
+	}
+
+	if s.lookupTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.lookupTimeout)
+		defer cancel()
+	}
+
+	startedAt := time.Now()
+	profile, err := s.backend.GetUser(ctx, userID)
+	if err != nil && !errors.Is(err, ErrUserNotFound) {
+		return nil, err
+	}
+
+	s.cache.SetIfNewer(userID, cloneProfile(profile), startedAt)
+	if err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// Invalidate удаляет профиль пользователя из кэша. Запросы профиля,
+// начатые раньше, кэш уже не заполнят.
+func (s *UserService) Invalidate(userID int64) {
+	s.cache.Delete(userID)
+}
+
+// cloneProfile копирует профиль вместе со списком каналов
+func cloneProfile(profile *models.UserProfile) *models.UserProfile {
+	if profile == nil {
+		return nil
+	}
+	clone := *profile
+	clone.Channels = append([]models.ChannelSummary(nil), profile.Channels...)
+	return &clone
+}
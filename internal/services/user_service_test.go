@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+	"tribute-chatbot/internal/models"
+)
+
+// blockingBackend отвечает на GetUser, только когда тест пришлет профиль
+type blockingBackend struct {
+	BackendClient
+	started  chan struct{}
+	profiles chan *models.UserProfile
+}
+
+func newBlockingBackend() *blockingBackend {
+	return &blockingBackend{
+		started:  make(chan struct{}, 1),
+		profiles: make(chan *models.UserProfile),
+	}
+}
+
+func (b *blockingBackend) GetUser(ctx context.Context, userID int64) (*models.UserProfile, error) {
+	b.started <- struct{}{}
+	return <-b.profiles, nil
+}
+
+// get запускает Get и возвращает канал с его результатом
+func get(s *UserService, userID int64) <-chan *models.UserProfile {
+	result := make(chan *models.UserProfile, 1)
+	go func() {
+		profile, err := s.Get(context.Background(), userID)
+		if err != nil {
+			profile = nil
+		}
+		result <- profile
+	}()
+	return result
+}
+
+func TestUserServiceCachesProfile(t *testing.T) {
+	backend := newBlockingBackend()
+	s := NewUserService(backend, time.Minute, 0)
+
+	result := get(s, 42)
+	<-backend.started
+	backend.profiles <- &models.UserProfile{IsVerified: true}
+	if profile := <-result; profile == nil || !profile.IsVerified {
+		t.Fatalf("profile = %+v, want verified", profile)
+	}
+
+	// Повторный запрос отвечает из кэша, не обращаясь к API
+	if profile := <-get(s, 42); profile == nil || !profile.IsVerified {
+		t.Fatalf("cached profile = %+v, want verified", profile)
+	}
+	select {
+	case <-backend.started:
+		t.Fatal("cached profile requested from API")
+	default:
+	}
+}
+
+func TestUserServiceDropsLookupStartedBeforeInvalidate(t *testing.T) {
+	backend := newBlockingBackend()
+	s := NewUserService(backend, time.Minute, 0)
+
+	stale := get(s, 42)
+	<-backend.started
+	// Бот меняет данные пользователя, пока запрос профиля еще идет
+	s.Invalidate(42)
+	backend.profiles <- &models.UserProfile{IsVerified: false}
+	if profile := <-stale; profile == nil || profile.IsVerified {
+		t.Fatalf("stale profile = %+v, want unverified", profile)
+	}
+
+	// Старый ответ не закэширован: следующий Get снова обращается к API
+	fresh := get(s, 42)
+	<-backend.started
+	backend.profiles <- &models.UserProfile{IsVerified: true}
+	if profile := <-fresh; profile == nil || !profile.IsVerified {
+		t.Fatalf("fresh profile = %+v, want verified", profile)
+	}
+	if profile := <-get(s, 42); profile == nil || !profile.IsVerified {
+		t.Fatalf("cached profile = %+v, want verified", profile)
+	}
+}
@@ -1,35 +1,102 @@
 package services
 
 import (
+	"errors"
 	"sync"
 	"tribute-chatbot/internal/models"
 )
 
-// VerificationService сервис для управления верификацией
+var (
+	// ErrStateNotFound возвращается, если у пользователя нет активной верификации
+	ErrStateNotFound = errors.New("verification state not found")
+	// ErrVersionConflict возвращается, если состояние изменилось с момента чтения
+	ErrVersionConflict = errors.New("verification state version conflict")
+	// ErrUnexpectedStep возвращается, если переход недопустим для текущего этапа
+	ErrUnexpectedStep = errors.New("unexpected verification step")
+	// ErrReviewHeld возвращается при подтверждении заявки, удержанной проверкой по списку
+	ErrReviewHeld = errors.New("verification review held by screening")
+)
+
+// maxUpdateAttempts ограничивает число повторов Update при конфликте версий
+const maxUpdateAttempts = 10
+
+// VerificationService сервис для управления верификацией.
+// Наружу всегда отдаются копии состояний, изменения применяются через Update.
 type VerificationService struct {
-	states map[int64]*models.VerificationState
-	mutex  sync.RWMutex
+	states     map[int64]*models.VerificationState
+	collectMRZ bool
+	mutex      sync.RWMutex
 }
 
-// NewVerificationService создает новый сервис верификации
-func NewVerificationService() *VerificationService {
+// NewVerificationService создает новый сервис верификации.
+// Если collectMRZ включен, после паспорта пользователь вводит строки MRZ.
+func NewVerificationService(collectMRZ bool) *VerificationService {
 	return &VerificationService{
-		states: make(map[int64]*models.VerificationState),
+		states:     make(map[int64]*models.VerificationState),
+		collectMRZ: collectMRZ,
 	}
 }
 
-// GetState получает состояние верификации пользователя
+// GetState получает копию состояния верификации пользователя
 func (s *VerificationService) GetState(userID int64) *models.VerificationState {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
-	return s.states[userID]
+	return s.states[userID].Clone()
 }
 
-// SetState устанавливает состояние верификации пользователя
+// SetState безусловно устанавливает состояние верификации пользователя
 func (s *VerificationService) SetState(userID int64, state *models.VerificationState) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	s.states[userID] = state
+	stored := state.Clone()
+	if current := s.states[userID]; current != nil {
+		stored.Version = current.Version + 1
+	} else {
+		stored.Version = 1
+	}
+	s.states[userID] = stored
+}
+
+// CompareAndSet сохраняет состояние, только если его версия совпадает с сохраненной
+func (s *VerificationService) CompareAndSet(userID int64, state *models.VerificationState) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	current := s.states[userID]
+	if current == nil {
+		return ErrStateNotFound
+	}
+	if current.Version != state.Version {
+		return ErrVersionConflict
+	}
+	stored := state.Clone()
+	stored.Version++
+	s.states[userID] = stored
+	return nil
+}
+
+// Update транзакционно изменяет состояние пользователя.
+// fn получает копию состояния и может быть вызвана повторно при конфликте версий,
+// поэтому она не должна иметь побочных эффектов.
+func (s *VerificationService) Update(userID int64, fn func(*models.VerificationState) error) (*models.VerificationState, error) {
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		state := s.GetState(userID)
+		if state == nil {
+			return nil, ErrStateNotFound
+		}
+		if err := fn(state); err != nil {
+			return nil, err
+		}
+		err := s.CompareAndSet(userID, state)
+		if errors.Is(err, ErrVersionConflict) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		state.Version++
+		return state, nil
+	}
+	return nil, ErrVersionConflict
 }
 
 // ClearState очищает состояние верификации пользователя
@@ -46,37 +113,144 @@ func (s *VerificationService) InitializeState(userID int64) *models.Verification
 		Step:   models.VerificationStepWaitingSelfie,
 	}
 	s.SetState(userID, state)
-	return state
+	return s.GetState(userID)
 }
 
 // UpdateSelfie обновляет селфи в состоянии верификации
-func (s *VerificationService) UpdateSelfie(userID int64, selfieID string) *models.VerificationState {
-	state := s.GetState(userID)
-	if state != nil {
+func (s *VerificationService) UpdateSelfie(userID int64, selfieID string) (*models.VerificationState, error) {
+	return s.Update(userID, func(state *models.VerificationState) error {
+		if state.Step != models.VerificationStepWaitingSelfie {
+			return ErrUnexpectedStep
+		}
 		state.SelfieID = selfieID
 		state.Step = models.VerificationStepWaitingPassport
-		s.SetState(userID, state)
-	}
-	return state
+		return nil
+	})
 }
 
 // UpdatePassport обновляет паспорт в состоянии верификации
-func (s *VerificationService) UpdatePassport(userID int64, passportID string) *models.VerificationState {
-	state := s.GetState(userID)
-	if state != nil {
+func (s *VerificationService) UpdatePassport(userID int64, passportID string) (*models.VerificationState, error) {
+	return s.Update(userID, func(state *models.VerificationState) error {
+		if state.Step != models.VerificationStepWaitingPassport {
+			return ErrUnexpectedStep
+		}
 		state.PassportID = passportID
+		state.Step = s.afterPassportStep()
+		return nil
+	})
+}
+
+// SubmitPhoto атомарно применяет фотографию к текущему этапу верификации:
+// селфи на этапе waiting_selfie, паспорт на этапе waiting_passport
+func (s *VerificationService) SubmitPhoto(userID int64, fileID string) (*models.VerificationState, error) {
+	return s.Update(userID, func(state *models.VerificationState) error {
+		switch state.Step {
+		case models.VerificationStepWaitingSelfie:
+			state.SelfieID = fileID
+			state.Step = models.VerificationStepWaitingPassport
+		case models.VerificationStepWaitingPassport:
+			state.PassportID = fileID
+			state.Step = s.afterPassportStep()
+		default:
+			return ErrUnexpectedStep
+		}
+		return nil
+	})
+}
+
+// SubmitMRZ сохраняет данные из MRZ с результатами проверок и завершает сбор документов
+func (s *VerificationService) SubmitMRZ(userID int64, details models.VerificationDetails) (*models.VerificationState, error) {
+	return s.Update(userID, func(state *models.VerificationState) error {
+		if state.Step != models.VerificationStepWaitingMRZ {
+			return ErrUnexpectedStep
+		}
+		if details.Identity != nil {
+			identity := *details.Identity
+			state.Identity = &identity
+		}
+		state.Policy = details.Policy.Clone()
+		state.Screening = details.Screening.Clone()
 		state.Step = models.VerificationStepCompleted
-		s.SetState(userID, state)
+		return nil
+	})
+}
+
+// SkipMRZ завершает сбор документов без ввода MRZ
+func (s *VerificationService) SkipMRZ(userID int64) (*models.VerificationState, error) {
+	return s.Update(userID, func(state *models.VerificationState) error {
+		if state.Step != models.VerificationStepWaitingMRZ {
+			return ErrUnexpectedStep
+		}
+		state.Step = models.VerificationStepCompleted
+		return nil
+	})
+}
+
+// afterPassportStep возвращает этап, следующий за фотографией паспорта
+func (s *VerificationService) afterPassportStep() string {
+	if s.collectMRZ {
+		return models.VerificationStepWaitingMRZ
 	}
-	return state
+	return models.VerificationStepCompleted
 }
 
 // UpdateMessageIDs обновляет ID сообщений в состоянии верификации
-func (s *VerificationService) UpdateMessageIDs(userID int64, selfieMessageID, passportMessageID int) {
-	state := s.GetState(userID)
-	if state != nil {
+func (s *VerificationService) UpdateMessageIDs(userID int64, selfieMessageID, passportMessageID int) error {
+	_, err := s.Update(userID, func(state *models.VerificationState) error {
 		state.SelfieMessageID = selfieMessageID
 		state.PassportMessageID = passportMessageID
-		s.SetState(userID, state)
+		return nil
+	})
+	return err
+}
+
+// StartReview переводит заявку на рассмотрение, чтобы решение по ней
+// обрабатывалось ровно один раз даже при одновременных нажатиях кнопок.
+// Удержанную заявку можно только отклонить: проверка выполняется вместе
+// с захватом, чтобы удержание не могло появиться между ними
+func (s *VerificationService) StartReview(userID int64, isVerified bool) (*models.VerificationState, error) {
+	return s.Update(userID, func(state *models.VerificationState) error {
+		if state.Step != models.VerificationStepCompleted {
+			return ErrUnexpectedStep
+		}
+		if isVerified && state.Screening != nil && state.Screening.Held {
+			return ErrReviewHeld
+		}
+		state.Step = models.VerificationStepReviewing
+		return nil
+	})
+}
+
+// CancelReview возвращает заявку в ожидание решения, если обработка не удалась
+func (s *VerificationService) CancelReview(userID int64) error {
+	_, err := s.Update(userID, func(state *models.VerificationState) error {
+		if state.Step != models.VerificationStepReviewing {
+			return ErrUnexpectedStep
+		}
+		state.Step = models.VerificationStepCompleted
+		return nil
+	})
+	return err
+}
+
+// FinishReview удаляет состояние после применения решения, если пользователь
+// за это время не начал верификацию заново
+func (s *VerificationService) FinishReview(userID int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if state := s.states[userID]; state != nil && state.Step == models.VerificationStepReviewing {
+		delete(s.states, userID)
 	}
 }
+
+// ReleaseHold снимает удержание заявки, вызванное совпадением со списком
+func (s *VerificationService) ReleaseHold(userID int64, releasedBy string) (*models.VerificationState, error) {
+	return s.Update(userID, func(state *models.VerificationState) error {
+		if state.Step != models.VerificationStepCompleted || state.Screening == nil || !state.Screening.Held {
+			return ErrUnexpectedStep
+		}
+		state.Screening.Held = false
+		state.Screening.ReleasedBy = releasedBy
+		return nil
+	})
+}
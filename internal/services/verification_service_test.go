@@ -0,0 +1,279 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"tribute-chatbot/internal/models"
+)
+
+// concurrency число одновременных вызовов в тестах. Не больше maxUpdateAttempts:
+// каждый конфликт версий означает успешную запись другой горутины, поэтому
+// при таком числе Update гарантированно успевает сохранить состояние.
+const concurrency = maxUpdateAttempts
+
+// runConcurrently одновременно запускает fn в n горутинах и ждет их завершения
+func runConcurrently(n int, fn func(i int)) {
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			fn(i)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+}
+
+// completedState переводит заявку пользователя в ожидание решения
+func completedState(t *testing.T, s *VerificationService, userID int64) {
+	t.Helper()
+	s.InitializeState(userID)
+	if _, err := s.SubmitPhoto(userID, "selfie"); err != nil {
+		t.Fatalf("submit selfie: %v", err)
+	}
+	if _, err := s.SubmitPhoto(userID, "passport"); err != nil {
+		t.Fatalf("submit passport: %v", err)
+	}
+}
+
+func TestSubmitPhotoConcurrent(t *testing.T) {
+	s := NewVerificationService(false)
+	const userID = 1
+	s.InitializeState(userID)
+
+	var applied, rejected atomic.Int32
+	runConcurrently(concurrency, func(i int) {
+		_, err := s.SubmitPhoto(userID, "photo")
+		switch {
+		case err == nil:
+			applied.Add(1)
+		case errors.Is(err, ErrUnexpectedStep):
+			rejected.Add(1)
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	// Селфи и паспорт принимаются ровно по одному разу
+	if got := applied.Load(); got != 2 {
+		t.Fatalf("applied photos = %d, want 2", got)
+	}
+	if got := rejected.Load(); got != concurrency-2 {
+		t.Fatalf("rejected photos = %d, want %d", got, concurrency-2)
+	}
+	state := s.GetState(userID)
+	if state.Step != models.VerificationStepCompleted {
+		t.Fatalf("step = %q, want %q", state.Step, models.VerificationStepCompleted)
+	}
+	if state.Version != 3 {
+		t.Fatalf("version = %d, want 3", state.Version)
+	}
+}
+
+func TestSubmitPhotoConcurrentUsers(t *testing.T) {
+	s := NewVerificationService(true)
+	for userID := int64(1); userID <= concurrency; userID++ {
+		s.InitializeState(userID)
+	}
+
+	// Каждый пользователь одновременно присылает селфи и паспорт
+	runConcurrently(concurrency*2, func(i int) {
+		if _, err := s.SubmitPhoto(int64(i/2+1), "photo"); err != nil {
+			t.Errorf("user %d: %v", i/2+1, err)
+		}
+	})
+
+	for userID := int64(1); userID <= concurrency; userID++ {
+		if state := s.GetState(userID); state.Step != models.VerificationStepWaitingMRZ {
+			t.Errorf("user %d step = %q, want %q", userID, state.Step, models.VerificationStepWaitingMRZ)
+		}
+	}
+}
+
+func TestStartReviewConcurrent(t *testing.T) {
+	s := NewVerificationService(false)
+	const userID = 1
+	completedState(t, s, userID)
+
+	var started atomic.Int32
+	runConcurrently(concurrency, func(i int) {
+		_, err := s.StartReview(userID, true)
+		switch {
+		case err == nil:
+			started.Add(1)
+		case !errors.Is(err, ErrUnexpectedStep):
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	if got := started.Load(); got != 1 {
+		t.Fatalf("started reviews = %d, want 1", got)
+	}
+	if state := s.GetState(userID); state.Step != models.VerificationStepReviewing {
+		t.Fatalf("step = %q, want %q", state.Step, models.VerificationStepReviewing)
+	}
+}
+
+func TestReviewLifecycleConcurrent(t *testing.T) {
+	s := NewVerificationService(false)
+	const users = 4
+	for userID := int64(1); userID <= users; userID++ {
+		completedState(t, s, userID)
+	}
+
+	// Администраторы одновременно принимают решения: часть попыток отменяется
+	// и повторяется, решение по каждой заявке должно быть применено один раз
+	var mu sync.Mutex
+	applied := make(map[int64]int)
+	runConcurrently(users*concurrency, func(i int) {
+		userID := int64(i%users + 1)
+		for attempt := 0; ; attempt++ {
+			_, err := s.StartReview(userID, true)
+			if errors.Is(err, ErrUnexpectedStep) || errors.Is(err, ErrStateNotFound) {
+				return
+			}
+			if err != nil {
+				t.Errorf("user %d: start review: %v", userID, err)
+				return
+			}
+			if attempt == 0 && i%3 == 0 {
+				if err := s.CancelReview(userID); err != nil {
+					t.Errorf("user %d: cancel review: %v", userID, err)
+				}
+				continue
+			}
+			mu.Lock()
+			applied[userID]++
+			mu.Unlock()
+			s.FinishReview(userID)
+			return
+		}
+	})
+
+	for userID := int64(1); userID <= users; userID++ {
+		if applied[userID] != 1 {
+			t.Errorf("user %d: decision applied %d times, want 1", userID, applied[userID])
+		}
+		if state := s.GetState(userID); state != nil {
+			t.Errorf("user %d: state left after review: step %q", userID, state.Step)
+		}
+	}
+}
+
+// heldState переводит заявку в ожидание решения с удержанием по списку
+func heldState(t *testing.T, s *VerificationService, userID int64) {
+	t.Helper()
+	completedState(t, s, userID)
+	if _, err := s.Update(userID, func(state *models.VerificationState) error {
+		state.Screening = &models.ScreeningResult{Held: true}
+		return nil
+	}); err != nil {
+		t.Fatalf("hold: %v", err)
+	}
+}
+
+func TestStartReviewHeld(t *testing.T) {
+	s := NewVerificationService(false)
+	const userID = 1
+	heldState(t, s, userID)
+
+	if _, err := s.StartReview(userID, true); !errors.Is(err, ErrReviewHeld) {
+		t.Fatalf("approve held review: err = %v, want %v", err, ErrReviewHeld)
+	}
+	// Отклонить удержанную заявку можно
+	if _, err := s.StartReview(userID, false); err != nil {
+		t.Fatalf("reject held review: %v", err)
+	}
+}
+
+func TestStartReviewReleaseHoldConcurrent(t *testing.T) {
+	s := NewVerificationService(false)
+	const userID = 1
+	heldState(t, s, userID)
+
+	// Снятие удержания и подтверждения одновременно: подтверждение проходит
+	// только после снятия удержания
+	var approved atomic.Int32
+	runConcurrently(concurrency, func(i int) {
+		if i == 0 {
+			if _, err := s.ReleaseHold(userID, "admin"); err != nil && !errors.Is(err, ErrUnexpectedStep) {
+				t.Errorf("release hold: %v", err)
+			}
+			return
+		}
+		state, err := s.StartReview(userID, true)
+		switch {
+		case err == nil:
+			approved.Add(1)
+			if state.Screening.Held {
+				t.Error("held review started")
+			}
+		case !errors.Is(err, ErrReviewHeld) && !errors.Is(err, ErrUnexpectedStep):
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	if got := approved.Load(); got > 1 {
+		t.Fatalf("started reviews = %d, want at most 1", got)
+	}
+}
+
+func TestFinishReviewKeepsRestartedVerification(t *testing.T) {
+	s := NewVerificationService(false)
+	const userID = 1
+	completedState(t, s, userID)
+	if _, err := s.StartReview(userID, true); err != nil {
+		t.Fatalf("start review: %v", err)
+	}
+
+	// Пользователь начал верификацию заново, пока решение отправлялось
+	s.InitializeState(userID)
+	s.FinishReview(userID)
+
+	state := s.GetState(userID)
+	if state == nil || state.Step != models.VerificationStepWaitingSelfie {
+		t.Fatalf("state = %+v, want new verification", state)
+	}
+}
+
+func TestUpdateConcurrentNoLostUpdates(t *testing.T) {
+	s := NewVerificationService(false)
+	const userID = 1
+	s.InitializeState(userID)
+
+	runConcurrently(concurrency, func(i int) {
+		_, err := s.Update(userID, func(state *models.VerificationState) error {
+			state.SelfieMessageID++
+			return nil
+		})
+		if err != nil {
+			t.Errorf("update: %v", err)
+		}
+	})
+
+	state := s.GetState(userID)
+	if state.SelfieMessageID != concurrency {
+		t.Fatalf("counter = %d, want %d", state.SelfieMessageID, concurrency)
+	}
+	if state.Version != concurrency+1 {
+		t.Fatalf("version = %d, want %d", state.Version, concurrency+1)
+	}
+}
+
+func TestGetStateReturnsCopy(t *testing.T) {
+	s := NewVerificationService(false)
+	const userID = 1
+	s.InitializeState(userID)
+
+	state := s.GetState(userID)
+	state.Step = models.VerificationStepReviewing
+
+	if got := s.GetState(userID).Step; got != models.VerificationStepWaitingSelfie {
+		t.Fatalf("stored step = %q, changed through a copy", got)
+	}
+}
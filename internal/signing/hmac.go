@@ -0,0 +1,125 @@
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Заголовки подписи запроса
+const (
+	HeaderKeyID     = "X-Tribute-Key-Id"
+	HeaderTimestamp = "X-Tribute-Timestamp"
+	HeaderNonce     = "X-Tribute-Nonce"
+	HeaderSignature = "X-Tribute-Signature"
+)
+
+var (
+	// ErrMissingSignature возвращается, если в запросе нет заголовков подписи
+	ErrMissingSignature = errors.New("request is not signed")
+	// ErrInvalidSignature возвращается, если подпись не совпала
+	ErrInvalidSignature = errors.New("invalid request signature")
+	// ErrExpired возвращается, если метка времени вне окна допустимой задержки
+	ErrExpired = errors.New("request timestamp is outside the replay window")
+	// ErrReplayed возвращается при повторном использовании nonce
+	ErrReplayed = errors.New("request nonce has already been used")
+)
+
+// Sign подписывает запрос HMAC-SHA256 от метода, пути с параметрами,
+// метки времени, nonce и SHA-256 тела запроса
+func Sign(req *http.Request, body []byte, keyID string, secret []byte, now time.Time) error {
+	nonce, err := newNonce()
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	if keyID != "" {
+		req.Header.Set(HeaderKeyID, keyID)
+	}
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderNonce, nonce)
+	req.Header.Set(HeaderSignature, signature(secret, req.Method, req.URL.RequestURI(), timestamp, nonce, body))
+	return nil
+}
+
+// Verifier проверяет подписи входящих запросов и отклоняет повторы
+// в пределах окна допустимой задержки
+type Verifier struct {
+	secret []byte
+	window time.Duration
+	seen   map[string]time.Time
+	mutex  sync.Mutex
+}
+
+// NewVerifier создает проверку подписей с указанным окном
+func NewVerifier(secret []byte, window time.Duration) *Verifier {
+	return &Verifier{
+		secret: secret,
+		window: window,
+		seen:   make(map[string]time.Time),
+	}
+}
+
+// Verify проверяет подпись, метку времени и уникальность nonce запроса
+func (v *Verifier) Verify(req *http.Request, body []byte, now time.Time) error {
+	timestamp := req.Header.Get(HeaderTimestamp)
+	nonce := req.Header.Get(HeaderNonce)
+	provided := req.Header.Get(HeaderSignature)
+	if timestamp == "" || nonce == "" || provided == "" {
+		return ErrMissingSignature
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad timestamp", ErrInvalidSignature)
+	}
+	signedAt := time.Unix(unix, 0)
+	if now.Sub(signedAt) > v.window || signedAt.Sub(now) > v.window {
+		return ErrExpired
+	}
+
+	expected := signature(v.secret, req.Method, req.URL.RequestURI(), timestamp, nonce, body)
+	if !hmac.Equal([]byte(expected), []byte(provided)) {
+		return ErrInvalidSignature
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	for key, at := range v.seen {
+		if now.Sub(at) > 2*v.window {
+			delete(v.seen, key)
+		}
+	}
+	if _, used := v.seen[nonce]; used {
+		return ErrReplayed
+	}
+	v.seen[nonce] = now
+	return nil
+}
+
+// signature вычисляет подпись канонического представления запроса
+func signature(secret []byte, method, requestURI, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	canonical := method + "\n" + requestURI + "\n" + timestamp + "\n" + nonce + "\n" + hex.EncodeToString(bodyHash[:])
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newNonce генерирует случайный nonce
+func newNonce() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,172 @@
+package signing
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const window = 5 * time.Minute
+
+var (
+	secret = []byte("secret")
+	now    = time.Unix(1_800_000_000, 0)
+	body   = []byte(`{"user_id":42}`)
+)
+
+// signedRequest возвращает подписанный запрос
+func signedRequest(t *testing.T, at time.Time) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "https://api.example.com/v1/events?source=test", nil)
+	if err := Sign(req, body, "key-1", secret, at); err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return req
+}
+
+func TestSignVerify(t *testing.T) {
+	req := signedRequest(t, now)
+	if req.Header.Get(HeaderKeyID) != "key-1" {
+		t.Errorf("%s = %q, want key-1", HeaderKeyID, req.Header.Get(HeaderKeyID))
+	}
+
+	// Получатель видит запрос с задержкой в пределах окна
+	if err := NewVerifier(secret, window).Verify(req, body, now.Add(window)); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+}
+
+func TestSignUsesFreshNonce(t *testing.T) {
+	first, second := signedRequest(t, now), signedRequest(t, now)
+	if first.Header.Get(HeaderNonce) == second.Header.Get(HeaderNonce) {
+		t.Fatal("nonce reused between requests")
+	}
+	if first.Header.Get(HeaderSignature) == second.Header.Get(HeaderSignature) {
+		t.Fatal("signature reused between requests")
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		tamper  func(req *http.Request) []byte
+		at      time.Time
+		wantErr error
+	}{
+		{
+			name:    "tampered body",
+			tamper:  func(req *http.Request) []byte { return []byte(`{"user_id":43}`) },
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "tampered method",
+			tamper: func(req *http.Request) []byte {
+				req.Method = http.MethodPut
+				return body
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "tampered path",
+			tamper: func(req *http.Request) []byte {
+				req.URL.Path = "/v1/admin"
+				return body
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "tampered query",
+			tamper: func(req *http.Request) []byte {
+				req.URL.RawQuery = "source=other"
+				return body
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "tampered timestamp",
+			tamper: func(req *http.Request) []byte {
+				req.Header.Set(HeaderTimestamp, "1800000001")
+				return body
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "bad timestamp",
+			tamper: func(req *http.Request) []byte {
+				req.Header.Set(HeaderTimestamp, "yesterday")
+				return body
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "other secret",
+			tamper: func(req *http.Request) []byte {
+				if err := Sign(req, body, "", []byte("other"), now); err != nil {
+					t.Fatalf("sign: %v", err)
+				}
+				return body
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "signed too long ago",
+			at:      now.Add(window + time.Second),
+			wantErr: ErrExpired,
+		},
+		{
+			name:    "signed in the future",
+			at:      now.Add(-window - time.Second),
+			wantErr: ErrExpired,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := signedRequest(t, now)
+			received := body
+			if tc.tamper != nil {
+				received = tc.tamper(req)
+			}
+			at := tc.at
+			if at.IsZero() {
+				at = now
+			}
+			if err := NewVerifier(secret, window).Verify(req, received, at); !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsMissingHeaders(t *testing.T) {
+	for _, header := range []string{HeaderTimestamp, HeaderNonce, HeaderSignature} {
+		req := signedRequest(t, now)
+		req.Header.Del(header)
+		if err := NewVerifier(secret, window).Verify(req, body, now); !errors.Is(err, ErrMissingSignature) {
+			t.Errorf("without %s: err = %v, want %v", header, err, ErrMissingSignature)
+		}
+	}
+}
+
+func TestVerifyRejectsReplay(t *testing.T) {
+	verifier := NewVerifier(secret, window)
+	req := signedRequest(t, now)
+	if err := verifier.Verify(req, body, now); err != nil {
+		t.Fatalf("first verify: %v", err)
+	}
+	if err := verifier.Verify(req, body, now.Add(time.Second)); !errors.Is(err, ErrReplayed) {
+		t.Fatalf("replay err = %v, want %v", err, ErrReplayed)
+	}
+
+	// Запрос с неверной подписью не занимает nonce
+	forged := signedRequest(t, now)
+	valid := forged.Header.Get(HeaderSignature)
+	forged.Header.Set(HeaderSignature, "forged")
+	if err := verifier.Verify(forged, body, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("forged err = %v, want %v", err, ErrInvalidSignature)
+	}
+	forged.Header.Set(HeaderSignature, valid)
+	if err := verifier.Verify(forged, body, now); err != nil {
+		t.Fatalf("verify after forged: %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+	"tribute-chatbot/internal/config"
+	"tribute-chatbot/internal/handlers/events"
+	"tribute-chatbot/internal/logger"
+	"tribute-chatbot/internal/models"
+	"tribute-chatbot/internal/services"
+	"tribute-chatbot/internal/signing"
+)
+
+// EventsPath путь, на который бэкенд отправляет события
+const EventsPath = "/v1/events"
+
+// maxEventSize ограничивает размер тела события
+const maxEventSize = 64 << 10
+
+// Server HTTP сервер для событий, которые бэкенд отправляет боту.
+// Запросы подписываются тем же HMAC-SHA256, что и запросы бота к API.
+type Server struct {
+	httpServer *http.Server
+	verifier   *signing.Verifier
+	handler    *events.Handler
+	logger     logger.Logger
+}
+
+// NewServer создает сервер на порту из конфигурации. Без WEBHOOK_HMAC_SECRET
+// прием событий выключен, доступна только проверка /healthz.
+func NewServer(cfg *config.Config, handler *events.Handler) *Server {
+	s := &Server{
+		handler: handler,
+		logger:  logger.New(),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	if cfg.WebhookHMACSecret != "" {
+		s.verifier = signing.NewVerifier([]byte(cfg.WebhookHMACSecret), cfg.WebhookReplayWindow)
+		mux.HandleFunc(EventsPath, s.handleEvent)
+	} else {
+		s.logger.Warn("WEBHOOK_HMAC_SECRET is not set, backend events are disabled")
+	}
+
+	s.httpServer = &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Port),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+	return s
+}
+
+// Start запускает сервер в фоне
+func (s *Server) Start() {
+	go func() {
+		s.logger.Info(fmt.Sprintf("Starting webhook server on %s", s.httpServer.Addr))
+		if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("Webhook server failed:", err)
+		}
+	}()
+}
+
+// Stop останавливает сервер, дожидаясь обработки текущих запросов
+func (s *Server) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		s.logger.Error("Failed to stop webhook server:", err)
+	}
+}
+
+// handleEvent принимает событие бэкенда: проверяет подпись, разбирает событие
+// и отправляет сообщение пользователю
+func (s *Server) handleEvent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, "error", "method not allowed")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxEventSize))
+	if err != nil {
+		writeJSON(w, http.StatusRequestEntityTooLarge, "error", "request body is too large")
+		return
+	}
+
+	if err := s.verifier.Verify(r, body, time.Now()); err != nil {
+		s.logger.Warn(fmt.Sprintf("Rejected webhook request from %s: %v", r.RemoteAddr, err))
+		writeJSON(w, http.StatusUnauthorized, "error", err.Error())
+		return
+	}
+
+	var event models.BackendEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		writeJSON(w, http.StatusBadRequest, "error", "invalid event payload")
+		return
+	}
+
+	duplicate, err := s.handler.HandleEvent(event)
+	switch {
+	case errors.Is(err, events.ErrUnknownEvent), errors.Is(err, events.ErrInvalidEvent):
+		s.logger.Warn(fmt.Sprintf("Rejected event %s: %v", event.ID, err))
+		writeJSON(w, http.StatusUnprocessableEntity, "error", err.Error())
+	case errors.Is(err, services.ErrEventInProgress):
+		writeJSON(w, http.StatusConflict, "error", err.Error())
+	case err != nil:
+		s.logger.Error(fmt.Sprintf("Failed to process event %s:", event.ID), err)
+		writeJSON(w, http.StatusInternalServerError, "error", "failed to process event")
+	case duplicate:
+		writeJSON(w, http.StatusOK, "status", "duplicate")
+	default:
+		writeJSON(w, http.StatusOK, "status", "processed")
+	}
+}
+
+// writeJSON отправляет ответ вида {"<key>": "<value>"}
+func writeJSON(w http.ResponseWriter, status int, key, value string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{key: value})
+}
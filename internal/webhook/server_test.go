@@ -0,0 +1,120 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+	"tribute-chatbot/internal/config"
+	"tribute-chatbot/internal/faketelegram"
+	"tribute-chatbot/internal/handlers/events"
+	"tribute-chatbot/internal/services"
+	"tribute-chatbot/internal/signing"
+	"tribute-chatbot/internal/storage"
+)
+
+const webhookSecret = "webhook-secret"
+
+// paymentEvent событие зачисления выплаты пользователю 42
+var paymentEvent = []byte(`{"id":"evt-1","type":"payment.received","data":{"user_id":42,"amount":"10","currency":"USD"}}`)
+
+// newTestServer запускает сервер событий с обработчиком, подключенным к фейковому Telegram
+func newTestServer(t *testing.T) (*httptest.Server, *faketelegram.Server) {
+	t.Helper()
+
+	telegram := faketelegram.New()
+	telegramServer := httptest.NewServer(telegram)
+	t.Cleanup(telegramServer.Close)
+	bot, err := faketelegram.NewBot(telegramServer.URL)
+	if err != nil {
+		t.Fatalf("bot: %v", err)
+	}
+	eventLog, err := services.NewEventLog(storage.NewJSONFile(filepath.Join(t.TempDir(), "events.json")))
+	if err != nil {
+		t.Fatalf("event log: %v", err)
+	}
+
+	cfg := &config.Config{
+		WebhookHMACSecret:   webhookSecret,
+		WebhookReplayWindow: time.Minute,
+	}
+	server := httptest.NewServer(NewServer(cfg, events.NewHandler(bot, eventLog, nil, nil)).httpServer.Handler)
+	t.Cleanup(server.Close)
+	return server, telegram
+}
+
+// eventRequest возвращает запрос с событием, подписанный secret; пустой secret — без подписи
+func eventRequest(t *testing.T, url string, secret string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url+EventsPath, bytes.NewReader(paymentEvent))
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	if secret != "" {
+		if err := signing.Sign(req, paymentEvent, "", []byte(secret), time.Now()); err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+	}
+	return req
+}
+
+// send отправляет запрос и возвращает код ответа и его тело
+func send(t *testing.T, req *http.Request) (int, map[string]string) {
+	t.Helper()
+	req.Body = io.NopCloser(bytes.NewReader(paymentEvent))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	defer resp.Body.Close()
+	var body map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	return resp.StatusCode, body
+}
+
+func TestRejectsUnsignedEvents(t *testing.T) {
+	server, telegram := newTestServer(t)
+
+	for name, secret := range map[string]string{"unsigned": "", "wrong secret": "other"} {
+		status, body := send(t, eventRequest(t, server.URL, secret))
+		if status != http.StatusUnauthorized {
+			t.Errorf("%s: status = %d, want %d (%v)", name, status, http.StatusUnauthorized, body)
+		}
+	}
+	if messages := telegram.CallsTo("sendMessage"); len(messages) != 0 {
+		t.Fatalf("messages = %d, want 0", len(messages))
+	}
+}
+
+func TestProcessesEventOnce(t *testing.T) {
+	server, telegram := newTestServer(t)
+
+	req := eventRequest(t, server.URL, webhookSecret)
+	if status, body := send(t, req); status != http.StatusOK || body["status"] != "processed" {
+		t.Fatalf("first delivery: status = %d, body = %v", status, body)
+	}
+
+	// Тот же запрос с тем же nonce отклоняется до обработки
+	if status, body := send(t, req); status != http.StatusUnauthorized || body["error"] != signing.ErrReplayed.Error() {
+		t.Fatalf("replay: status = %d, body = %v", status, body)
+	}
+
+	// Повторная доставка события с новой подписью пропускается по журналу событий
+	if status, body := send(t, eventRequest(t, server.URL, webhookSecret)); status != http.StatusOK || body["status"] != "duplicate" {
+		t.Fatalf("redelivery: status = %d, body = %v", status, body)
+	}
+
+	messages := telegram.CallsTo("sendMessage")
+	if len(messages) != 1 {
+		t.Fatalf("messages = %d, want 1", len(messages))
+	}
+	if messages[0].Param("chat_id") != "42" {
+		t.Errorf("message chat_id = %s, want 42", messages[0].Param("chat_id"))
+	}
+}